@@ -4,9 +4,15 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"strings"
+	"sync"
+	"time"
 
 	walletclient "decred.org/dcrwallet/rpc/client/dcrwallet"
 	"github.com/decred/dcrd/rpcclient/v6"
@@ -29,18 +35,13 @@ var (
 	manifestPattern  = regexp.MustCompile(`^.*-manifest\.txt$`)
 	dcrdAppDir       = filepath.Join(AppDir, dcrd)
 	dcrdCertPath     = filepath.Join(AppDir, dcrd, "rpc.cert")
+	remoteCertPath   = filepath.Join(AppDir, dcrd, "remote.cert")
 	dcrwalletAppDir  = filepath.Join(AppDir, dcrwallet)
 	dcrWalletRPCCert = filepath.Join(dcrwalletAppDir, "rpc.cert")
 	dcrWalletRPCKey  = filepath.Join(dcrwalletAppDir, "rpc.key")
 	decreditonAppDir = filepath.Join(AppDir, decrediton)
 	dexAppDir        = filepath.Join(AppDir, dexc)
 
-	dcrdRPCListen       = ":19703"
-	dcrdListen          = ":19704"
-	dcrWalletRPCListen  = ":19705"
-	dcrWalletGRPCListen = ":19706"
-	dexWebAddr          = ":26270"
-
 	chromiumVersionRegexp = regexp.MustCompile(`^[^\d]*(\d+)\.(\d+)\.(\d+)`)
 
 	dexAcctName = "dex"
@@ -53,6 +54,8 @@ type serviceExe struct {
 	cancel context.CancelFunc
 	done   chan struct{}
 	feed   func([]byte)
+	// startMtx guards cmd.Process, which is set when the command is started.
+	startMtx sync.Mutex
 }
 
 func newExe(ctx context.Context, exe string, args ...string) *serviceExe {
@@ -69,17 +72,23 @@ func newExe(ctx context.Context, exe string, args ...string) *serviceExe {
 		done:   make(chan struct{}),
 	}
 
-	cmd.Stdout = &outputWriter{s.processOutput}
+	// Capture the output in the service's log. The output is still
+	// processed if the log can't be opened.
+	svc := strings.TrimSuffix(s.name, filepath.Ext(s.name))
+	if svcLog, err := serviceLogger(svc); err != nil {
+		log.Errorf("Error opening log for %s: %v", svc, err)
+		cmd.Stdout = &outputWriter{s.processOutput}
+	} else {
+		cmd.Stdout = io.MultiWriter(&outputWriter{s.processOutput}, svcLog.writer())
+		cmd.Stderr = svcLog.writer()
+	}
 
 	return s
 }
 
 func (s *serviceExe) processOutput(msg []byte) {
-	// For dcrwallet, there is not a great indicator of sync status via RPC.
-	// We could potentially catch the output during startup and read it here.
-	// The sync line ends like below.
-	//
-	// Blockchain sync completed, wallet ready for general usage
+	// dcrwallet has no indicator of sync status via RPC, so its output is fed
+	// to a walletSyncParser.
 	if s.feed != nil {
 		s.feed(msg)
 	}
@@ -89,7 +98,12 @@ func (s *serviceExe) processOutput(msg []byte) {
 func (s *serviceExe) Run() error {
 	defer close(s.done)
 	log.Infof("Running %q", s.cmd)
-	err := s.cmd.Run()
+	s.startMtx.Lock()
+	err := s.cmd.Start()
+	s.startMtx.Unlock()
+	if err == nil {
+		err = s.cmd.Wait()
+	}
 	log.Tracef("%s finished", s.name)
 	if err != nil && s.ctx.Err() == nil {
 		log.Errorf("Error encountered running %q: %v", s.cmd, err)
@@ -97,6 +111,29 @@ func (s *serviceExe) Run() error {
 	return err
 }
 
+// stop asks the process to exit with an interrupt signal, and kills it if it
+// hasn't exited before the timeout.
+func (s *serviceExe) stop(timeout time.Duration) error {
+	s.startMtx.Lock()
+	proc := s.cmd.Process
+	s.startMtx.Unlock()
+	if proc == nil {
+		s.cancel()
+		return nil
+	}
+	// os.Interrupt is not implemented on Windows, so just kill it there.
+	if err := proc.Signal(os.Interrupt); err != nil {
+		s.cancel()
+	}
+	select {
+	case <-s.done:
+	case <-time.After(timeout):
+		s.cancel()
+		return fmt.Errorf("Timed out waiting for %s to shutdown. Killing the process", s.name)
+	}
+	return nil
+}
+
 // func (s *serviceExe) Wait() {
 // 	<-s.done
 // }
@@ -121,7 +158,6 @@ type executable struct {
 type DCRD struct {
 	DCRDState
 	client *rpcclient.Client
-	exe    *serviceExe
 }
 
 func dcrdNewState() *DCRDState {
@@ -141,7 +177,6 @@ func dcrdDefaultUserSettings() DCRDUserSettings {
 type DCRWallet struct {
 	DCRWalletState
 	client *walletclient.Client
-	exe    *serviceExe
 }
 
 func dcrWalletNewState() *DCRWalletState {
@@ -182,12 +217,12 @@ type RemoteCredentials struct {
 	RPCPort string `json:"rpc_port"`
 }
 
-func defaultDecreditonConfig() *decreditonConfig {
+func defaultDecreditonConfig(network string) *decreditonConfig {
 	return &decreditonConfig{
 		Theme:               "theme-dark",
 		DaemonStartAdvanced: true,
 		Locale:              "en",
-		Network:             "mainnet",
+		Network:             network,
 		UIAnimations:        true,
 		AllowExternalRequests: []string{
 			"EXTERNALREQUEST_NETWORK_STATUS",
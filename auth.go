@@ -0,0 +1,237 @@
+package eco
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/buck54321/eco/encode"
+)
+
+// Scope is a set of permissions for an IPC client.
+type Scope uint8
+
+const (
+	// ScopeRead allows reading the state, the feeds, settings, and logs.
+	// Passwords are never sent to clients.
+	ScopeRead Scope = 1 << iota
+	// ScopeControl allows initializing, upgrading, and configuring Eco, and
+	// starting and stopping services.
+	ScopeControl
+	// ScopeWallet allows dcrctl commands, which can spend from the wallet,
+	// and access to the wallet seed.
+	ScopeWallet
+
+	// ScopeAll is every scope. Only a client with every scope can add and
+	// revoke clients, so a client can't grant itself more permissions.
+	ScopeAll = ScopeRead | ScopeControl | ScopeWallet
+)
+
+// ipcTokenSize is the size of a client token, before hex encoding.
+const ipcTokenSize = 32
+
+// ipcClientsKey is where the added clients are stored.
+const ipcClientsKey = "ipcClients"
+
+// routeScopes are the scopes needed for each route.
+var routeScopes = map[string]Scope{
+	routeServiceStatus:       ScopeRead,
+	routeSync:                ScopeRead,
+	routeStorage:             ScopeRead,
+	routeLogs:                ScopeRead,
+	routeGetSettings:         ScopeRead,
+	routeInit:                ScopeControl,
+	routeStartDecrediton:     ScopeControl,
+	routeStartDEX:            ScopeControl,
+	routeUpgrade:             ScopeControl,
+	routeSetVersion:          ScopeControl,
+	routeSetReleaseChannel:   ScopeControl,
+	routeSetReleaseSource:    ScopeControl,
+	routePruneVersions:       ScopeControl,
+	routeSetVersionRetention: ScopeControl,
+	routeClearCache:          ScopeControl,
+	routeSetCacheLimit:       ScopeControl,
+	routeStopService:         ScopeControl,
+	routeRestartService:      ScopeControl,
+	routeSetProxy:            ScopeControl,
+	routeSetSettings:         ScopeControl,
+	routeUnlock:              ScopeControl,
+	routeDCRCtl:              ScopeWallet,
+	routeRevealSeed:          ScopeWallet,
+	routeSeedQuiz:            ScopeWallet,
+	routeDeleteSeed:          ScopeWallet,
+	routeAddClient:           ScopeAll,
+	routeRevokeClient:        ScopeAll,
+}
+
+// ipcClient is a client added with AddClient. Only the hash of the token is
+// stored.
+type ipcClient struct {
+	TokenHash []byte
+	Scopes    Scope
+}
+
+// loadToken loads the owner's token from the file, creating the file if it
+// doesn't exist. The file is only readable by the user, unlike the TLS
+// certificate.
+func loadToken(path string) ([]byte, error) {
+	b, err := ioutil.ReadFile(path)
+	if err == nil {
+		token := bytes.TrimSpace(b)
+		if len(token) == 0 {
+			return nil, fmt.Errorf("Empty IPC token file %s", path)
+		}
+		return token, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("Error reading IPC token: %w", err)
+	}
+	token := newToken()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("Error creating IPC token directory: %w", err)
+	}
+	if err := ioutil.WriteFile(path, token, 0600); err != nil {
+		return nil, fmt.Errorf("Error writing IPC token: %w", err)
+	}
+	return token, nil
+}
+
+func newToken() []byte {
+	return []byte(hex.EncodeToString(encode.RandomBytes(ipcTokenSize)))
+}
+
+func hashToken(token []byte) []byte {
+	h := sha256.Sum256(token)
+	return h[:]
+}
+
+// authorize checks that the token has the scopes for the route.
+func (s *Server) authorize(token []byte, route string) error {
+	need, found := routeScopes[route]
+	if !found {
+		return fmt.Errorf("Unknown route %q", route)
+	}
+	var scopes Scope
+	if subtle.ConstantTimeCompare(token, s.token) == 1 {
+		scopes = ScopeAll
+	} else {
+		clients, err := s.eco.ipcClients()
+		if err != nil {
+			log.Errorf("Error loading IPC clients: %v", err)
+			return fmt.Errorf("Unauthorized")
+		}
+		h := hashToken(token)
+		var found bool
+		for _, cl := range clients {
+			if subtle.ConstantTimeCompare(h, cl.TokenHash) == 1 {
+				scopes, found = cl.Scopes, true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("Unauthorized")
+		}
+	}
+	if scopes&need != need {
+		return fmt.Errorf("Unauthorized for %s", route)
+	}
+	return nil
+}
+
+func (eco *Eco) ipcClients() (map[string]*ipcClient, error) {
+	clients := make(map[string]*ipcClient)
+	if _, err := eco.db.FetchDecode(ipcClientsKey, &clients); err != nil {
+		return nil, err
+	}
+	return clients, nil
+}
+
+// addClient adds a client with the scopes, and returns its token. A client
+// with the same name is replaced.
+func (eco *Eco) addClient(name string, scopes Scope) ([]byte, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("No client name")
+	}
+	if scopes == 0 || scopes&^ScopeAll != 0 {
+		return nil, fmt.Errorf("Invalid scopes %d", scopes)
+	}
+	eco.clientsMtx.Lock()
+	defer eco.clientsMtx.Unlock()
+	clients, err := eco.ipcClients()
+	if err != nil {
+		return nil, fmt.Errorf("Error loading IPC clients: %w", err)
+	}
+	token := newToken()
+	clients[name] = &ipcClient{
+		TokenHash: hashToken(token),
+		Scopes:    scopes,
+	}
+	if err := eco.db.EncodeStore(ipcClientsKey, clients); err != nil {
+		return nil, fmt.Errorf("Error saving IPC clients: %w", err)
+	}
+	return token, nil
+}
+
+// revokeClient removes a client added with addClient.
+func (eco *Eco) revokeClient(name string) error {
+	eco.clientsMtx.Lock()
+	defer eco.clientsMtx.Unlock()
+	clients, err := eco.ipcClients()
+	if err != nil {
+		return fmt.Errorf("Error loading IPC clients: %w", err)
+	}
+	if _, found := clients[name]; !found {
+		return fmt.Errorf("Unknown client %q", name)
+	}
+	delete(clients, name)
+	if err := eco.db.EncodeStore(ipcClientsKey, clients); err != nil {
+		return fmt.Errorf("Error saving IPC clients: %w", err)
+	}
+	return nil
+}
+
+type authResponse struct {
+	Err string
+}
+
+type addClientRequest struct {
+	Name   string
+	Scopes Scope
+}
+
+type addClientResponse struct {
+	Token []byte
+	Err   string
+}
+
+type revokeClientRequest struct {
+	Name string
+}
+
+// AddClient adds an IPC client with the scopes, and returns its token. The
+// client uses the token by saving it to a file and pointing TokenPath at the
+// file. Adding a client with an existing name replaces its token.
+func AddClient(ctx context.Context, name string, scopes Scope) ([]byte, error) {
+	resp := new(addClientResponse)
+	err := request(ctx, routeAddClient, &addClientRequest{Name: name, Scopes: scopes}, resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Err != "" {
+		return nil, fmt.Errorf(resp.Err)
+	}
+	return resp.Token, nil
+}
+
+// RevokeClient revokes the token of a client added with AddClient.
+func RevokeClient(ctx context.Context, name string) error {
+	return errorRequest(ctx, routeRevokeClient, &revokeClientRequest{Name: name})
+}
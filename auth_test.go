@@ -0,0 +1,141 @@
+package eco
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/buck54321/eco/db"
+	"github.com/decred/slog"
+)
+
+func TestAuthorize(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(tmpDir)
+	dbb, err := db.NewDB(filepath.Join(tmpDir, "eco.db"), slog.Disabled)
+	if err != nil {
+		t.Fatalf("NewDB error: %v", err)
+	}
+	eco := &Eco{db: dbb}
+	owner := newToken()
+	s := &Server{eco: eco, token: owner}
+
+	readToken, err := eco.addClient("reader", ScopeRead)
+	if err != nil {
+		t.Fatalf("addClient error: %v", err)
+	}
+	controlToken, err := eco.addClient("controller", ScopeRead|ScopeControl)
+	if err != nil {
+		t.Fatalf("addClient error: %v", err)
+	}
+	if _, err := eco.addClient("bad", 1<<5); err == nil {
+		t.Fatalf("No error for an unknown scope")
+	}
+
+	tests := []struct {
+		name  string
+		token []byte
+		route string
+		ok    bool
+	}{
+		{"owner state", owner, routeServiceStatus, true},
+		{"owner dcrctl", owner, routeDCRCtl, true},
+		{"owner add client", owner, routeAddClient, true},
+		{"reader state", readToken, routeServiceStatus, true},
+		{"reader feed", readToken, routeSync, true},
+		{"reader stop", readToken, routeStopService, false},
+		{"reader dcrctl", readToken, routeDCRCtl, false},
+		{"controller stop", controlToken, routeStopService, true},
+		{"controller dcrctl", controlToken, routeDCRCtl, false},
+		{"controller add client", controlToken, routeAddClient, false},
+		{"unknown token", newToken(), routeServiceStatus, false},
+		{"no token", nil, routeServiceStatus, false},
+		{"unknown route", owner, "nope", false},
+	}
+	for _, tt := range tests {
+		if err := s.authorize(tt.token, tt.route); (err == nil) != tt.ok {
+			t.Fatalf("%s: expected ok = %t, got error %v", tt.name, tt.ok, err)
+		}
+	}
+
+	if err := eco.revokeClient("reader"); err != nil {
+		t.Fatalf("revokeClient error: %v", err)
+	}
+	if err := s.authorize(readToken, routeServiceStatus); err == nil {
+		t.Fatalf("Revoked token still authorized")
+	}
+	if err := s.authorize(controlToken, routeServiceStatus); err != nil {
+		t.Fatalf("Other client's token revoked: %v", err)
+	}
+}
+
+func TestServerAuth(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(tmpDir)
+	defer func(addr *NetAddr, key, cert, token string) {
+		serverAddress, KeyPath, CertPath, TokenPath = addr, key, cert, token
+	}(serverAddress, KeyPath, CertPath, TokenPath)
+	serverAddress = &NetAddr{Net: "tcp4", Addr: "127.0.0.1:39080"}
+	KeyPath = filepath.Join(tmpDir, "decred-eco.key")
+	CertPath = filepath.Join(tmpDir, "decred-eco.cert")
+	ownerPath := filepath.Join(tmpDir, "decred-eco.token")
+	TokenPath = ownerPath
+
+	dbb, err := db.NewDB(filepath.Join(tmpDir, "eco.db"), slog.Disabled)
+	if err != nil {
+		t.Fatalf("NewDB error: %v", err)
+	}
+	srv, err := NewServer(&Eco{
+		db:   dbb,
+		dcrd: &DCRD{DCRDState: *dcrdNewState()},
+	})
+	if err != nil {
+		t.Fatalf("NewServer error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	go srv.Run(ctx)
+
+	fi, err := os.Stat(ownerPath)
+	if err != nil {
+		t.Fatalf("Owner token not created: %v", err)
+	}
+	if perm := fi.Mode().Perm(); perm != 0600 {
+		t.Fatalf("Owner token has permissions %o", perm)
+	}
+
+	token, err := AddClient(ctx, "reader", ScopeRead)
+	if err != nil {
+		t.Fatalf("AddClient error: %v", err)
+	}
+	readerPath := filepath.Join(tmpDir, "reader.token")
+	ioutil.WriteFile(readerPath, token, 0600)
+	TokenPath = readerPath
+
+	dcrdState := new(DCRDState)
+	if err := serviceStatus(ctx, dcrd, dcrdState); err != nil {
+		t.Fatalf("serviceStatus error: %v", err)
+	}
+	if dcrdState.RPCUser == "" {
+		t.Fatalf("No dcrd state decoded")
+	}
+	if dcrdState.RPCPass != "" {
+		t.Fatalf("Read-only client got the RPC password")
+	}
+	err = request(ctx, routeDCRCtl, &dcrCtlRequest{}, new(dcrCtlResponse))
+	if err == nil || !strings.Contains(err.Error(), "Unauthorized") {
+		t.Fatalf("Expected an unauthorized error for dcrctl, got %v", err)
+	}
+	if _, err := AddClient(ctx, "sneaky", ScopeAll); err == nil {
+		t.Fatalf("Read-only client added a client")
+	}
+
+	ioutil.WriteFile(readerPath, newToken(), 0600)
+	if err := serviceStatus(ctx, dcrd, new(DCRDState)); err == nil {
+		t.Fatalf("No error for an unknown token")
+	}
+}
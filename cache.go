@@ -0,0 +1,139 @@
+package eco
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// defaultCacheLimit is the download cache size limit if the user hasn't set
+// one.
+const defaultCacheLimit = 1 << 30 // 1 GiB
+
+// downloadCacheDir holds verified downloads, named by their SHA-256 hash.
+func downloadCacheDir() string {
+	return filepath.Join(AppDir, "downloads", "cache")
+}
+
+// downloadCache is a content-addressed cache of verified downloads. Files are
+// stored by the hex-encoded SHA-256 hash of their contents, so a file found in
+// the cache for a manifest hash can be reused without downloading it again.
+type downloadCache struct {
+	dir string
+	// limit is the maximum total size of the cached files. When the limit is
+	// exceeded, the least recently used files are evicted.
+	limit int64
+}
+
+func newDownloadCache(dir string, limit int64) *downloadCache {
+	if limit <= 0 {
+		limit = defaultCacheLimit
+	}
+	return &downloadCache{dir: dir, limit: limit}
+}
+
+func (c *downloadCache) path(hash []byte) string {
+	return filepath.Join(c.dir, hex.EncodeToString(hash))
+}
+
+// fetch gets the file with the expected SHA-256 hash into dir/name. If the
+// file is in the cache, it is copied from the cache and verified. Otherwise,
+// the file is retrieved with fetchFunc, verified, and added to the cache.
+func (c *downloadCache) fetch(dir, name string, hash []byte, fetchFunc func() (string, error), prog *progressReporter) (string, error) {
+	tgt := filepath.Join(dir, name)
+	cachePath := c.path(hash)
+	if fileExists(cachePath) {
+		prog.report(0, "Using cached %s", name)
+		err := linkOrCopy(cachePath, tgt)
+		if err == nil {
+			err = checkFileHash(tgt, hash)
+		}
+		if err == nil {
+			// Update the modification time, which is used for LRU eviction.
+			now := time.Now()
+			os.Chtimes(cachePath, now, now)
+			return tgt, nil
+		}
+		log.Warnf("Discarding cached %s: %v", name, err)
+		os.Remove(tgt)
+		os.Remove(cachePath)
+	}
+
+	path, err := fetchFunc()
+	if err != nil {
+		return "", err
+	}
+	prog.report(0.99, "Validating %s", name)
+	if err := checkFileHash(path, hash); err != nil {
+		return "", fmt.Errorf("Failed file hash check: %w", err)
+	}
+
+	if err := c.add(path, hash); err != nil {
+		// Not fatal. We just won't have it cached.
+		log.Errorf("Error adding %s to the download cache: %v", name, err)
+	}
+	return path, nil
+}
+
+// add adds a verified file to the cache, then evicts files to get under the
+// limit.
+func (c *downloadCache) add(path string, hash []byte) error {
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		return fmt.Errorf("Error creating cache directory: %w", err)
+	}
+	if err := linkOrCopy(path, c.path(hash)); err != nil {
+		return err
+	}
+	return c.evict()
+}
+
+// evict deletes the least recently used files until the cache is under the
+// limit.
+func (c *downloadCache) evict() error {
+	fis, err := ioutil.ReadDir(c.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("Error reading cache directory: %w", err)
+	}
+	var total int64
+	for _, fi := range fis {
+		total += fi.Size()
+	}
+	// Oldest first.
+	sort.Slice(fis, func(i, j int) bool {
+		return fis[i].ModTime().Before(fis[j].ModTime())
+	})
+	for _, fi := range fis {
+		if total <= c.limit {
+			break
+		}
+		log.Infof("Evicting %s from the download cache", fi.Name())
+		if err := os.Remove(filepath.Join(c.dir, fi.Name())); err != nil {
+			return fmt.Errorf("Error evicting cached file: %w", err)
+		}
+		total -= fi.Size()
+	}
+	return nil
+}
+
+// clear deletes all cached files.
+func (c *downloadCache) clear() error {
+	return os.RemoveAll(c.dir)
+}
+
+// linkOrCopy hard links the file to dst, or copies it if it can't be linked,
+// e.g. because dst is on a different device. Files are never modified in
+// place, so a hard link is as good as a copy.
+func linkOrCopy(src, dst string) error {
+	os.Remove(dst)
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+	return copyFile(src, dst)
+}
@@ -0,0 +1,97 @@
+package eco
+
+import (
+	"crypto/sha256"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDownloadCache(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(tmpDir)
+
+	prog := &progressReporter{
+		report: func(float32, string, ...interface{}) {},
+		fail:   func(string, error) {},
+	}
+
+	cache := newDownloadCache(filepath.Join(tmpDir, "cache"), 250)
+	srcDir := filepath.Join(tmpDir, "src")
+	os.Mkdir(srcDir, 0755)
+
+	var fetches int
+	fetcher := func(name string, b []byte) func() (string, error) {
+		return func() (string, error) {
+			fetches++
+			path := filepath.Join(srcDir, name)
+			return path, ioutil.WriteFile(path, b, 0644)
+		}
+	}
+
+	get := func(name string, b []byte) {
+		t.Helper()
+		dir, _ := ioutil.TempDir(tmpDir, "")
+		h := sha256.Sum256(b)
+		path, err := cache.fetch(dir, name, h[:], fetcher(name, b), prog)
+		if err != nil {
+			t.Fatalf("fetch error for %s: %v", name, err)
+		}
+		if err := checkFileHash(path, h[:]); err != nil {
+			t.Fatalf("Wrong file contents for %s: %v", name, err)
+		}
+	}
+
+	a, b, c := make([]byte, 100), make([]byte, 100), make([]byte, 100)
+	a[0], b[0], c[0] = 'a', 'b', 'c'
+
+	get("a", a)
+	if fetches != 1 {
+		t.Fatalf("Expected 1 fetch, saw %d", fetches)
+	}
+	// Second time is from the cache.
+	get("a", a)
+	if fetches != 1 {
+		t.Fatalf("Cached file fetched again")
+	}
+
+	// A corrupted cache entry is replaced.
+	aHash := sha256.Sum256(a)
+	ioutil.WriteFile(cache.path(aHash[:]), []byte("corrupt"), 0644)
+	get("a", a)
+	if fetches != 2 {
+		t.Fatalf("Corrupted cache entry not re-fetched")
+	}
+
+	// A fetched file with the wrong hash is rejected and not cached.
+	bHash := sha256.Sum256(b)
+	_, err := cache.fetch(tmpDir, "b", bHash[:], fetcher("b", c), prog)
+	if err == nil {
+		t.Fatalf("No error for bad hash")
+	}
+	if fileExists(cache.path(bHash[:])) {
+		t.Fatalf("File with bad hash was cached")
+	}
+
+	// Make a the oldest file, then go over the limit. a should be evicted.
+	old := time.Now().Add(-time.Hour)
+	os.Chtimes(cache.path(aHash[:]), old, old)
+	get("b", b)
+	get("c", c)
+	if fileExists(cache.path(aHash[:])) {
+		t.Fatalf("Least recently used file not evicted")
+	}
+	cHash := sha256.Sum256(c)
+	if !fileExists(cache.path(bHash[:])) || !fileExists(cache.path(cHash[:])) {
+		t.Fatalf("Recently used file evicted")
+	}
+
+	if err := cache.clear(); err != nil {
+		t.Fatalf("clear error: %v", err)
+	}
+	if fileExists(cache.dir) {
+		t.Fatalf("Cache not cleared")
+	}
+}
@@ -24,6 +24,9 @@ import (
 )
 
 const (
+	// logViewLines is the number of lines shown in the log viewer.
+	logViewLines = 100
+
 	introductionText = "For the best security and the full range of Decred services, you'll want to sync the full blockchain, which will use around 5 GB of disk space. If you're only interested in basic wallet functionality, you may choose to sync in SPV mode, which will be very fast and use about 100 MB of disk space."
 )
 
@@ -77,9 +80,20 @@ type GUI struct {
 
 	// Intro page
 	intro struct {
-		box   *ui.Element
-		pw    *betterEntry
-		pwRow *ui.Element
+		box     *ui.Element
+		pw      *betterEntry
+		pwRow   *ui.Element
+		seed    *betterEntry
+		seedRow *ui.Element
+		network eco.Network
+		netLbl  *ui.EcoLabel
+		// The remote dcrd form.
+		remoteBox  *ui.Element
+		remoteHost *betterEntry
+		remoteUser *betterEntry
+		remotePass *betterEntry
+		remoteCert *betterEntry
+		remoteMsg  *ui.EcoLabel
 	}
 
 	// Downloading page
@@ -92,6 +106,8 @@ type GUI struct {
 	// Home page
 	home struct {
 		box          *ui.Element
+		notification *widget.Label
+		notifyBox    *ui.Element
 		dcrdProgress *ui.EcoLabel
 		dcrwProgress *ui.EcoLabel
 		appRow       *ui.Element
@@ -117,6 +133,61 @@ type GUI struct {
 		spinner    *spinner
 	}
 
+	settings struct {
+		view       *ui.Element
+		channel    *ui.EcoLabel
+		msg        *ui.EcoLabel
+		storage    *ui.Element
+		storageMsg *ui.EcoLabel
+		version    *ui.EcoLabel
+		versions   *ui.Element
+		versionMsg *ui.EcoLabel
+		proxyAddr  *betterEntry
+		proxyUser  *betterEntry
+		proxyPass  *betterEntry
+		proxyMode  *ui.EcoLabel
+		proxyMsg   *ui.EcoLabel
+	}
+
+	// dcrd settings page
+	dcrdSettings struct {
+		view       *ui.Element
+		debugLevel *betterEntry
+		maxPeers   *betterEntry
+		addPeers   *betterEntry
+		connect    *betterEntry
+		dataDir    *betterEntry
+		txIndex    *widget.Check
+		addrIndex  *widget.Check
+		blocksOnly *widget.Check
+		msg        *ui.EcoLabel
+	}
+
+	// Wallet seed backup page
+	seed struct {
+		view    *ui.Element
+		pw      *betterEntry
+		content *ui.Element
+		msg     *ui.EcoLabel
+		answers []*betterEntry
+	}
+
+	// Unlock page, shown when setup steps are waiting for the password.
+	unlock struct {
+		view *ui.Element
+		pw   *betterEntry
+		msg  *ui.EcoLabel
+	}
+
+	logs struct {
+		view    *ui.Element
+		svcLbl  *ui.EcoLabel
+		msg     *ui.EcoLabel
+		results *betterEntry
+		// cancel stops following the current service log.
+		cancel context.CancelFunc
+	}
+
 	dcrctl struct {
 		// AppLauncher.
 		launcher *ui.Element
@@ -157,6 +228,11 @@ func NewGUI(ctx context.Context) *GUI {
 	gui.initializeDownloadView()
 	gui.initializeHomeView()
 	gui.initializeDCRCtl()
+	gui.initializeSettingsView()
+	gui.initializeDCRDSettingsView()
+	gui.initializeSeedView()
+	gui.initializeUnlockView()
+	gui.initializeLogsView()
 
 	gui.showHomeView()
 	// gui.showDCRCtl()
@@ -187,6 +263,7 @@ func (gui *GUI) Run() {
 			}
 		}
 		gui.storeEcoState(&state.Eco)
+		gui.settings.channel.SetText(state.Eco.ReleaseChannel.String())
 
 		st := state.Services["dcrd"]
 		dcrdLoading := st == nil
@@ -203,7 +280,7 @@ func (gui *GUI) Run() {
 		}
 
 		var dexLoading bool
-		if state.Eco.SyncMode == eco.SyncModeFull {
+		if state.Eco.SyncMode == eco.SyncModeFull || state.Eco.SyncMode == eco.SyncModeRemote {
 			gui.dex.launcher.Show()
 			if walletSyncing {
 				dexLoading = true
@@ -214,10 +291,17 @@ func (gui *GUI) Run() {
 
 		if state.Eco.WalletExists {
 			gui.intro.pwRow.Hide()
+			gui.intro.seedRow.Hide()
+		}
+
+		if state.Eco.SyncMode != eco.SyncModeUninitialized && !state.Eco.Network.HasDecrediton() {
+			gui.decrediton.launcher.Hide()
 		}
 
 		if state.Eco.SyncMode == eco.SyncModeUninitialized {
 			gui.showIntroView()
+		} else if state.Locked {
+			gui.setView(gui.unlock.view)
 		}
 
 		gui.home.box.Refresh()
@@ -247,6 +331,12 @@ func (gui *GUI) Run() {
 					gui.processDCRDSyncUpdate(u)
 				}
 			},
+			Notification: func(n *eco.Notification) {
+				gui.home.notification.SetText(fmt.Sprintf("%s: %s", n.Subject, n.Details))
+				gui.home.notifyBox.Show()
+				gui.home.box.Refresh()
+				canvas.Refresh(gui.home.box)
+			},
 			ServiceStatus: func(st *eco.ServiceStatus) {
 
 				fmt.Println("--ServiceStatus", dirtyEncode(st))
@@ -399,6 +489,10 @@ func (gui *GUI) Run() {
 }
 
 func (gui *GUI) setView(wgt fyne.CanvasObject) {
+	if gui.logs.cancel != nil {
+		gui.logs.cancel()
+		gui.logs.cancel = nil
+	}
 	gui.mainView.RemoveChildByIndex(0)
 	gui.mainView.InsertChild(wgt, 0)
 	gui.mainView.Refresh()
@@ -422,15 +516,28 @@ func (gui *GUI) initializeIntroView() {
 		MaxW:         450,
 	}, pw)
 
+	// An existing seed is optional. Without one, a new wallet is created.
+	gui.intro.seed, gui.intro.seedRow = inputRow("restore from seed (optional), hex or 33 words", true)
+
 	bttn1 := newEcoBttn(&bttnOpts{
 		bgColor:    ui.ButtonColor2,
 		hoverColor: ui.ButtonHoverColor2,
 	}, "Full Sync", func(*fyne.PointEvent) {
-		gui.initEco(eco.SyncModeFull)
+		gui.initEco(eco.SyncModeFull, nil)
 	})
 
 	bttn2 := newEcoBttn(nil, "Lite Mode (SPV)", func(*fyne.PointEvent) {
-		gui.initEco(eco.SyncModeSPV)
+		gui.initEco(eco.SyncModeSPV, nil)
+	})
+
+	bttn3 := newEcoBttn(nil, "Remote dcrd", func(*fyne.PointEvent) {
+		if gui.intro.remoteBox.Visible() {
+			gui.intro.remoteBox.Hide()
+		} else {
+			gui.intro.remoteBox.Show()
+		}
+		gui.intro.box.Refresh()
+		canvas.Refresh(gui.intro.box)
 	})
 
 	bttnRow := ui.NewElement(&ui.Style{
@@ -440,6 +547,30 @@ func (gui *GUI) initializeIntroView() {
 	},
 		bttn1,
 		bttn2,
+		bttn3,
+	)
+
+	gui.initializeRemoteForm()
+
+	gui.intro.netLbl = ui.NewEcoLabel(eco.NetworkMainnet.String(), &ui.TextStyle{FontSize: 15, Bold: true})
+	netBttn := func(network eco.Network) *ui.Element {
+		return newEcoBttn(&bttnOpts{paddingX: 10, paddingY: 5, fontSize: 13}, network.String(), func(*fyne.PointEvent) {
+			gui.intro.network = network
+			gui.intro.netLbl.SetText(network.String())
+			gui.intro.box.Refresh()
+			canvas.Refresh(gui.intro.box)
+		})
+	}
+	netRow := ui.NewElement(&ui.Style{
+		Ori:     ui.OrientationHorizontal,
+		Align:   ui.AlignMiddle,
+		Spacing: 15,
+	},
+		ui.NewEcoLabel("Network:", &ui.TextStyle{FontSize: 15}),
+		gui.intro.netLbl,
+		netBttn(eco.NetworkMainnet),
+		netBttn(eco.NetworkTestnet),
+		netBttn(eco.NetworkSimnet),
 	)
 
 	gui.intro.box = ui.NewElement(
@@ -453,10 +584,71 @@ func (gui *GUI) initializeIntroView() {
 		gui.logo,
 		ui.NewLabelWithWidth(intro, 430),
 		gui.intro.pwRow,
+		gui.intro.seedRow,
+		netRow,
 		bttnRow,
+		gui.intro.remoteBox,
+		gui.settingsLink(),
 	)
 }
 
+// inputRow creates a single-line text input in a styled row.
+func inputRow(placeholder string, password bool) (*betterEntry, *ui.Element) {
+	entry := &betterEntry{Entry: &widget.Entry{}, w: 430}
+	entry.PlaceHolder = placeholder
+	entry.Password = password
+	entry.ExtendBaseWidget(entry)
+	return entry, ui.NewElement(&ui.Style{
+		Padding:      ui.FourSpec{10, 10, 10, 10},
+		BgColor:      ui.InputColor,
+		BorderRadius: 3,
+		MaxW:         450,
+	}, entry)
+}
+
+// initializeRemoteForm creates the form for connecting to an existing dcrd.
+// The form is hidden until the user selects the remote dcrd mode.
+func (gui *GUI) initializeRemoteForm() {
+	var hostRow, userRow, passRow, certRow *ui.Element
+	gui.intro.remoteHost, hostRow = inputRow("dcrd RPC address, e.g. 10.0.0.5:9109", false)
+	gui.intro.remoteUser, userRow = inputRow("RPC username", false)
+	gui.intro.remotePass, passRow = inputRow("RPC password", true)
+	gui.intro.remoteCert, certRow = inputRow("path to dcrd's rpc.cert", false)
+	gui.intro.remoteMsg = ui.NewEcoLabel("", nil)
+
+	connectBttn := newEcoBttn(&bttnOpts{
+		bgColor:    ui.ButtonColor2,
+		hoverColor: ui.ButtonHoverColor2,
+	}, "Connect", func(*fyne.PointEvent) {
+		cert, err := ioutil.ReadFile(strings.TrimSpace(gui.intro.remoteCert.Text))
+		if err != nil {
+			gui.intro.remoteMsg.SetText("Error reading certificate: %v", err)
+			gui.intro.box.Refresh()
+			return
+		}
+		gui.initEco(eco.SyncModeRemote, &eco.RemoteDCRD{
+			Host:    gui.intro.remoteHost.Text,
+			RPCUser: gui.intro.remoteUser.Text,
+			RPCPass: gui.intro.remotePass.Text,
+			Cert:    cert,
+		})
+	})
+
+	gui.intro.remoteBox = ui.NewElement(&ui.Style{
+		Spacing: 10,
+		Align:   ui.AlignCenter,
+	},
+		ui.NewEcoLabel("Use an existing dcrd", &ui.TextStyle{FontSize: 15, Bold: true}),
+		hostRow,
+		userRow,
+		passRow,
+		certRow,
+		gui.intro.remoteMsg,
+		connectBttn,
+	)
+	gui.intro.remoteBox.Hide()
+}
+
 func (gui *GUI) showIntroView() {
 	gui.setView(gui.intro.box)
 }
@@ -611,10 +803,8 @@ func (gui *GUI) initializeHomeView() {
 			log.Errorf("Cannot start decrediton. Service not available.")
 			return
 		}
-		if st.On {
-			// Nothing to do
-			return
-		}
+		// dexc may be running without a window, so always ask. Eco won't open
+		// a second window.
 		eco.StartDEX(gui.ctx)
 	},
 		ui.NewSizedImage(dexLogo, 0, logoHeight),
@@ -697,6 +887,19 @@ func (gui *GUI) initializeHomeView() {
 		gui.home.xcDatum, gui.home.sdDatum, gui.home.hrDatum, gui.home.bhDatum,
 	)
 
+	// A box for messages about things Eco did on its own, like rolling back a
+	// failed upgrade. Hidden until there's a Notification.
+	gui.home.notification = widget.NewLabel("")
+	gui.home.notification.Wrapping = fyne.TextWrapWord
+	gui.home.notifyBox = ui.NewElement(&ui.Style{
+		Padding:      ui.FourSpec{10, 10, 10, 10},
+		Margins:      ui.FourSpec{10, 0, 10, 0},
+		BgColor:      ui.InputColor,
+		BorderRadius: 3,
+		MaxW:         900,
+	}, ui.NewLabelWithWidth(gui.home.notification, 880))
+	gui.home.notifyBox.Hide()
+
 	// topHR := ui.NewHorizontalRule(1, ui.DefaultBorderColor, 5)
 	// topHR.Style.Margins[0] = 20
 
@@ -710,6 +913,7 @@ func (gui *GUI) initializeHomeView() {
 			// justi:            justifyStart,
 		},
 		gui.logo,
+		gui.home.notifyBox,
 		// topHR,
 		sectionHeader("Stats"),
 		gui.home.stats,
@@ -719,6 +923,8 @@ func (gui *GUI) initializeHomeView() {
 		ui.NewHorizontalRule(1, ui.DefaultBorderColor, 5),
 		sectionHeader("Sync"),
 		progressRow,
+		ui.NewHorizontalRule(1, ui.DefaultBorderColor, 5),
+		gui.settingsLink(),
 	)
 	// gui.home.box.Name = "homeBox"
 }
@@ -727,7 +933,9 @@ func (gui *GUI) showHomeView() {
 	gui.setView(gui.home.box)
 }
 
-func (gui *GUI) initializeDCRCtl() {
+// backLink creates a row with a link back to the home view, or to the intro
+// view if Eco is not initialized yet.
+func (gui *GUI) backLink(minW int) *ui.Element {
 	larrow := canvas.NewImageFromResource(leftArrow)
 	sz := fyne.NewSize(13, 13)
 	larrow.Resize(sz)
@@ -746,6 +954,11 @@ func (gui *GUI) initializeDCRCtl() {
 		Spacing: 5,
 		Listeners: ui.EventListeners{
 			Click: func(ev *fyne.PointEvent) {
+				st := gui.ecoState()
+				if st != nil && st.SyncMode == eco.SyncModeUninitialized {
+					gui.showIntroView()
+					return
+				}
 				gui.showHomeView()
 			},
 			MouseIn: func(*desktop.MouseEvent) {
@@ -757,13 +970,17 @@ func (gui *GUI) initializeDCRCtl() {
 		},
 	}, larrow, goHome)
 
-	linkRow := ui.NewElement(&ui.Style{
+	return ui.NewElement(&ui.Style{
 		Align:   ui.AlignLeft,
 		Display: ui.DisplayInline,
-		MinW:    750,
+		MinW:    minW,
 	},
 		link,
 	)
+}
+
+func (gui *GUI) initializeDCRCtl() {
+	linkRow := gui.backLink(750)
 
 	var resultDiv *ui.Element
 	var results *betterEntry
@@ -842,6 +1059,762 @@ func (gui *GUI) showDCRCtl() {
 	gui.setView(gui.dcrctl.view)
 }
 
+// settingsLink creates a clickable label that opens the settings view.
+func (gui *GUI) settingsLink() *ui.Element {
+	return ui.NewElement(&ui.Style{
+		Ori:     ui.OrientationHorizontal,
+		Cursor:  desktop.PointerCursor,
+		Display: ui.DisplayInline,
+	},
+		ui.NewEcoLabel("Settings", &ui.TextStyle{FontSize: 15, Bold: true}, func(*fyne.PointEvent) {
+			gui.showSettingsView()
+		}),
+	)
+}
+
+func (gui *GUI) initializeSettingsView() {
+	gui.settings.channel = ui.NewEcoLabel(eco.ReleaseChannelStable.String(), &ui.TextStyle{FontSize: 15, Bold: true})
+	gui.settings.msg = ui.NewEcoLabel("", nil)
+
+	setChannel := func(channel eco.ReleaseChannel) {
+		err := eco.SetReleaseChannel(gui.ctx, channel)
+		if err != nil {
+			gui.settings.msg.SetText("Error setting release channel: %v", err)
+		} else {
+			gui.settings.msg.SetText("")
+			gui.settings.channel.SetText(channel.String())
+			if st := gui.ecoState(); st != nil {
+				stCopy := *st
+				stCopy.ReleaseChannel = channel
+				gui.storeEcoState(&stCopy)
+			}
+		}
+		gui.settings.view.Refresh()
+		canvas.Refresh(gui.settings.view)
+	}
+
+	channelRow := ui.NewElement(&ui.Style{
+		Ori:     ui.OrientationHorizontal,
+		Align:   ui.AlignMiddle,
+		Spacing: 20,
+	},
+		ui.NewEcoLabel("Release channel:", &ui.TextStyle{FontSize: 15}),
+		gui.settings.channel,
+		newEcoBttn(nil, "Stable", func(*fyne.PointEvent) {
+			setChannel(eco.ReleaseChannelStable)
+		}),
+		newEcoBttn(nil, "Pre-release", func(*fyne.PointEvent) {
+			setChannel(eco.ReleaseChannelPrerelease)
+		}),
+	)
+
+	gui.settings.version = ui.NewEcoLabel("", &ui.TextStyle{FontSize: 15, Bold: true})
+	gui.settings.versions = ui.NewElement(&ui.Style{
+		Ori:     ui.OrientationHorizontal,
+		Align:   ui.AlignMiddle,
+		Spacing: 20,
+	})
+	gui.settings.versionMsg = ui.NewEcoLabel("", nil)
+	versionRow := ui.NewElement(&ui.Style{
+		Ori:     ui.OrientationHorizontal,
+		Align:   ui.AlignMiddle,
+		Spacing: 20,
+	},
+		ui.NewEcoLabel("Running version:", &ui.TextStyle{FontSize: 15}),
+		gui.settings.version,
+		newEcoBttn(nil, "Check for upgrade", func(*fyne.PointEvent) {
+			go gui.upgrade()
+		}),
+	)
+
+	gui.settings.storage = ui.NewElement(&ui.Style{
+		Spacing: 5,
+		MinW:    450,
+	})
+	gui.settings.storageMsg = ui.NewEcoLabel("", nil)
+
+	storageBttns := ui.NewElement(&ui.Style{
+		Ori:     ui.OrientationHorizontal,
+		Align:   ui.AlignMiddle,
+		Spacing: 20,
+	},
+		newEcoBttn(nil, "Refresh", func(*fyne.PointEvent) {
+			gui.refreshStorage()
+		}),
+		newEcoBttn(nil, "Prune old versions", func(*fyne.PointEvent) {
+			removed, err := eco.PruneVersions(gui.ctx)
+			switch {
+			case err != nil:
+				gui.settings.storageMsg.SetText("Error pruning versions: %v", err)
+			case len(removed) == 0:
+				gui.settings.storageMsg.SetText("No versions to prune")
+			default:
+				gui.settings.storageMsg.SetText("Removed %s", strings.Join(removed, ", "))
+			}
+			gui.refreshStorage()
+		}),
+		newEcoBttn(nil, "Clear download cache", func(*fyne.PointEvent) {
+			if err := eco.ClearCache(gui.ctx); err != nil {
+				gui.settings.storageMsg.SetText("Error clearing download cache: %v", err)
+			} else {
+				gui.settings.storageMsg.SetText("Download cache cleared")
+			}
+			gui.refreshStorage()
+		}),
+	)
+
+	var logLinks []fyne.CanvasObject
+	for _, svc := range []string{"dcrd", "dcrwallet", "dexc", "decrediton"} {
+		svc := svc
+		logLinks = append(logLinks, newEcoBttn(nil, svc, func(*fyne.PointEvent) {
+			gui.showLogsView(svc)
+		}))
+	}
+	logBttns := ui.NewElement(&ui.Style{
+		Ori:     ui.OrientationHorizontal,
+		Align:   ui.AlignMiddle,
+		Spacing: 20,
+	}, logLinks...)
+
+	var proxyAddrRow, proxyUserRow, proxyPassRow *ui.Element
+	gui.settings.proxyAddr, proxyAddrRow = inputRow("SOCKS5 proxy address, e.g. 127.0.0.1:9050", false)
+	gui.settings.proxyUser, proxyUserRow = inputRow("proxy username (optional)", false)
+	gui.settings.proxyPass, proxyPassRow = inputRow("proxy password (optional)", true)
+	gui.settings.proxyMode = ui.NewEcoLabel(proxyModeText(nil), &ui.TextStyle{FontSize: 15, Bold: true})
+	gui.settings.proxyMsg = ui.NewEcoLabel("", nil)
+	setProxy := func(p *eco.ProxyConfig) {
+		if p != nil {
+			p.Addr = gui.settings.proxyAddr.Text
+			p.User = gui.settings.proxyUser.Text
+			p.Pass = gui.settings.proxyPass.Text
+		}
+		if err := eco.SetProxy(gui.ctx, p); err != nil {
+			gui.settings.proxyMsg.SetText("Error setting proxy: %v", err)
+		} else {
+			gui.settings.proxyMode.SetText(proxyModeText(p))
+			gui.settings.proxyMsg.SetText("Saved. Restart the services to use the new setting.")
+			if st := gui.ecoState(); st != nil {
+				stCopy := *st
+				if p != nil {
+					// Eco never sends the password back, so don't keep it
+					// here either.
+					pCopy := *p
+					pCopy.Pass = ""
+					stCopy.Proxy = &pCopy
+				} else {
+					stCopy.Proxy = nil
+				}
+				gui.storeEcoState(&stCopy)
+			}
+		}
+		gui.settings.view.Refresh()
+		canvas.Refresh(gui.settings.view)
+	}
+	proxyBttns := ui.NewElement(&ui.Style{
+		Ori:     ui.OrientationHorizontal,
+		Align:   ui.AlignMiddle,
+		Spacing: 20,
+	},
+		gui.settings.proxyMode,
+		newEcoBttn(nil, "All traffic", func(*fyne.PointEvent) {
+			setProxy(&eco.ProxyConfig{})
+		}),
+		newEcoBttn(nil, "Onion only", func(*fyne.PointEvent) {
+			setProxy(&eco.ProxyConfig{OnionOnly: true})
+		}),
+		newEcoBttn(nil, "Off", func(*fyne.PointEvent) {
+			setProxy(nil)
+		}),
+	)
+
+	gui.settings.view = ui.NewElement(
+		&ui.Style{
+			Padding: ui.FourSpec{20, 0, 0, 0},
+			Align:   ui.AlignCenter,
+			Spacing: 15,
+		},
+		gui.logo,
+		gui.backLink(750),
+		ui.NewEcoLabel("Settings", &ui.TextStyle{FontSize: 18, Bold: true}),
+		newEcoBttn(nil, "dcrd settings", func(*fyne.PointEvent) {
+			gui.showDCRDSettingsView()
+		}),
+		newEcoBttn(nil, "Wallet seed", func(*fyne.PointEvent) {
+			gui.showSeedView()
+		}),
+		channelRow,
+		gui.settings.msg,
+		ui.NewHorizontalRule(1, ui.DefaultBorderColor, 5),
+		ui.NewEcoLabel("Version", &ui.TextStyle{FontSize: 18, Bold: true}),
+		versionRow,
+		gui.settings.versions,
+		gui.settings.versionMsg,
+		ui.NewHorizontalRule(1, ui.DefaultBorderColor, 5),
+		ui.NewEcoLabel("Storage", &ui.TextStyle{FontSize: 18, Bold: true}),
+		gui.settings.storage,
+		storageBttns,
+		gui.settings.storageMsg,
+		ui.NewHorizontalRule(1, ui.DefaultBorderColor, 5),
+		ui.NewEcoLabel("Service logs", &ui.TextStyle{FontSize: 18, Bold: true}),
+		logBttns,
+		ui.NewHorizontalRule(1, ui.DefaultBorderColor, 5),
+		ui.NewEcoLabel("Proxy", &ui.TextStyle{FontSize: 18, Bold: true}),
+		proxyAddrRow,
+		proxyUserRow,
+		proxyPassRow,
+		proxyBttns,
+		gui.settings.proxyMsg,
+	)
+}
+
+// proxyModeText describes the proxy setting.
+func proxyModeText(p *eco.ProxyConfig) string {
+	switch {
+	case p == nil:
+		return "No proxy"
+	case p.OnionOnly:
+		return "Onion only"
+	}
+	return "All traffic"
+}
+
+func (gui *GUI) showSettingsView() {
+	gui.refreshStorage()
+	gui.refreshVersions()
+	if st := gui.ecoState(); st != nil {
+		if p := st.Proxy; p != nil {
+			gui.settings.proxyAddr.SetText(p.Addr)
+			gui.settings.proxyUser.SetText(p.User)
+			// The saved password isn't sent to clients. Leaving the field
+			// blank keeps it.
+			gui.settings.proxyPass.SetText("")
+			if p.User != "" {
+				gui.settings.proxyPass.SetPlaceHolder("proxy password (blank to keep the saved password)")
+			}
+		}
+		gui.settings.proxyMode.SetText(proxyModeText(st.Proxy))
+	}
+	gui.setView(gui.settings.view)
+}
+
+func (gui *GUI) initializeDCRDSettingsView() {
+	ds := &gui.dcrdSettings
+	var levelRow, peersRow, addRow, connectRow, dataRow *ui.Element
+	ds.debugLevel, levelRow = inputRow("debug level, e.g. info", false)
+	ds.maxPeers, peersRow = inputRow("max peers (blank for the default)", false)
+	ds.addPeers, addRow = inputRow("persistent peers, comma-separated", false)
+	ds.connect, connectRow = inputRow("connect only to these peers, comma-separated", false)
+	ds.dataDir, dataRow = inputRow("blockchain data directory (blank for the default)", false)
+	ds.txIndex = widget.NewCheck("Transaction index", nil)
+	ds.addrIndex = widget.NewCheck("Address index (requires the transaction index)", nil)
+	ds.blocksOnly = widget.NewCheck("Bandwidth saver (don't relay unconfirmed transactions)", nil)
+	ds.msg = ui.NewEcoLabel("", nil)
+
+	saveBttn := newEcoBttn(&bttnOpts{
+		bgColor:    ui.ButtonColor2,
+		hoverColor: ui.ButtonHoverColor2,
+	}, "Save", func(*fyne.PointEvent) {
+		gui.saveDCRDSettings()
+	})
+
+	gui.dcrdSettings.view = ui.NewElement(
+		&ui.Style{
+			Padding: ui.FourSpec{20, 0, 0, 0},
+			Align:   ui.AlignCenter,
+			Spacing: 15,
+		},
+		gui.logo,
+		gui.backLink(750),
+		ui.NewEcoLabel("dcrd settings", &ui.TextStyle{FontSize: 18, Bold: true}),
+		ui.NewEcoLabel("Changes other than the debug level restart dcrd.", nil),
+		levelRow,
+		peersRow,
+		addRow,
+		connectRow,
+		dataRow,
+		ds.txIndex,
+		ds.addrIndex,
+		ds.blocksOnly,
+		saveBttn,
+		ds.msg,
+	)
+}
+
+// showDCRDSettingsView fetches the current settings into the dcrd settings
+// form.
+func (gui *GUI) showDCRDSettingsView() {
+	ds := &gui.dcrdSettings
+	ds.msg.SetText("")
+	gui.setView(ds.view)
+	settings, err := eco.GetSettings(gui.ctx)
+	if err != nil {
+		ds.msg.SetText("Error retrieving settings: %v", err)
+		ds.view.Refresh()
+		return
+	}
+	s := settings.DCRD
+	ds.debugLevel.SetText(s.DebugLevel)
+	ds.maxPeers.SetText("")
+	if s.MaxPeers > 0 {
+		ds.maxPeers.SetText(strconv.Itoa(s.MaxPeers))
+	}
+	ds.addPeers.SetText(strings.Join(s.AddPeers, ", "))
+	ds.connect.SetText(strings.Join(s.ConnectPeers, ", "))
+	ds.dataDir.SetText(s.DataDir)
+	ds.txIndex.SetChecked(s.TxIndex)
+	ds.addrIndex.SetChecked(s.AddrIndex)
+	ds.blocksOnly.SetChecked(s.BlocksOnly)
+	ds.view.Refresh()
+	canvas.Refresh(ds.view)
+}
+
+// saveDCRDSettings sends the dcrd settings form to Eco.
+func (gui *GUI) saveDCRDSettings() {
+	ds := &gui.dcrdSettings
+	splitPeers := func(s string) []string {
+		var peers []string
+		for _, p := range strings.Split(s, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				peers = append(peers, p)
+			}
+		}
+		return peers
+	}
+	var maxPeers int
+	if txt := strings.TrimSpace(ds.maxPeers.Text); txt != "" {
+		var err error
+		maxPeers, err = strconv.Atoi(txt)
+		if err != nil {
+			ds.msg.SetText("Max peers must be a number")
+			ds.view.Refresh()
+			return
+		}
+	}
+	s := eco.DCRDUserSettings{
+		DebugLevel:   strings.TrimSpace(ds.debugLevel.Text),
+		TxIndex:      ds.txIndex.Checked,
+		AddrIndex:    ds.addrIndex.Checked,
+		MaxPeers:     maxPeers,
+		AddPeers:     splitPeers(ds.addPeers.Text),
+		ConnectPeers: splitPeers(ds.connect.Text),
+		BlocksOnly:   ds.blocksOnly.Checked,
+		DataDir:      ds.dataDir.Text,
+	}
+	ds.msg.SetText("Saving...")
+	ds.view.Refresh()
+	go func() {
+		restarted, err := eco.SetSettings(gui.ctx, &eco.Settings{DCRD: s})
+		switch {
+		case err != nil:
+			ds.msg.SetText("Error saving settings: %v", err)
+		case restarted:
+			ds.msg.SetText("Settings saved. dcrd was restarted.")
+		default:
+			ds.msg.SetText("Settings saved")
+		}
+		ds.view.Refresh()
+		canvas.Refresh(ds.view)
+	}()
+}
+
+func (gui *GUI) initializeSeedView() {
+	var pwRow *ui.Element
+	gui.seed.pw, pwRow = inputRow("password", true)
+	gui.seed.msg = ui.NewEcoLabel("", nil)
+	gui.seed.content = ui.NewElement(&ui.Style{
+		Align:   ui.AlignCenter,
+		Spacing: 10,
+	})
+
+	revealBttn := newEcoBttn(&bttnOpts{
+		bgColor:    ui.ButtonColor2,
+		hoverColor: ui.ButtonHoverColor2,
+	}, "Reveal seed", func(*fyne.PointEvent) {
+		gui.revealSeed()
+	})
+
+	gui.seed.view = ui.NewElement(
+		&ui.Style{
+			Padding: ui.FourSpec{20, 0, 0, 0},
+			Align:   ui.AlignCenter,
+			Spacing: 15,
+		},
+		gui.logo,
+		gui.backLink(750),
+		ui.NewEcoLabel("Wallet seed", &ui.TextStyle{FontSize: 18, Bold: true}),
+		ui.NewEcoLabel("Write the words down in order, and keep them somewhere safe.", nil),
+		ui.NewEcoLabel("Once you confirm your backup, Eco deletes its copy of the seed.", nil),
+		pwRow,
+		revealBttn,
+		gui.seed.content,
+		gui.seed.msg,
+	)
+}
+
+func (gui *GUI) showSeedView() {
+	gui.clearSeedContent()
+	gui.seed.pw.SetText("")
+	gui.seed.msg.SetText("")
+	if st := gui.ecoState(); st != nil && st.SeedBackedUp {
+		gui.seed.msg.SetText("Your seed backup was confirmed, and the seed was deleted from Eco.")
+	}
+	gui.setView(gui.seed.view)
+}
+
+func (gui *GUI) clearSeedContent() {
+	for gui.seed.content.RemoveChildByIndex(0) {
+	}
+	gui.seed.answers = nil
+}
+
+// revealSeed shows the seed words, with a button to start the backup quiz.
+func (gui *GUI) revealSeed() {
+	pw := []byte(gui.seed.pw.Text)
+	gui.seed.msg.SetText("Decrypting...")
+	gui.seed.view.Refresh()
+	go func() {
+		defer canvas.Refresh(gui.seed.view)
+		words, err := eco.RevealSeed(gui.ctx, pw)
+		gui.clearSeedContent()
+		if err != nil {
+			gui.seed.msg.SetText("Error revealing seed: %v", err)
+			gui.seed.view.Refresh()
+			return
+		}
+		gui.seed.msg.SetText("")
+		const wordsPerRow = 6
+		for i := 0; i < len(words); i += wordsPerRow {
+			var cells []fyne.CanvasObject
+			for j := i; j < i+wordsPerRow && j < len(words); j++ {
+				cells = append(cells, ui.NewEcoLabel(fmt.Sprintf("%d. %s", j+1, words[j]), &ui.TextStyle{FontSize: 15}))
+			}
+			gui.seed.content.InsertChild(ui.NewElement(&ui.Style{
+				Ori:     ui.OrientationHorizontal,
+				Spacing: 20,
+			}, cells...), -1)
+		}
+		gui.seed.content.InsertChild(newEcoBttn(nil, "I've written it down", func(*fyne.PointEvent) {
+			gui.startSeedQuiz()
+		}), -1)
+		gui.seed.view.Refresh()
+	}()
+}
+
+// startSeedQuiz replaces the seed words with entries for the quiz words.
+func (gui *GUI) startSeedQuiz() {
+	gui.clearSeedContent()
+	indices, err := eco.SeedQuiz(gui.ctx)
+	if err != nil {
+		gui.seed.msg.SetText("Error starting the quiz: %v", err)
+		gui.seed.view.Refresh()
+		canvas.Refresh(gui.seed.view)
+		return
+	}
+	gui.seed.content.InsertChild(ui.NewEcoLabel("Enter these words from your backup.", nil), -1)
+	for _, idx := range indices {
+		entry, row := inputRow(fmt.Sprintf("word #%d", idx+1), false)
+		gui.seed.answers = append(gui.seed.answers, entry)
+		gui.seed.content.InsertChild(row, -1)
+	}
+	gui.seed.content.InsertChild(newEcoBttn(&bttnOpts{
+		bgColor:    ui.ButtonColor2,
+		hoverColor: ui.ButtonHoverColor2,
+	}, "Confirm and delete seed", func(*fyne.PointEvent) {
+		gui.deleteSeed()
+	}), -1)
+	gui.seed.msg.SetText("")
+	gui.seed.view.Refresh()
+	canvas.Refresh(gui.seed.view)
+}
+
+// deleteSeed submits the quiz answers. A failed quiz starts a new one.
+func (gui *GUI) deleteSeed() {
+	pw := []byte(gui.seed.pw.Text)
+	answers := make([]string, 0, len(gui.seed.answers))
+	for _, entry := range gui.seed.answers {
+		answers = append(answers, entry.Text)
+	}
+	gui.seed.msg.SetText("Checking...")
+	gui.seed.view.Refresh()
+	go func() {
+		defer canvas.Refresh(gui.seed.view)
+		if err := eco.DeleteSeed(gui.ctx, pw, answers); err != nil {
+			gui.startSeedQuiz()
+			gui.seed.msg.SetText("%v. Here's a new quiz.", err)
+			gui.seed.view.Refresh()
+			return
+		}
+		gui.clearSeedContent()
+		gui.seed.pw.SetText("")
+		gui.seed.msg.SetText("Backup confirmed. The seed was deleted from Eco.")
+		if st := gui.ecoState(); st != nil {
+			stCopy := *st
+			stCopy.SeedBackedUp = true
+			gui.storeEcoState(&stCopy)
+		}
+		gui.seed.view.Refresh()
+	}()
+}
+
+func (gui *GUI) initializeUnlockView() {
+	var pwRow *ui.Element
+	gui.unlock.pw, pwRow = inputRow("password", true)
+	gui.unlock.msg = ui.NewEcoLabel("", nil)
+
+	unlockBttn := newEcoBttn(&bttnOpts{
+		bgColor:    ui.ButtonColor2,
+		hoverColor: ui.ButtonHoverColor2,
+	}, "Unlock", func(*fyne.PointEvent) {
+		gui.unlockEco()
+	})
+
+	gui.unlock.view = ui.NewElement(
+		&ui.Style{
+			Padding: ui.FourSpec{20, 0, 0, 0},
+			Align:   ui.AlignCenter,
+			Spacing: 15,
+		},
+		gui.logo,
+		gui.backLink(750),
+		ui.NewEcoLabel("Unlock Eco", &ui.TextStyle{FontSize: 18, Bold: true}),
+		ui.NewEcoLabel("Eco was restarted before setup finished. Enter your password to continue.", nil),
+		pwRow,
+		unlockBttn,
+		gui.unlock.msg,
+	)
+}
+
+// unlockEco sends the password to Eco, and returns to the home view once the
+// pending setup steps have started.
+func (gui *GUI) unlockEco() {
+	pw := []byte(gui.unlock.pw.Text)
+	gui.unlock.msg.SetText("Unlocking...")
+	gui.unlock.view.Refresh()
+	go func() {
+		err := eco.Unlock(gui.ctx, pw)
+		if err != nil {
+			gui.unlock.msg.SetText("Error unlocking: %v", err)
+			gui.unlock.view.Refresh()
+			canvas.Refresh(gui.unlock.view)
+			return
+		}
+		gui.unlock.pw.SetText("")
+		gui.unlock.msg.SetText("")
+		gui.showHomeView()
+	}()
+}
+
+func (gui *GUI) initializeLogsView() {
+	gui.logs.svcLbl = ui.NewEcoLabel("", &ui.TextStyle{FontSize: 18, Bold: true})
+	gui.logs.msg = ui.NewEcoLabel("", nil)
+
+	results := &betterEntry{Entry: &widget.Entry{}, w: 730, readOnly: true}
+	results.ExtendBaseWidget(results)
+	results.MultiLine = true
+	results.Wrapping = fyne.TextWrapWord
+	results.textStyle = fyne.TextStyle{Monospace: true}
+	gui.logs.results = results
+
+	resultDiv := ui.NewElement(&ui.Style{
+		BgColor:      ui.InputColor,
+		Padding:      ui.FourSpec{10, 10, 10, 10},
+		BorderRadius: 4,
+		BorderWidth:  1,
+		BorderColor:  ui.StringToColor("#444"),
+		Display:      ui.DisplayInline,
+		MinW:         730,
+	},
+		results,
+	)
+
+	gui.logs.view = ui.NewElement(
+		&ui.Style{
+			Padding: ui.FourSpec{20, 0, 0, 0},
+			Align:   ui.AlignCenter,
+			Spacing: 15,
+		},
+		gui.logo,
+		gui.backLink(750),
+		gui.logs.svcLbl,
+		gui.logs.msg,
+		resultDiv,
+	)
+}
+
+// showLogsView shows the log viewer and starts following the service's log.
+// Following stops when another view is shown.
+func (gui *GUI) showLogsView(svc string) {
+	gui.logs.svcLbl.SetText("%s log", svc)
+	gui.logs.msg.SetText("")
+	gui.logs.results.SetText("")
+	gui.setView(gui.logs.view)
+	ctx, cancel := context.WithCancel(gui.ctx)
+	gui.logs.cancel = cancel
+
+	go func() {
+		var lines []string
+		err := eco.FollowServiceLogs(ctx, svc, logViewLines, func(newLines []string) {
+			lines = append(lines, newLines...)
+			if len(lines) > logViewLines {
+				lines = lines[len(lines)-logViewLines:]
+			}
+			gui.logs.results.SetText(strings.Join(lines, "\n"))
+			gui.logs.view.Refresh()
+			canvas.Refresh(gui.logs.view)
+		})
+		if err != nil && ctx.Err() == nil {
+			gui.logs.msg.SetText("Error reading %s log: %v", svc, err)
+			gui.logs.view.Refresh()
+		}
+	}()
+}
+
+// refreshStorage fetches the disk usage report and rebuilds the storage
+// panel.
+func (gui *GUI) refreshStorage() {
+	div := gui.settings.storage
+	for div.RemoveChildByIndex(0) {
+	}
+
+	report, err := eco.Storage(gui.ctx)
+	if err != nil {
+		gui.settings.storageMsg.SetText("Error getting storage report: %v", err)
+		return
+	}
+
+	usageRow := func(name string, bytes int64) *ui.Element {
+		return ui.NewElement(&ui.Style{
+			Ori:   ui.OrientationHorizontal,
+			Justi: ui.JustifyBetween,
+		},
+			ui.NewEcoLabel(name, &ui.TextStyle{FontSize: 14}),
+			ui.NewEcoLabel(formatBytes(bytes), &ui.TextStyle{FontSize: 14, Bold: true}),
+		)
+	}
+
+	div.InsertChild(ui.NewEcoLabel(fmt.Sprintf("Versions (keeping %d)", report.Retention), &ui.TextStyle{FontSize: 15, Bold: true}), -1)
+	for _, u := range report.Versions {
+		name := u.Name
+		switch {
+		case u.Active:
+			name += " (active)"
+		case u.Protected:
+			name += " (rollback)"
+		}
+		div.InsertChild(usageRow(name, u.Bytes), -1)
+	}
+	div.InsertChild(ui.NewEcoLabel("Data", &ui.TextStyle{FontSize: 15, Bold: true}), -1)
+	for _, u := range report.Components {
+		div.InsertChild(usageRow(u.Name, u.Bytes), -1)
+	}
+	div.InsertChild(usageRow("Total", report.Total), -1)
+	gui.settings.view.Refresh()
+	canvas.Refresh(gui.settings.view)
+}
+
+// refreshVersions fetches the Eco state, and rebuilds the version panel with a
+// button to switch to each of the other known-good versions.
+func (gui *GUI) refreshVersions() {
+	div := gui.settings.versions
+	for div.RemoveChildByIndex(0) {
+	}
+
+	state, err := eco.State(gui.ctx)
+	if err != nil {
+		gui.settings.versionMsg.SetText("Error getting Eco state: %v", err)
+		return
+	}
+	gui.storeEcoState(&state.Eco)
+	gui.settings.version.SetText(state.Eco.Version)
+
+	for _, v := range state.Eco.GoodVersions {
+		if v == state.Eco.Version {
+			continue
+		}
+		v := v
+		div.InsertChild(newEcoBttn(nil, "Use "+v, func(*fyne.PointEvent) {
+			gui.settings.versionMsg.SetText("Switching to %s", v)
+			go func() {
+				if err := eco.SetVersion(gui.ctx, v); err != nil {
+					gui.settings.versionMsg.SetText("Error switching to %s: %v", v, err)
+				} else {
+					gui.settings.versionMsg.SetText("Now running %s", v)
+				}
+				gui.refreshVersions()
+			}()
+		}), -1)
+	}
+	gui.settings.view.Refresh()
+	canvas.Refresh(gui.settings.view)
+}
+
+// upgrade upgrades Eco to the newest release on the release channel, and
+// reports the progress in the version panel.
+func (gui *GUI) upgrade() {
+	ch, err := eco.Upgrade(gui.ctx)
+	if err != nil {
+		gui.settings.versionMsg.SetText("Error upgrading Eco: %v", err)
+		return
+	}
+	for {
+		select {
+		case u := <-ch:
+			if u.Err != "" {
+				gui.settings.versionMsg.SetText(u.Err)
+				gui.refreshVersions()
+				return
+			}
+			gui.settings.versionMsg.SetText("%s (%.0f%%)", u.Status, u.Progress*100)
+			if u.Progress > 0.9999 {
+				gui.settings.versionMsg.SetText(u.Status)
+				gui.refreshVersions()
+				gui.refreshStorage()
+				return
+			}
+			gui.settings.view.Refresh()
+			canvas.Refresh(gui.settings.view)
+		case <-gui.ctx.Done():
+			return
+		}
+	}
+}
+
+// formatBytes formats the byte count with a binary unit prefix.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// formatDuration formats a duration for a time remaining, to the minute, e.g.
+// "2h 10m".
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	days, hours, mins := d/(24*time.Hour), d%(24*time.Hour)/time.Hour, d%time.Hour/time.Minute
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, mins)
+	case mins > 0:
+		return fmt.Sprintf("%dm", mins)
+	}
+	return "less than a minute"
+}
+
+func (gui *GUI) ecoState() *eco.EcoState {
+	gui.stateMtx.RLock()
+	defer gui.stateMtx.RUnlock()
+	return gui.ecoStatus
+}
+
 func (gui *GUI) storeEcoState(newState *eco.EcoState) (oldState *eco.EcoState) {
 	gui.stateMtx.Lock()
 	defer gui.stateMtx.Unlock()
@@ -891,16 +1864,28 @@ func (gui *GUI) updateSyncLabel(lbl *ui.EcoLabel, u *eco.Progress) {
 		lbl.SetText("sync error: %s", u.Err)
 		return
 	}
-	lbl.SetText("%s (%.0f%%)", u.Status, u.Progress*100)
+	if u.ETA > 0 {
+		lbl.SetText("%s (%.0f%%), about %s remaining", u.Status, u.Progress*100, formatDuration(u.ETA))
+	} else {
+		lbl.SetText("%s (%.0f%%)", u.Status, u.Progress*100)
+	}
 	lbl.Refresh()
 	gui.home.box.Refresh()
 	canvas.Refresh(lbl)
 }
 
-// initEco should be run in a goroutine.
-func (gui *GUI) initEco(syncMode eco.SyncMode) {
+// initEco should be run in a goroutine. remote is only used for
+// SyncModeRemote.
+func (gui *GUI) initEco(syncMode eco.SyncMode, remote *eco.RemoteDCRD) {
 	pw := gui.intro.pw.Text
-	ch, err := eco.Init(gui.ctx, pw, syncMode)
+	seed := gui.intro.seed.Text
+	var ch <-chan *eco.Progress
+	var err error
+	if syncMode == eco.SyncModeRemote {
+		ch, err = eco.InitRemote(gui.ctx, pw, gui.intro.network, remote, seed)
+	} else {
+		ch, err = eco.Init(gui.ctx, pw, syncMode, gui.intro.network, seed)
+	}
 	if err != nil {
 		gui.download.msg.SetText("Error initalizing Eco: %v", err)
 		return
@@ -922,7 +1907,10 @@ func (gui *GUI) initEco(syncMode eco.SyncMode) {
 			gui.download.box.Refresh()
 			canvas.Refresh(gui.download.box)
 			if u.Progress > 0.9999 {
-				if syncMode == eco.SyncModeFull {
+				if !gui.intro.network.HasDecrediton() {
+					gui.decrediton.launcher.Hide()
+				}
+				if syncMode == eco.SyncModeFull || syncMode == eco.SyncModeRemote {
 					gui.dex.spinnerBox.Show()
 					gui.dex.spinner.Show()
 					gui.dex.launcher.Show()
@@ -30,7 +30,7 @@ func main() {
 
 	var ctx, cancel = context.WithCancel(context.Background())
 	defer cancel()
-	killChan := make(chan os.Signal)
+	killChan := make(chan os.Signal, 1)
 	signal.Notify(killChan, os.Interrupt)
 	go func() {
 		<-killChan
@@ -13,7 +13,7 @@ import (
 	"fyne.io/fyne"
 	"fyne.io/fyne/app"
 	"fyne.io/fyne/canvas"
-	"github.com/buck54321/eco"
+	"github.com/buck54321/eco/extract"
 	"github.com/buck54321/eco/ui"
 )
 
@@ -52,7 +52,7 @@ func main() {
 
 	go func() {
 		time.Sleep(time.Second)
-		_, err := eco.ExtractTarGz("unpacked", bytes.NewReader(archive))
+		_, err := extract.TarGz(bytes.NewReader(archive), "unpacked", nil)
 		if err != nil {
 			setProgress("error unpacking: %v", err)
 			return
@@ -0,0 +1,454 @@
+package eco
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxDownloadAttempts is the number of times fetchAsset will attempt a
+	// download before giving up.
+	maxDownloadAttempts   = 6
+	maxDownloadRetryDelay = time.Second * 30
+	// downloadReportInterval limits how often byte progress is reported.
+	downloadReportInterval = time.Millisecond * 250
+)
+
+// downloadRetryDelay is the delay before the first retry. The delay is
+// doubled for each subsequent retry, up to maxDownloadRetryDelay.
+var downloadRetryDelay = time.Second
+
+// partialDownloadDir holds incomplete downloads between attempts, and between
+// runs.
+func partialDownloadDir() string {
+	return filepath.Join(AppDir, "downloads", "partial")
+}
+
+// partialDownloadPath is where the download from url is kept until it's
+// complete. The path includes a hash of the url, so a same-named asset from
+// another release never resumes from this download.
+func partialDownloadPath(url, name string) string {
+	h := sha256.Sum256([]byte(url))
+	return filepath.Join(partialDownloadDir(), fmt.Sprintf("%s-%x.partial", name, h[:8]))
+}
+
+// fetchAsset downloads the file at url to dir/name. The download is written to
+// the partialDownloadDir first, and is resumed with an HTTP Range request if
+// the connection is lost or a previous download was interrupted. size is the
+// expected size of the file, and is used for progress reporting if the server
+// doesn't tell us. size can be zero if unknown. prog can be nil.
+func fetchAsset(ctx context.Context, dir string, url, name string, size int64, prog *progressReporter) (string, error) {
+	err := os.MkdirAll(partialDownloadDir(), 0700)
+	if err != nil {
+		return "", fmt.Errorf("Error creating partial download directory: %w", err)
+	}
+	partialPath := partialDownloadPath(url, name)
+	// Without a size, a partial download left by an earlier run can't be
+	// checked, so start over.
+	if size <= 0 {
+		if err := os.Remove(partialPath); err != nil && !os.IsNotExist(err) {
+			return "", fmt.Errorf("Error removing partial download: %w", err)
+		}
+	}
+
+	delay := downloadRetryDelay
+	for attempt := 1; ; attempt++ {
+		retry, err := resumeDownload(ctx, partialPath, url, name, size, prog)
+		if err == nil {
+			break
+		}
+		if !retry || attempt >= maxDownloadAttempts || ctx.Err() != nil {
+			return "", err
+		}
+		log.Warnf("Download of %s failed (attempt %d of %d). Retrying in %s: %v", name, attempt, maxDownloadAttempts, delay, err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+		if delay *= 2; delay > maxDownloadRetryDelay {
+			delay = maxDownloadRetryDelay
+		}
+	}
+
+	tgt := filepath.Join(dir, name)
+	err = moveFile(partialPath, tgt)
+	if err != nil {
+		return "", fmt.Errorf("Error moving completed download: %w", err)
+	}
+	return tgt, nil
+}
+
+// resumeDownload makes a single attempt to complete the download into the file
+// at partialPath, picking up wherever the last attempt left off. If an error is
+// returned, retry indicates whether another attempt might succeed.
+func resumeDownload(ctx context.Context, partialPath, url, name string, size int64, prog *progressReporter) (retry bool, err error) {
+	f, err := os.OpenFile(partialPath, os.O_WRONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return false, fmt.Errorf("Error opening partial download: %w", err)
+	}
+	defer f.Close()
+
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return false, fmt.Errorf("Error reading partial download: %w", err)
+	}
+
+	restart := func() error {
+		offset = 0
+		if err := f.Truncate(0); err != nil {
+			return fmt.Errorf("Error truncating partial download: %w", err)
+		}
+		_, err := f.Seek(0, io.SeekStart)
+		return err
+	}
+
+	if size > 0 {
+		if offset == size {
+			log.Infof("Using previously downloaded %s", name)
+			return false, nil
+		}
+		if offset > size {
+			if err := restart(); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	// From github API docs...
+	// > To download the asset's binary content, set the "Accept" header of the
+	//   request to "application/octet-stream"
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return false, fmt.Errorf("Error preparing request: %w", err)
+	}
+	req.Header.Set("Accept", "application/octet-stream")
+	if offset > 0 {
+		log.Infof("Resuming download of %q at byte %d", url, offset)
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	} else {
+		log.Infof("Fetching %q to %q", url, partialPath)
+	}
+
+	resp, err := webClient().Do(req)
+	if err != nil {
+		return true, fmt.Errorf("Request error for %q %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	total := size
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		start, rangeTotal, err := parseContentRange(resp.Header.Get("Content-Range"))
+		if err != nil || start != offset {
+			// Not the range we asked for. Start over.
+			if err := restart(); err != nil {
+				return false, err
+			}
+			return true, fmt.Errorf("Unexpected Content-Range %q for %q", resp.Header.Get("Content-Range"), url)
+		}
+		if rangeTotal > 0 {
+			total = rangeTotal
+		}
+	case http.StatusOK:
+		// The server is sending the whole file.
+		if offset > 0 {
+			if err := restart(); err != nil {
+				return false, err
+			}
+		}
+		if resp.ContentLength > 0 {
+			total = resp.ContentLength
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		// We have more than the server does. Start over.
+		if err := restart(); err != nil {
+			return false, err
+		}
+		return true, fmt.Errorf("Range not satisfiable for %q", url)
+	default:
+		// Server errors and rate limiting might be temporary. Anything else
+		// won't change by trying again.
+		retry = resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return retry, fmt.Errorf("Request error for %q: %s", url, resp.Status)
+	}
+
+	w := &progressWriter{
+		w:       f,
+		name:    name,
+		written: offset,
+		total:   total,
+		prog:    prog,
+	}
+	w.reportProgress()
+	_, err = io.Copy(w, resp.Body)
+	if err != nil {
+		return true, fmt.Errorf("Error saving %s to file: %w", name, err)
+	}
+	if total > 0 && w.written != total {
+		return true, fmt.Errorf("Download of %s ended early. Expected %d bytes, got %d", name, total, w.written)
+	}
+	w.reportProgress()
+	return false, nil
+}
+
+// parseContentRange parses a Content-Range header of the form
+// "bytes start-end/total". total is -1 if the server specified "*".
+func parseContentRange(s string) (start, total int64, err error) {
+	errBadRange := errors.New("bad Content-Range")
+	if !strings.HasPrefix(s, "bytes ") {
+		return 0, 0, errBadRange
+	}
+	parts := strings.Split(strings.TrimPrefix(s, "bytes "), "/")
+	if len(parts) != 2 {
+		return 0, 0, errBadRange
+	}
+	rangeParts := strings.Split(parts[0], "-")
+	if len(rangeParts) != 2 {
+		return 0, 0, errBadRange
+	}
+	start, err = strconv.ParseInt(rangeParts[0], 10, 64)
+	if err != nil {
+		return 0, 0, errBadRange
+	}
+	if parts[1] == "*" {
+		return start, -1, nil
+	}
+	total, err = strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, 0, errBadRange
+	}
+	return start, total, nil
+}
+
+// progressWriter is an io.Writer that reports download progress as bytes are
+// written.
+type progressWriter struct {
+	w              io.Writer
+	name           string
+	written, total int64
+	prog           *progressReporter
+	lastReport     time.Time
+}
+
+func (w *progressWriter) Write(b []byte) (int, error) {
+	n, err := w.w.Write(b)
+	w.written += int64(n)
+	if time.Since(w.lastReport) >= downloadReportInterval {
+		w.reportProgress()
+	}
+	return n, err
+}
+
+func (w *progressWriter) reportProgress() {
+	if w.prog == nil {
+		return
+	}
+	w.lastReport = time.Now()
+	if w.total <= 0 {
+		w.prog.report(0, "Downloading %s (%s)", w.name, formatBytes(w.written))
+		return
+	}
+	w.prog.report(float32(w.written)/float32(w.total), "Downloading %s (%s of %s)", w.name, formatBytes(w.written), formatBytes(w.total))
+}
+
+// formatBytes formats the byte count with a binary unit prefix.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// moveFile moves the file, copying it if it can't be renamed, e.g. because
+// the destination is on a different device.
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	if err := copyFile(src, dst); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+// copyFile copies the file at src to dst, replacing any file at dst.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// maxConcurrentDownloads limits the number of archives fetched at once.
+const maxConcurrentDownloads = 3
+
+// fetchArchives fetches and verifies the archives concurrently, using at most
+// maxConcurrentDownloads workers, and sets each asset's archive path. The
+// progress of the individual downloads is merged into a single stream,
+// weighted by the archive sizes.
+func fetchArchives(ctx context.Context, src ReleaseSource, cache *downloadCache, tmpDir string, archives []*releaseAsset, hashes map[string][]byte, prog *progressReporter) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	agg := newProgressAggregator(prog)
+	jobs := make(chan int, len(archives))
+	reporters := make([]*progressReporter, len(archives))
+	for i, asset := range archives {
+		reporters[i] = agg.reporter(asset.Name, int64(asset.Size))
+		jobs <- i
+	}
+	close(jobs)
+
+	errs := make([]error, len(archives))
+	var wg sync.WaitGroup
+	workers := maxConcurrentDownloads
+	if len(archives) < workers {
+		workers = len(archives)
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				asset := archives[i]
+				if ctx.Err() != nil {
+					errs[i] = ctx.Err()
+					continue
+				}
+				checkHash, found := hashes[asset.Name]
+				if !found {
+					errs[i] = fmt.Errorf("No hash in manifest for %s", asset.Name)
+					cancel()
+					continue
+				}
+				assetProg := reporters[i]
+				asset.archive, errs[i] = cache.fetch(tmpDir, asset.Name, checkHash, func() (string, error) {
+					return src.Fetch(ctx, asset.githubAsset, tmpDir, assetProg)
+				}, assetProg)
+				if errs[i] != nil {
+					// No reason to keep downloading the others.
+					cancel()
+					continue
+				}
+				assetProg.report(1, "Fetched %s", asset.Name)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Report the root cause, not the cancellations it caused.
+	for i, err := range errs {
+		if err != nil && !errors.Is(err, context.Canceled) {
+			return fmt.Errorf("Error fetching %q: %w", archives[i].Name, err)
+		}
+	}
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("Error fetching %q: %w", archives[i].Name, err)
+		}
+	}
+	return nil
+}
+
+// progressAggregator merges the progress of concurrent tasks into a single
+// progressReporter. Each task's progress is weighted by its size.
+type progressAggregator struct {
+	mtx      sync.Mutex
+	prog     *progressReporter
+	names    []string
+	weights  []int64
+	progress []float32
+}
+
+func newProgressAggregator(prog *progressReporter) *progressAggregator {
+	return &progressAggregator{prog: prog}
+}
+
+// reporter creates a progressReporter for a task. size is the weight of the
+// task, and can be zero if unknown, in which case it is weighted the same as
+// the average known task.
+func (a *progressAggregator) reporter(name string, size int64) *progressReporter {
+	a.mtx.Lock()
+	idx := len(a.names)
+	a.names = append(a.names, name)
+	a.weights = append(a.weights, size)
+	a.progress = append(a.progress, 0)
+	a.mtx.Unlock()
+	return &progressReporter{
+		report: func(p float32, s string, args ...interface{}) {
+			a.update(idx, p)
+		},
+		fail: a.prog.fail,
+	}
+}
+
+func (a *progressAggregator) update(idx int, p float32) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	// Tasks may report a lower progress for a later step, e.g. validation
+	// after a download, but the overall progress shouldn't go backwards.
+	if p > a.progress[idx] {
+		a.progress[idx] = p
+	}
+
+	var known, nKnown int64
+	for _, w := range a.weights {
+		if w > 0 {
+			known += w
+			nKnown++
+		}
+	}
+	avg := int64(1)
+	if nKnown > 0 {
+		avg = known / nKnown
+	}
+
+	var total, done float64
+	var active []string
+	for i, w := range a.weights {
+		if w <= 0 {
+			w = avg
+		}
+		total += float64(w)
+		done += float64(w) * float64(a.progress[i])
+		if a.progress[i] > 0 && a.progress[i] < 1 {
+			active = append(active, a.names[i])
+		}
+	}
+	var overall float32
+	if total > 0 {
+		overall = float32(done / total)
+	}
+	if len(active) == 0 {
+		a.prog.report(overall, "Downloading %d files", len(a.names))
+		return
+	}
+	a.prog.report(overall, "Downloading %s", strings.Join(active, ", "))
+}
@@ -0,0 +1,268 @@
+package eco
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// tempDownloadDirs points the AppDir, and so the download directories, at a
+// temporary directory, and shortens the retry delay. Call the returned
+// function to undo.
+func tempDownloadDirs(tmpDir string) func() {
+	appDir, delay := AppDir, downloadRetryDelay
+	AppDir = tmpDir
+	downloadRetryDelay = time.Millisecond
+	return func() {
+		AppDir, downloadRetryDelay = appDir, delay
+	}
+}
+
+func TestFetchAssetResume(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(tmpDir)
+	defer tempDownloadDirs(tmpDir)()
+
+	content := make([]byte, 1<<16)
+	rand.Read(content)
+
+	var mtx sync.Mutex
+	var reqs int
+	var ranges []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		reqs++
+		n := reqs
+		ranges = append(ranges, r.Header.Get("Range"))
+		mtx.Unlock()
+		switch n {
+		case 1:
+			// Drop the connection halfway through.
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.Write(content[:len(content)/2])
+			panic(http.ErrAbortHandler)
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			http.ServeContent(w, r, "asset", time.Time{}, bytes.NewReader(content))
+		}
+	}))
+	defer ts.Close()
+
+	var lastP float32
+	prog := &progressReporter{
+		report: func(p float32, s string, a ...interface{}) {
+			if p < lastP {
+				t.Errorf("Progress went backwards from %f to %f", lastP, p)
+			}
+			lastP = p
+		},
+		fail: func(s string, err error) {},
+	}
+
+	outDir := filepath.Join(tmpDir, "out")
+	os.Mkdir(outDir, 0755)
+	path, err := fetchAsset(context.Background(), outDir, ts.URL, "asset.tar.gz", int64(len(content)), prog)
+	if err != nil {
+		t.Fatalf("fetchAsset error: %v", err)
+	}
+	b, _ := ioutil.ReadFile(path)
+	if !bytes.Equal(b, content) {
+		t.Fatalf("Wrong file contents")
+	}
+	if reqs != 3 {
+		t.Fatalf("Expected 3 requests, saw %d", reqs)
+	}
+	if ranges[0] != "" {
+		t.Fatalf("First request had a Range header %q", ranges[0])
+	}
+	expRange := "bytes=" + strconv.Itoa(len(content)/2) + "-"
+	if ranges[2] != expRange {
+		t.Fatalf("Wrong Range header. Expected %q, got %q", expRange, ranges[2])
+	}
+	if lastP != 1 {
+		t.Fatalf("Final progress not reported. Last progress %f", lastP)
+	}
+	if fileExists(partialDownloadPath(ts.URL, "asset.tar.gz")) {
+		t.Fatalf("Partial download not removed")
+	}
+
+	// Client errors aren't retried.
+	reqs = 0
+	ts404 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqs++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts404.Close()
+	_, err = fetchAsset(context.Background(), outDir, ts404.URL, "missing", 0, nil)
+	if err == nil {
+		t.Fatalf("No error for missing asset")
+	}
+	if reqs != 1 {
+		t.Fatalf("Client error retried. %d requests", reqs)
+	}
+
+	// Same-named assets at different URLs don't share a partial download.
+	if partialDownloadPath(ts.URL+"/v1", "asset.tar.gz") == partialDownloadPath(ts.URL+"/v2", "asset.tar.gz") {
+		t.Fatalf("Partial download path doesn't depend on the URL")
+	}
+
+	// A partial download isn't resumed if the size is unknown.
+	partialPath := partialDownloadPath(ts.URL, "asset.tar.gz")
+	if err := ioutil.WriteFile(partialPath, bytes.Repeat([]byte{0xff}, len(content)/2), 0600); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	ranges = nil
+	path, err = fetchAsset(context.Background(), outDir, ts.URL, "asset.tar.gz", 0, nil)
+	if err != nil {
+		t.Fatalf("fetchAsset error: %v", err)
+	}
+	b, _ = ioutil.ReadFile(path)
+	if !bytes.Equal(b, content) {
+		t.Fatalf("Wrong file contents after a stale partial download")
+	}
+	for _, r := range ranges {
+		if r != "" {
+			t.Fatalf("Stale partial download resumed with Range header %q", r)
+		}
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		s            string
+		start, total int64
+		wantErr      bool
+	}{
+		{s: "bytes 100-199/200", start: 100, total: 200},
+		{s: "bytes 0-99/*", start: 0, total: -1},
+		{s: "bytes */200", wantErr: true},
+		{s: "items 0-99/200", wantErr: true},
+		{s: "bytes 0-99", wantErr: true},
+	}
+	for _, tt := range tests {
+		start, total, err := parseContentRange(tt.s)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%q: wanted error = %t, got %v", tt.s, tt.wantErr, err)
+		}
+		if tt.wantErr {
+			continue
+		}
+		if start != tt.start || total != tt.total {
+			t.Fatalf("%q: wanted %d/%d, got %d/%d", tt.s, tt.start, tt.total, start, total)
+		}
+	}
+}
+
+func TestFetchArchives(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(tmpDir)
+	defer tempDownloadDirs(tmpDir)()
+
+	contents := map[string][]byte{
+		"decred.tar.gz":     bytes.Repeat([]byte{1}, 3000),
+		"decrediton.tar.gz": bytes.Repeat([]byte{2}, 2000),
+		"dexc.tar.gz":       bytes.Repeat([]byte{3}, 1000),
+	}
+
+	var mtx sync.Mutex
+	var active, maxActive int
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, found := contents[filepath.Base(r.URL.Path)]
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		mtx.Lock()
+		active++
+		if active > maxActive {
+			maxActive = active
+		}
+		if active == len(contents) {
+			close(release)
+		}
+		mtx.Unlock()
+		// Hold all requests until they're all in flight.
+		select {
+		case <-release:
+		case <-time.After(time.Second * 5):
+		}
+		w.Write(b)
+		mtx.Lock()
+		active--
+		mtx.Unlock()
+	}))
+	defer ts.Close()
+
+	hashes := make(map[string][]byte)
+	var archives []*releaseAsset
+	for name, b := range contents {
+		h := sha256.Sum256(b)
+		hashes[name] = h[:]
+		archives = append(archives, &releaseAsset{githubAsset: &githubAsset{
+			Name: name,
+			URL:  ts.URL + "/" + name,
+			Size: uint32(len(b)),
+		}})
+	}
+
+	var progMtx sync.Mutex
+	var lastP float32
+	prog := &progressReporter{
+		report: func(p float32, s string, a ...interface{}) {
+			progMtx.Lock()
+			defer progMtx.Unlock()
+			if p < lastP {
+				t.Errorf("Progress went backwards from %f to %f", lastP, p)
+			}
+			lastP = p
+		},
+		fail: func(string, error) {},
+	}
+
+	cache := newDownloadCache(filepath.Join(tmpDir, "cache"), 0)
+	src := newGitHubSource(ts.URL + "/releases")
+	outDir := filepath.Join(tmpDir, "out")
+	os.Mkdir(outDir, 0755)
+	err := fetchArchives(context.Background(), src, cache, outDir, archives, hashes, prog)
+	if err != nil {
+		t.Fatalf("fetchArchives error: %v", err)
+	}
+	if maxActive != len(contents) {
+		t.Fatalf("Expected %d concurrent downloads, saw %d", len(contents), maxActive)
+	}
+	if lastP != 1 {
+		t.Fatalf("Final progress %f", lastP)
+	}
+	for _, asset := range archives {
+		if err := checkFileHash(asset.archive, hashes[asset.Name]); err != nil {
+			t.Fatalf("Bad archive for %s: %v", asset.Name, err)
+		}
+	}
+
+	// A bad hash fails the whole batch.
+	hashes["dexc.tar.gz"] = make([]byte, sha256.Size)
+	cache.clear()
+	lastP = 0
+	err = fetchArchives(context.Background(), src, cache, outDir, archives, hashes, prog)
+	if err == nil {
+		t.Fatalf("No error for bad hash")
+	}
+	// The bad hash is reported, not the cancellations of the other
+	// downloads.
+	if !strings.Contains(err.Error(), "dexc.tar.gz") {
+		t.Fatalf("Wrong root cause reported: %v", err)
+	}
+}
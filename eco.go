@@ -8,6 +8,7 @@ import (
 	"encoding/csv"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -30,7 +31,6 @@ import (
 	"github.com/buck54321/eco/db"
 	"github.com/buck54321/eco/encode"
 	"github.com/buck54321/eco/encrypt"
-	"github.com/decred/dcrd/chaincfg/v3"
 	chainjson "github.com/decred/dcrd/rpc/jsonrpc/types/v2"
 	"github.com/decred/dcrd/rpcclient/v6"
 	"golang.org/x/net/publicsuffix"
@@ -38,24 +38,34 @@ import (
 
 const (
 	UnixSocketFilename = "decred.sock"
-	TCPSocketHost      = ":45219"
+	TCPSocketHost      = "127.0.0.1:45219"
 	ListenerFilename   = "addr.txt"
 	dbFilename         = "eco.db"
 
+	// maxGoodVersions is the number of known-good versions kept in
+	// EcoState.GoodVersions.
+	maxGoodVersions = 3
+	// upgradeHealthWindow is how long the services from a new version have
+	// to start answering RPC requests before Eco rolls back.
+	upgradeHealthWindow = time.Minute * 5
+	// maxUpgradeExits is the number of times dcrd or dcrwallet can exit
+	// during the upgradeHealthWindow before Eco rolls back.
+	maxUpgradeExits = 3
+
 	crypterKey    = "crypter"
 	walletSeedKey = "walletSeed"
-	extraInputKey = "extraInput"
-	dexInputKey   = "dexInput"
 	ecoStateKey   = "ecoState"
 )
 
 var (
 	KeyPath  = filepath.Join(AppDir, "decred-eco.key")
 	CertPath = filepath.Join(AppDir, "decred-eco.cert")
+	// TokenPath is the IPC client token. Eco creates the owner's token, with
+	// every scope, on first start. A client added with AddClient points
+	// TokenPath at its own token.
+	TokenPath = filepath.Join(AppDir, "decred-eco.token")
 
-	dcrdRunning, dcrdSyncedOnce, dcrWalletRunning,
-	decreditonRunning, dcrwalletRunningOnce, dexRunning,
-	dexWindowOpen uint32
+	dexWindowOpen, upgrading uint32
 
 	osUser, _ = user.Current()
 )
@@ -66,12 +76,12 @@ type NetAddr struct {
 }
 
 type Eco struct {
-	db             *db.DB
-	innerCtx       context.Context
-	outerCtx       context.Context
-	dcrdSynced     chan struct{}
-	dcrwalletReady chan struct{}
+	db       *db.DB
+	innerCtx context.Context
+	outerCtx context.Context
 
+	// syncMtx guards the feed, and the state.Services. A ServiceStatus in
+	// the Services is replaced, never modified.
 	syncMtx   sync.Mutex
 	syncCache map[string]*FeedMessage
 	syncChans map[chan *FeedMessage]struct{}
@@ -81,6 +91,20 @@ type Eco struct {
 	versionDir string
 	dcrd       *DCRD
 	dcrwallet  *DCRWallet
+
+	// session holds the password for pending jobs.
+	session session
+
+	// clientsMtx guards the IPC clients in the database.
+	clientsMtx sync.Mutex
+
+	// seedQuiz are the indices of the seed words the user must enter
+	// before the seed is deleted.
+	quizMtx  sync.Mutex
+	seedQuiz []int
+
+	// sup runs dcrd, dcrwallet, dexc, and Decrediton.
+	sup *Supervisor
 }
 
 func Run(outerCtx context.Context) {
@@ -137,11 +161,20 @@ func Run(outerCtx context.Context) {
 		if !loadService(dcrwallet, &dcrWalletState) {
 			return
 		}
+		if migratePWCache(dbb, state) {
+			if err := dbb.EncodeStore(ecoStateKey, state); err != nil {
+				log.Errorf("Error saving pending jobs: %v", err)
+			}
+		}
 	}
 
 	// Populate the WalletExists field so the GUI knows whether to prompt for
 	// a password.
-	state.WalletExists = walletFileExists()
+	state.WalletExists = walletFileExists(state.Network)
+
+	if err := setHTTPProxy(state.Proxy); err != nil {
+		log.Errorf("Error configuring proxy: %v", err)
+	}
 
 	// We need an inner Context that is delayed on cancellation to allow clean
 	// shutdown of e.g. dcrd
@@ -151,8 +184,7 @@ func Run(outerCtx context.Context) {
 	services := make(map[string]*ServiceStatus, 2)
 	services[decrediton] = &ServiceStatus{Service: decrediton}
 
-	dexNeedsInit, _ := dbb.FetchDecode(dexInputKey, new(pwCache))
-	if !dexNeedsInit {
+	if state.Pending&PendingDEXInit == 0 {
 		services[dexc] = &ServiceStatus{Service: dexc}
 	}
 
@@ -164,31 +196,25 @@ func Run(outerCtx context.Context) {
 			Eco:      *state,
 			Services: map[string]*ServiceStatus{},
 		},
-		versionDir:     filepath.Join(EcoDir, state.Version),
-		dcrd:           &DCRD{DCRDState: *dcrdState},
-		dcrwallet:      &DCRWallet{DCRWalletState: *dcrWalletState},
-		syncChans:      make(map[chan *FeedMessage]struct{}),
-		dcrdSynced:     make(chan struct{}),
-		dcrwalletReady: make(chan struct{}),
-		syncCache:      make(map[string]*FeedMessage),
+		versionDir: filepath.Join(EcoDir, state.Version),
+		dcrd:       &DCRD{DCRDState: *dcrdState},
+		dcrwallet:  &DCRWallet{DCRWalletState: *dcrWalletState},
+		syncChans:  make(map[chan *FeedMessage]struct{}),
+		syncCache:  make(map[string]*FeedMessage),
 	}
+	eco.sup = newSupervisor(innerCtx, eco.sendServiceStatus)
 
 	go func() {
 		<-outerCtx.Done()
 		time.AfterFunc(time.Second*30, func() { cancel() })
-		err := eco.stopDCRD()
-		if err != nil {
-			log.Errorf("Error closing dcrd: %v", err)
-		}
-		err = eco.stopDCRWallet()
-		if err != nil {
-			log.Errorf("Error closing dcrwallet: %v", err)
-		}
+		eco.stopServices()
 		cancel()
 	}()
 
 	if state.SyncMode != SyncModeUninitialized {
-		eco.state.Services[decrediton] = &ServiceStatus{Service: decrediton}
+		if state.Network.HasDecrediton() {
+			eco.sendServiceStatus(&ServiceStatus{Service: decrediton})
+		}
 		eco.start()
 	}
 
@@ -214,62 +240,86 @@ func Run(outerCtx context.Context) {
 }
 
 func (eco *Eco) start() {
-	if eco.state.Eco.SyncMode == SyncModeFull {
+	if err := eco.allocatePorts(); err != nil {
+		log.Errorf("Port allocation error: %v", err)
+	}
+	if mode := eco.syncMode(); mode == SyncModeFull || mode == SyncModeRemote {
 		err := eco.runDCRD()
 		if err != nil {
 			log.Errorf("dcrd startup error: %w", err)
 		}
 	}
 	err := eco.runDCRWallet()
-	if err != nil {
+	if errors.Is(err, errLocked) {
+		log.Infof("dcrwallet will start when Eco is unlocked")
+	} else if err != nil {
 		log.Errorf("dcrwallet startup error: %w", err)
 	}
 
-	// The dexInputKey is only stored until initialized.
-	if dexNeedsInit, _ := eco.db.FetchDecode(dexInputKey, new(pwCache)); dexNeedsInit {
+	if eco.pending()&PendingDEXInit != 0 {
 		err := eco.runDEX()
-		if err != nil {
+		if errors.Is(err, errLocked) {
+			log.Infof("DEX will be initialized when Eco is unlocked")
+		} else if err != nil {
 			log.Errorf("DEX initialization error: %v", err)
 		}
 	}
 }
 
 func (eco *Eco) dcrdClient() (*rpcclient.Client, error) {
-	return eco.newRPCClient(dcrdRPCListen, dcrdCertPath)
+	c := eco.dcrdConn()
+	return newRPCClient(c.host, c.user, c.pass, c.certPath, c.proxy)
 }
 
 func (eco *Eco) dcrWalletClient() (*walletclient.Client, error) {
-	cl, err := eco.newRPCClient(dcrWalletRPCListen, dcrWalletRPCCert)
+	network := eco.network()
+	cl, err := eco.newRPCClient(eco.ports().DCRWalletRPCListen, dcrWalletRPCCert)
 	if err != nil {
 		return nil, err
 	}
-	return walletclient.NewClient(walletclient.RawRequestCaller(cl), chaincfg.MainNetParams()), nil
+	return walletclient.NewClient(walletclient.RawRequestCaller(cl), network.chainParams()), nil
 }
 
 func (eco *Eco) newRPCClient(rpcListen, certPath string) (*rpcclient.Client, error) {
 	eco.stateMtx.RLock()
 	rpcUser, rpcPass := eco.dcrd.RPCUser, eco.dcrd.RPCPass
 	eco.stateMtx.RUnlock()
+	return newRPCClient("localhost"+rpcListen, rpcUser, rpcPass, certPath, nil)
+}
+
+// newRPCClient creates a client for the RPC server at host. The connection
+// goes through the proxy if it's not nil.
+func newRPCClient(host, rpcUser, rpcPass, certPath string, proxy *ProxyConfig) (*rpcclient.Client, error) {
 	certs, err := ioutil.ReadFile(certPath)
 	if err != nil {
 		return nil, fmt.Errorf("TLS certificate read error: %v", err)
 	}
 
 	config := &rpcclient.ConnConfig{
-		Host:         "localhost" + rpcListen,
+		Host:         host,
 		HTTPPostMode: true,
 		User:         rpcUser,
 		Pass:         rpcPass,
 		Certificates: certs,
 	}
+	proxy.applyRPC(config)
 
 	return rpcclient.New(config, nil)
 }
 
+// dcrdState is the dcrd state for clients. The RPC passwords are removed, since
+// dcrwallet's RPC server uses the same credentials, and a read-only client
+// could use them to spend from the wallet.
 func (eco *Eco) dcrdState() (cfg *DCRDState) {
 	eco.stateMtx.RLock()
 	defer eco.stateMtx.RUnlock()
 	sCopy := eco.dcrd.DCRDState
+	sCopy.RPCPass = ""
+	if sCopy.Remote != nil {
+		r := *sCopy.Remote
+		r.RPCPass = ""
+		sCopy.Remote = &r
+	}
 	return &sCopy
 }
 
@@ -277,6 +327,14 @@ func (eco *Eco) metaState() (cfg *MetaState) {
 	eco.stateMtx.RLock()
 	defer eco.stateMtx.RUnlock()
 	sCopy := eco.state
+	sCopy.Eco.Proxy = sCopy.Eco.Proxy.redacted()
+	eco.syncMtx.Lock()
+	sCopy.Services = make(map[string]*ServiceStatus, len(eco.state.Services))
+	for svc, st := range eco.state.Services {
+		sCopy.Services[svc] = st
+	}
+	eco.syncMtx.Unlock()
+	sCopy.Locked = sCopy.Eco.Pending != 0 && !eco.session.unlocked()
 	return &sCopy
 }
 
@@ -306,14 +364,30 @@ func (eco *Eco) returnSyncChan(ch chan *FeedMessage) {
 func (eco *Eco) sendSyncUpdate(pu *Progress) {
 	eco.syncMtx.Lock()
 	defer eco.syncMtx.Unlock()
-	st := eco.state.Services[pu.Service]
-	if st != nil {
-		st.Sync = pu
+	if st := eco.state.Services[pu.Service]; st != nil {
+		stCopy := *st
+		stCopy.Sync = pu
+		eco.state.Services[pu.Service] = &stCopy
 	}
 	eco.sendFeedMessage(syncKey(pu.Service), MsgTypeSyncStatusUpdate, pu)
 }
 
+func (eco *Eco) sendNotification(subject, details string) {
+	eco.syncMtx.Lock()
+	defer eco.syncMtx.Unlock()
+	eco.sendFeedMessage("", MsgTypeNotification, &Notification{
+		Subject: subject,
+		Details: details,
+	})
+}
+
 func (eco *Eco) sendServiceStatus(su *ServiceStatus) {
+	eco.syncMtx.Lock()
+	defer eco.syncMtx.Unlock()
+	// Keep the last sync update through status changes.
+	if old := eco.state.Services[su.Service]; old != nil && su.Sync == nil {
+		su.Sync = old.Sync
+	}
 	eco.state.Services[su.Service] = su
 	eco.sendFeedMessage("", MsgTypeServiceStatus, su)
 }
@@ -349,6 +423,11 @@ type releaseAssets struct {
 type releaseAsset struct {
 	*githubAsset
 	path string
+	// sig is the detached signature asset for a manifest. sig is nil for
+	// archives, or if no signature was published.
+	sig *githubAsset
+	// archive is the path of the downloaded and verified archive.
+	archive string
 }
 
 func (eco *Eco) initEco(conn net.Conn, req *initRequest) {
@@ -357,11 +436,28 @@ func (eco *Eco) initEco(conn net.Conn, req *initRequest) {
 
 	prog := newProgressReporter(conn, "eco")
 
-	if len(req.PW) == 0 && !walletFileExists() {
+	if len(req.PW) == 0 && !walletFileExists(req.Network) {
 		prog.fail("Password required to initialize wallet", nil)
 		return
 	}
 
+	// Check the seed before downloading anything.
+	var seed []byte
+	if len(req.Seed) > 0 {
+		if walletFileExists(req.Network) {
+			prog.fail("Cannot restore from seed. A wallet already exists", nil)
+			return
+		}
+		var err error
+		seed, err = decodeSeed(req.Seed)
+		if err != nil {
+			// The error is the message, e.g. a checksum mismatch.
+			prog.fail(err.Error(), nil)
+			return
+		}
+		defer encode.ClearBytes(seed)
+	}
+
 	// If we already have a version number and a password, we won't
 	// re-initialize.
 	if eco.state.Eco.Version != "" {
@@ -372,184 +468,106 @@ func (eco *Eco) initEco(conn net.Conn, req *initRequest) {
 		}
 	}
 
+	if req.SyncMode == SyncModeRemote {
+		prog.report(0.01, "Connecting to dcrd")
+		var err error
+		eco.runContext(time.Second*10, func(ctx context.Context) {
+			err = checkRemoteDCRD(ctx, req.Remote, req.Network, eco.proxyLocked())
+		})
+		if err != nil {
+			// The error is the message, e.g. a network mismatch.
+			prog.fail(err.Error(), nil)
+			return
+		}
+	}
+
 	prog.report(0.05, "Checking for updates")
-	releases, err := fetchReleases()
+	src, err := eco.releaseSourceLocked()
 	if err != nil {
-		prog.fail("Error fetching releases", err)
+		prog.fail("Error configuring release source", err)
 		return
 	}
-	// For now, just get the most recent release, regardless of whether it is
-	// pre-release. Eventually, we'll want to initially seed with latest stable
-	// release, then offer pre-releases as a user preference.
-	if len(releases) == 0 {
-		prog.fail("No releases fetched", nil)
+	releases, err := src.Releases(eco.outerCtx)
+	if err != nil {
+		prog.fail("Error fetching releases", err)
 		return
 	}
-	release := releases[0]
-	assets, err := parseAssets(release)
-	if err != nil {
-		prog.fail("Failed to parse assets", err)
+	release := selectRelease(releases, eco.state.Eco.ReleaseChannel)
+	if release == nil {
+		prog.fail(fmt.Sprintf("No %s releases fetched", eco.state.Eco.ReleaseChannel), nil)
 		return
 	}
 
 	versionDir := filepath.Join(EcoDir, release.Name)
 
-	skipDownload := false
-	if skipDownload {
-		log.Critical("Don't forget to remove skipDownload := true")
-	} else { // Need a way to disable re-download during testing here.
-		// All assets were found. Download and unpack them to a temporary directory.
-		tmpDir, err := ioutil.TempDir("", "")
-		if err != nil {
-			prog.fail("Failed to create temporary directory", err)
-			return
-		}
-		defer os.RemoveAll(tmpDir)
-
-		// Fetch and parse the manifests.
-		prog.report(0.1, "Downloading hash manifests")
-		hashes := make(map[string][]byte)
-
-		parseParts := func(line string) []string {
-			parts := make([]string, 0, 2)
-			for _, part := range strings.Split(line, " ") {
-				if part == "" {
-					continue
-				}
-				parts = append(parts, part)
-			}
-			return parts
-		}
-
-		log.Infof("Retrieving %d manifest files", len(assets.manifests))
-		for _, m := range assets.manifests {
-			log.Infof("Downloading %s", m.Name)
-			m.path, err = fetchAsset(eco.outerCtx, tmpDir, m.URL, m.Name)
-			if err != nil {
-				prog.fail("Failed to fetch manifest", err)
-				return
-			}
-			manifestFile, err := os.Open(m.path)
-			if err != nil {
-				prog.fail("Error opening manifest file", err)
-				return
-			}
-			defer manifestFile.Close()
-
-			scanner := bufio.NewScanner(manifestFile)
-			for scanner.Scan() {
-				line := scanner.Text()
-				if line == "" {
-					continue
-				}
-				parts := parseParts(line)
-				if len(parts) != 2 {
-					err := fmt.Errorf("Manifest line parse error. Expected 2 parts, got %d for %q: %q", len(parts), line, parts)
-					prog.fail("Manifest parse error", err)
-					return
-				}
-				b, err := hex.DecodeString(parts[0])
-				if err != nil {
-					prog.fail("Hex decode error", err)
-					return
-				}
-				if len(b) != sha256.Size {
-					err := fmt.Errorf("Invalid manifest hash length. Wanted %d, got %d", sha256.Size, len(b))
-					prog.fail("Invalid manifest length", err)
-					return
-				}
-				hashes[parts[1]] = b
-			}
-
-			if err := scanner.Err(); err != nil {
-				prog.fail("Error reading manifest: %w", err)
-				return
-			}
-		}
-
-		// Fetch, unpack, and move all resources.
-		err = moveResources(eco.outerCtx, tmpDir, assets, hashes, prog.subReporter(0.12, 0.80))
-		if err != nil {
-			prog.fail("Error moving assets", err)
-			return
-		}
-
-		// Make sure we have a chromium browser for DEX. If we don't, but we know
-		// where to get one, download it. If we don't know where to get one, we'll
-		// have to deal with it at the UI level. E.g. a message saying "Open DEX in
-		// your browser at ..."
-		_, _, found, err := chromium(eco.outerCtx)
-		if err != nil {
-			prog.fail("Error searching for Chromium", err)
-			return
-		}
-		if !found {
-			// If we have a file to download, do it.
-			err := downloadChromium(eco.outerCtx, tmpDir, versionDir, prog.subReporter(0.80, 0.85))
-			if err != nil {
-				log.Errorf("Error downloading Chromium: %v", err)
-			}
-		}
-
-		// Update complete, store password and new eco state.
-		crypter := encrypt.NewCrypter(req.PW)
-		err = eco.db.Store(crypterKey, crypter.Serialize())
-		if err != nil {
-			err := fmt.Errorf("Upgraded to version %s, but failed to save encryption key to the DB: %w", release.Name, err)
-			prog.fail("DB error storing encryption key", err)
-			return
-		}
+	trustExtraKeys := eco.state.Eco.ReleaseSource.TrustExtraKeys
+	if !eco.downloadRelease(src, trustExtraKeys, eco.downloadCacheLocked(), release, prog.subReporter(0.05, 0.85)) {
+		return
 	}
 
-	// Need to cache the password until we can initialize DEX.
-	pwc, err := newPWCache(req.PW)
+	// Update complete, store password and new eco state.
+	crypter := encrypt.NewCrypter(req.PW)
+	err = eco.db.Store(crypterKey, crypter.Serialize())
 	if err != nil {
-		prog.fail("Encryption error", err)
+		err := fmt.Errorf("Upgraded to version %s, but failed to save encryption key to the DB: %w", release.Name, err)
+		prog.fail("DB error storing encryption key", err)
 		return
 	}
-	err = eco.db.EncodeStore(dexInputKey, pwc)
-	if err != nil {
-		prog.fail("Error storing dex input", err)
+
+	// The password is kept in memory until DEX is initialized and, for a new
+	// wallet, dcrwallet has started. If Eco is restarted first, the jobs wait
+	// for an unlock.
+	pending := PendingDEXInit
+	if err := eco.session.set(req.PW); err != nil {
+		prog.fail("Encryption error", err)
 		return
 	}
 
-	if !walletFileExists() {
-		prog.report(0.85, "Initializing dcrwallet")
+	restoring := seed != nil
+	if !walletFileExists(req.Network) {
+		if restoring {
+			prog.report(0.85, "Restoring wallet from seed")
+		} else {
+			prog.report(0.85, "Initializing dcrwallet")
+		}
 		createWallet := func() bool {
 			// Write the user's password to a file.
-			passFile, err := ioutil.TempFile("", "")
+			passFilePath, err := writeWalletPassFile(req.PW)
 			if err != nil {
 				prog.fail("Error initializing wallet pass file", err)
 				return false
 			}
 			// Delete the file asap.
-			defer os.Remove(passFile.Name())
-			passFile.Write([]byte(fmt.Sprintf("pass=%s\n", string(req.PW))))
+			defer os.Remove(passFilePath)
 
 			// Create a seed, and save it encrypted with the user's wallet
-			// password until the user authorizes deletion.
-			seed := encode.RandomBytes(32)
-			crypter := encrypt.NewCrypter(req.PW)
-			encSeed, err := crypter.Encrypt(seed)
-			if err != nil {
-				prog.fail("Error encrypting wallet seed", err)
-				return false
-			}
-			err = eco.db.Store(walletSeedKey, encSeed)
-			if err != nil {
-				prog.fail("Error storing wallet seed", err)
-				return false
+			// password until the user authorizes deletion. A restored seed
+			// is already backed up, so it isn't saved.
+			if !restoring {
+				seed = encode.RandomBytes(walletSeedSize)
+				crypter := encrypt.NewCrypter(req.PW)
+				encSeed, err := crypter.Encrypt(seed)
+				if err != nil {
+					prog.fail("Error encrypting wallet seed", err)
+					return false
+				}
+				err = eco.db.Store(walletSeedKey, encSeed)
+				if err != nil {
+					prog.fail("Error storing wallet seed", err)
+					return false
+				}
 			}
 
 			exe := filepath.Join(versionDir, decred, dcrWalletExeName)
 
 			err = nil
 			eco.runContext(time.Second*5, func(ctx context.Context) {
-				svcExe := newExe(eco.outerCtx, exe,
+				args := append([]string{
 					fmt.Sprintf("--appdata=\"%s\"", dcrwalletAppDir),
 					"--create",
-					fmt.Sprintf("--configfile=\"%s\"", passFile.Name()),
-				)
+					fmt.Sprintf("--configfile=\"%s\"", passFilePath),
+				}, req.Network.args()...)
+				svcExe := newExe(eco.outerCtx, exe, args...)
 
 				var stdin io.WriteCloser
 				stdin, err = svcExe.cmd.StdinPipe()
@@ -572,24 +590,13 @@ func (eco *Eco) initEco(conn net.Conn, req *initRequest) {
 				return false
 			}
 
-			// Here's the problem. dcrwallet requires the password the first
-			// time it is started. But we don't start dcrwallet until dcrd is
-			// synced, so storing the password in memory until startup is not
-			// good enough, since the user could kill Eco before sync is
-			// complete, and we wouldn't have it during the next startup.
-			// So, we'll store the password in the database until the wallet
-			// is started and a sync has begun.
-			pwc, err := newPWCache(req.PW)
-			if err != nil {
-				prog.fail("Encryption error", err)
-				return false
+			// dcrwallet requires the password the first time it is started,
+			// but isn't started until dcrd is synced. A restored wallet also
+			// needs it for account discovery once the wallet is synced.
+			pending |= PendingWalletStart
+			if restoring {
+				pending |= PendingWalletRestore
 			}
-			err = eco.db.EncodeStore(extraInputKey, pwc)
-			if err != nil {
-				prog.fail("Error storing extra input", err)
-				return false
-			}
-
 			return true
 		}
 		if !createWallet() {
@@ -597,9 +604,23 @@ func (eco *Eco) initEco(conn net.Conn, req *initRequest) {
 		}
 	}
 
+	if req.SyncMode == SyncModeRemote {
+		eco.dcrd.Remote = req.Remote
+		if err := eco.saveDCRDState(); err != nil {
+			eco.dcrd.Remote = nil
+			prog.fail("DB error storing dcrd configuration", err)
+			return
+		}
+	}
+
 	eco.state.Eco.WalletExists = true // Can't get here without a wallet.
 	eco.state.Eco.Version = release.Name
 	eco.state.Eco.SyncMode = req.SyncMode
+	eco.state.Eco.Network = req.Network
+	eco.state.Eco.Pending = pending
+	if restoring {
+		eco.state.Eco.SeedBackedUp = true
+	}
 	err = eco.saveEcoState()
 	if err != nil {
 		err := fmt.Errorf("Upgraded to version %s, but failed to save new state to the DB: %w", release.Name, err)
@@ -609,388 +630,884 @@ func (eco *Eco) initEco(conn net.Conn, req *initRequest) {
 
 	// The client should close the connection up on receiving progress = 1.0.
 	prog.report(1.0, "Upgrade complete")
-	eco.sendServiceStatus(&ServiceStatus{Service: decrediton})
+	if req.Network.HasDecrediton() {
+		eco.sendServiceStatus(&ServiceStatus{Service: decrediton})
+	}
 	go eco.start()
 }
 
-func (eco *Eco) saveEcoState() error {
-	fmt.Println("--saveEcoState", dirtyEncode(eco.state))
-	return eco.db.EncodeStore(ecoStateKey, eco.state.Eco)
+// upgradeEco upgrades an initialized Eco to the newest release. The new
+// release is downloaded and verified before any services are stopped, so a
+// failed download leaves the current installation untouched.
+func (eco *Eco) upgradeEco(conn net.Conn) {
+	prog := newProgressReporter(conn, "eco")
+
+	if !atomic.CompareAndSwapUint32(&upgrading, 0, 1) {
+		prog.fail("Upgrade already in progress", nil)
+		return
+	}
+	defer atomic.StoreUint32(&upgrading, 0)
+
+	eco.stateMtx.RLock()
+	currentVersion := eco.state.Eco.Version
+	channel := eco.state.Eco.ReleaseChannel
+	trustExtraKeys := eco.state.Eco.ReleaseSource.TrustExtraKeys
+	eco.stateMtx.RUnlock()
+
+	if currentVersion == "" {
+		prog.fail("Eco is not initialized", nil)
+		return
+	}
+
+	prog.report(0.05, "Checking for updates")
+	src, err := eco.releaseSource()
+	if err != nil {
+		prog.fail("Error configuring release source", err)
+		return
+	}
+	releases, err := src.Releases(eco.outerCtx)
+	if err != nil {
+		prog.fail("Error fetching releases", err)
+		return
+	}
+	release := selectRelease(releases, channel)
+	if release == nil {
+		prog.fail(fmt.Sprintf("No %s releases fetched", channel), nil)
+		return
+	}
+	// Switching from the pre-release channel to stable can leave us on a
+	// pre-release that is newer than the newest stable release. Don't
+	// downgrade.
+	for _, r := range releases {
+		if r.Name == currentVersion && !release.Published.After(r.Published) {
+			release = r
+			break
+		}
+	}
+	if release.Name == currentVersion {
+		prog.report(1.0, "Eco is already up to date")
+		return
+	}
+
+	if !eco.downloadRelease(src, trustExtraKeys, eco.downloadCache(), release, prog.subReporter(0.05, 0.85)) {
+		return
+	}
+
+	// The current version has been running, so it's our rollback target if
+	// the new version fails to start.
+	if err := eco.recordGoodVersion(currentVersion); err != nil {
+		prog.fail("Error recording current version", err)
+		return
+	}
+
+	prog.report(0.85, "Stopping services")
+	err = eco.switchVersion(release.Name)
+	if err != nil {
+		prog.fail("Error switching versions", err)
+		return
+	}
+
+	go eco.monitorUpgrade(release.Name, currentVersion)
+
+	prog.report(1.0, "Upgraded to version %s", release.Name)
 }
 
-func (eco *Eco) dcrdProcess() (*serviceExe, *rpcclient.Client) {
+// monitorUpgrade watches dcrd and dcrwallet after an upgrade. If either exits
+// repeatedly or doesn't answer RPC requests within the upgradeHealthWindow,
+// Eco switches back to the previous version and sends a Notification
+// explaining why. Otherwise, the new version is recorded as known-good.
+func (eco *Eco) monitorUpgrade(newVersion, prevVersion string) {
+	dcrdExits := eco.sup.exitCount(dcrd)
+	walletExits := eco.sup.exitCount(dcrwallet)
+
+	rollback := func(reason string) {
+		log.Errorf("Rolling back upgrade to %s: %s", newVersion, reason)
+		if !atomic.CompareAndSwapUint32(&upgrading, 0, 1) {
+			log.Errorf("Not rolling back. Another upgrade is in progress.")
+			return
+		}
+		defer atomic.StoreUint32(&upgrading, 0)
+		err := eco.switchVersion(prevVersion)
+		if err != nil {
+			log.Errorf("Error rolling back to version %s: %v", prevVersion, err)
+			eco.sendNotification("Upgrade failed",
+				fmt.Sprintf("Version %s failed to start (%s), and rolling back to version %s failed: %v", newVersion, reason, prevVersion, err))
+			return
+		}
+		eco.sendNotification("Upgrade rolled back",
+			fmt.Sprintf("Version %s failed to start (%s). Eco has switched back to version %s.", newVersion, reason, prevVersion))
+	}
+
+	deadline := time.NewTimer(upgradeHealthWindow)
+	defer deadline.Stop()
+	ticker := time.NewTicker(time.Second * 5)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if n := eco.sup.exitCount(dcrd) - dcrdExits; n >= maxUpgradeExits {
+				rollback(fmt.Sprintf("dcrd exited %d times", n))
+				return
+			}
+			if n := eco.sup.exitCount(dcrwallet) - walletExits; n >= maxUpgradeExits {
+				rollback(fmt.Sprintf("dcrwallet exited %d times", n))
+				return
+			}
+			if eco.servicesHealthy() {
+				log.Infof("Upgrade to version %s looks healthy", newVersion)
+				if err := eco.recordGoodVersion(newVersion); err != nil {
+					log.Errorf("Error recording good version %s: %v", newVersion, err)
+				}
+				if _, err := eco.pruneVersions(); err != nil {
+					log.Errorf("Error pruning old versions: %v", err)
+				}
+				return
+			}
+		case <-deadline.C:
+			rollback(fmt.Sprintf("no RPC response within %s", upgradeHealthWindow))
+			return
+		case <-eco.outerCtx.Done():
+			return
+		}
+	}
+}
+
+// servicesHealthy checks that dcrd and dcrwallet are answering RPC requests.
+// dcrd is not checked in SPV mode. In full mode, dcrwallet isn't started until
+// dcrd has synced, so dcrwallet is only checked after that.
+func (eco *Eco) servicesHealthy() bool {
 	eco.stateMtx.RLock()
-	defer eco.stateMtx.RUnlock()
-	return eco.dcrd.exe, eco.dcrd.client
+	dcrdCl, walletCl := eco.dcrd.client, eco.dcrwallet.client
+	spv := eco.state.Eco.SyncMode == SyncModeSPV
+	eco.stateMtx.RUnlock()
+
+	if !spv {
+		if dcrdCl == nil {
+			return false
+		}
+		var err error
+		eco.runContext(time.Second*5, func(ctx context.Context) {
+			_, err = dcrdCl.GetBlockChainInfo(ctx)
+		})
+		if err != nil {
+			return false
+		}
+		if !eco.sup.isReady(dcrd) {
+			return true
+		}
+	}
+
+	if walletCl == nil {
+		return false
+	}
+	var err error
+	eco.runContext(time.Second*5, func(ctx context.Context) {
+		_, err = walletCl.GetInfo(ctx)
+	})
+	return err == nil
 }
 
-func (eco *Eco) dcrWalletProcess() (*serviceExe, *walletclient.Client) {
+// recordGoodVersion adds the version to the front of the known-good versions
+// and saves the EcoState.
+func (eco *Eco) recordGoodVersion(version string) error {
+	eco.stateMtx.Lock()
+	defer eco.stateMtx.Unlock()
+	goodVersions := []string{version}
+	for _, v := range eco.state.Eco.GoodVersions {
+		if v != version && len(goodVersions) < maxGoodVersions {
+			goodVersions = append(goodVersions, v)
+		}
+	}
+	eco.state.Eco.GoodVersions = goodVersions
+	return eco.saveEcoState()
+}
+
+// setReleaseChannel sets and saves the release channel preference. The
+// channel is used for release selection during initialization and upgrades.
+func (eco *Eco) setReleaseChannel(channel ReleaseChannel) error {
+	switch channel {
+	case ReleaseChannelStable, ReleaseChannelPrerelease:
+	default:
+		return fmt.Errorf("Unknown release channel %d", channel)
+	}
+	eco.stateMtx.Lock()
+	defer eco.stateMtx.Unlock()
+	eco.state.Eco.ReleaseChannel = channel
+	return eco.saveEcoState()
+}
+
+// releaseSource creates the configured ReleaseSource.
+func (eco *Eco) releaseSource() (ReleaseSource, error) {
 	eco.stateMtx.RLock()
 	defer eco.stateMtx.RUnlock()
-	return eco.dcrwallet.exe, eco.dcrwallet.client
+	return eco.releaseSourceLocked()
 }
 
-func (eco *Eco) runContext(dur time.Duration, f func(context.Context)) {
-	callCtx, cancel := context.WithTimeout(eco.outerCtx, dur)
-	defer cancel()
-	f(callCtx)
+// releaseSourceLocked is releaseSource for a caller that holds the stateMtx.
+func (eco *Eco) releaseSourceLocked() (ReleaseSource, error) {
+	cfg := eco.state.Eco.ReleaseSource
+	return newReleaseSource(&cfg)
 }
 
-func (eco *Eco) stopDCRD() error {
-	if atomic.LoadUint32(&dcrdRunning) == 0 {
-		return fmt.Errorf("Cannot stop dcrd. Not running")
-	}
-	svcExe, cl := eco.dcrdProcess()
-	if cl == nil {
-		return fmt.Errorf("Cannot stop dcrd. No client found")
+// setReleaseSource sets and saves the release source configuration.
+func (eco *Eco) setReleaseSource(cfg *ReleaseSourceConfig) error {
+	if _, err := newReleaseSource(cfg); err != nil {
+		return err
 	}
-	if svcExe == nil {
-		return fmt.Errorf("No serviceExe for dcrd")
+	eco.stateMtx.Lock()
+	defer eco.stateMtx.Unlock()
+	eco.state.Eco.ReleaseSource = *cfg
+	return eco.saveEcoState()
+}
+
+// downloadCache creates the downloadCache with the configured limit.
+func (eco *Eco) downloadCache() *downloadCache {
+	eco.stateMtx.RLock()
+	defer eco.stateMtx.RUnlock()
+	return eco.downloadCacheLocked()
+}
+
+// downloadCacheLocked is downloadCache for a caller that holds the stateMtx.
+func (eco *Eco) downloadCacheLocked() *downloadCache {
+	return newDownloadCache(downloadCacheDir(), eco.state.Eco.DownloadCacheLimit)
+}
+
+// setDownloadCacheLimit sets and saves the download cache size limit, and
+// evicts files if the cache is over the new limit.
+func (eco *Eco) setDownloadCacheLimit(limit int64) error {
+	if limit < 0 {
+		return fmt.Errorf("Invalid cache limit %d", limit)
 	}
-	_, err := cl.RawRequest(eco.innerCtx, "stop", nil)
+	eco.stateMtx.Lock()
+	eco.state.Eco.DownloadCacheLimit = limit
+	err := eco.saveEcoState()
+	eco.stateMtx.Unlock()
 	if err != nil {
-		log.Errorf("dcrd RawRequest(stop) error: %v", err)
-	}
-	select {
-	case <-svcExe.Done():
-	case <-time.After(time.Second * 60):
-		svcExe.cmd.Process.Kill()
-		return fmt.Errorf("Timed out waiting for dcrd to shutdown. Killing the process")
+		return err
 	}
-	return nil
+	return eco.downloadCache().evict()
 }
 
-func (eco *Eco) stopDCRWallet() error {
-	if atomic.LoadUint32(&dcrWalletRunning) == 0 {
-		return fmt.Errorf("Cannot stop dcrwallet. Not running")
+// setVersion switches to one of the known-good versions.
+func (eco *Eco) setVersion(version string) error {
+	if !atomic.CompareAndSwapUint32(&upgrading, 0, 1) {
+		return fmt.Errorf("Upgrade in progress")
 	}
-	svcExe, cl := eco.dcrWalletProcess()
-	if cl == nil {
-		return fmt.Errorf("Cannot stop dcrwallet. No client found")
+	defer atomic.StoreUint32(&upgrading, 0)
+
+	eco.stateMtx.RLock()
+	currentVersion := eco.state.Eco.Version
+	var known bool
+	for _, v := range eco.state.Eco.GoodVersions {
+		if v == version {
+			known = true
+			break
+		}
 	}
-	if svcExe == nil {
-		return fmt.Errorf("No serviceExe for dcrwallet")
+	eco.stateMtx.RUnlock()
+
+	if !known {
+		return fmt.Errorf("%s is not a known-good version", version)
 	}
-	err := cl.Call(eco.innerCtx, "stop", nil)
-	if err != nil {
-		log.Errorf("error calling 'stop' method for dcrwallet")
+	if version == currentVersion {
+		return fmt.Errorf("Already running version %s", version)
 	}
-	select {
-	case <-svcExe.Done():
-	case <-time.After(time.Second * 60):
-		svcExe.cmd.Process.Kill()
-		return fmt.Errorf("Timed out waiting for dcrd to shutdown. Killing the process")
+	if !fileExists(filepath.Join(EcoDir, version)) {
+		return fmt.Errorf("No directory found for version %s", version)
 	}
-	return nil
+	return eco.switchVersion(version)
 }
 
-func (eco *Eco) runDCRD() error {
+// switchVersion stops the services, sets the version, and restarts the
+// services with the binaries from the new version directory. If the new
+// version can't be saved, the services are restarted with the old version.
+func (eco *Eco) switchVersion(version string) error {
+	dexWasRunning := eco.sup.running(dexc)
+
+	eco.stopServices()
+
 	eco.stateMtx.Lock()
-	defer eco.stateMtx.Unlock()
-	userSettings := &eco.dcrd.UserSettings
+	oldVersion := eco.state.Eco.Version
+	eco.state.Eco.Version = version
+	err := eco.saveEcoState()
+	if err != nil {
+		eco.state.Eco.Version = oldVersion
+		err = fmt.Errorf("failed to save new version %s to the DB: %w", version, err)
+	}
+	eco.versionDir = filepath.Join(EcoDir, eco.state.Eco.Version)
+	eco.stateMtx.Unlock()
+
+	go func() {
+		eco.start()
+		if dexWasRunning && !eco.sup.running(dexc) {
+			if err := eco.runDEX(); err != nil {
+				log.Errorf("Error restarting DEX: %v", err)
+			}
+		}
+	}()
+	return err
+}
 
-	if !atomic.CompareAndSwapUint32(&dcrdRunning, 0, 1) {
-		return fmt.Errorf("dcrd already running")
+// downloadRelease downloads, verifies, and unpacks the release into its
+// version directory, using the cache for the archives. trustExtraKeys is the
+// ReleaseSourceConfig.TrustExtraKeys setting. Failures are reported through
+// the progressReporter, and downloadRelease returns false. downloadRelease
+// doesn't lock the stateMtx, so initEco can call it with the lock held.
+func (eco *Eco) downloadRelease(src ReleaseSource, trustExtraKeys bool, cache *downloadCache, release *githubRelease, prog *progressReporter) bool {
+	assets, err := parseAssets(release)
+	if err != nil {
+		prog.fail("Failed to parse assets", err)
+		return false
 	}
 
-	eco.sendServiceStatus(&ServiceStatus{
-		Service: dcrd,
-		On:      true,
-	})
+	versionDir := filepath.Join(EcoDir, release.Name)
 
-	args := []string{
-		fmt.Sprintf("--appdata=\"%s\"", dcrdAppDir),
-		fmt.Sprintf("--debuglevel=%s", userSettings.DebugLevel),
-		fmt.Sprintf("--rpclisten=%s", dcrdRPCListen),
-		fmt.Sprintf("--rpcuser=%s", eco.dcrd.RPCUser),
-		fmt.Sprintf("--rpcpass=%s", eco.dcrd.RPCPass),
-		fmt.Sprintf("--listen=%s", dcrdListen),
+	// All assets were found. Download and unpack them to a temporary directory.
+	tmpDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		prog.fail("Failed to create temporary directory", err)
+		return false
 	}
+	defer os.RemoveAll(tmpDir)
 
-	go func() {
-		defer atomic.StoreUint32(&dcrdRunning, 0)
-		defer eco.sendServiceStatus(&ServiceStatus{
-			Service: dcrd,
-			On:      false,
-		})
-		for {
-			exe := filepath.Join(EcoDir, eco.state.Eco.Version, decred, dcrdExeName)
+	keyring, extraKeys, err := releaseKeyring(trustExtraKeys)
+	if err != nil {
+		prog.fail("Unable to load release signing keys", err)
+		return false
+	}
+	if len(extraKeys) > 0 {
+		eco.sendNotification("Extra release signing keys trusted",
+			fmt.Sprintf("Release %s is verified with keys from %s: %s", release.Name, extraReleaseKeysPath, strings.Join(extraKeys, ", ")))
+	}
 
-			svcExe := newExe(eco.innerCtx, exe, args...)
-			eco.dcrd.exe = svcExe
-			svcExe.Run()
-			select {
-			case <-time.After(time.Second * 5):
-			case <-eco.outerCtx.Done():
-				return
+	// Fetch, verify, and parse the manifests.
+	prog.report(0.05, "Downloading hash manifests")
+	hashes := make(map[string][]byte)
+
+	parseParts := func(line string) []string {
+		parts := make([]string, 0, 2)
+		for _, part := range strings.Split(line, " ") {
+			if part == "" {
+				continue
 			}
+			parts = append(parts, part)
 		}
+		return parts
+	}
 
-	}()
-
-	go func() {
-		var connectAttempts int
+	log.Infof("Retrieving %d manifest files", len(assets.manifests))
+	for _, m := range assets.manifests {
+		log.Infof("Downloading %s", m.Name)
+		m.path, err = src.Fetch(eco.outerCtx, m.githubAsset, tmpDir, nil)
+		if err != nil {
+			prog.fail("Failed to fetch manifest", err)
+			return false
+		}
+		if m.sig == nil {
+			prog.fail("Missing manifest signature", fmt.Errorf("No signature published for %s", m.Name))
+			return false
+		}
+		sigPath, err := src.Fetch(eco.outerCtx, m.sig, tmpDir, nil)
+		if err != nil {
+			prog.fail("Failed to fetch manifest signature", err)
+			return false
+		}
+		if err := verifyManifest(keyring, m.path, sigPath); err != nil {
+			prog.fail("Invalid manifest signature", err)
+			return false
+		}
+		manifestFile, err := os.Open(m.path)
+		if err != nil {
+			prog.fail("Error opening manifest file", err)
+			return false
+		}
+		defer manifestFile.Close()
 
-		// First, keep trying to get a client until successful. On initial
-		// startup, this may fail until the TLS keypair is generated, which
-		// is probably only once.
-		var cl *rpcclient.Client
-		for {
-			var err error
-			cl, err = eco.dcrdClient()
-			if err == nil {
-				break
+		scanner := bufio.NewScanner(manifestFile)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if line == "" {
+				continue
 			}
-			connectAttempts++
-			if connectAttempts >= 5 && connectAttempts%5 == 0 {
-				log.Errorf("Error getting dcrd RPC client: %v", err)
+			parts := parseParts(line)
+			if len(parts) != 2 {
+				err := fmt.Errorf("Manifest line parse error. Expected 2 parts, got %d for %q: %q", len(parts), line, parts)
+				prog.fail("Manifest parse error", err)
+				return false
 			}
-			select {
-			case <-time.After(time.Second * 5):
-			case <-eco.outerCtx.Done():
-				return
+			b, err := hex.DecodeString(parts[0])
+			if err != nil {
+				prog.fail("Hex decode error", err)
+				return false
 			}
+			if len(b) != sha256.Size {
+				err := fmt.Errorf("Invalid manifest hash length. Wanted %d, got %d", sha256.Size, len(b))
+				prog.fail("Invalid manifest length", err)
+				return false
+			}
+			hashes[parts[1]] = b
 		}
-		eco.stateMtx.Lock()
-		eco.dcrd.client = cl
-		eco.stateMtx.Unlock()
 
-		defer func() {
-			eco.stateMtx.Lock()
-			eco.dcrd.client = nil
-			eco.stateMtx.Unlock()
-		}()
+		if err := scanner.Err(); err != nil {
+			prog.fail("Error reading manifest: %w", err)
+			return false
+		}
+	}
 
-		getInfo := func() (bci *chainjson.GetBlockChainInfoResult) {
-			var err error
-			eco.runContext(time.Second, func(ctx context.Context) {
-				bci, err = cl.GetBlockChainInfo(ctx)
-			})
-			if err != nil {
-				log.Debugf("GetBlockChainInfo error: %v", err)
-			}
-			return bci
+	// Fetch, unpack, and move all resources.
+	err = moveResources(eco.outerCtx, src, cache, tmpDir, assets, hashes, prog.subReporter(0.1, 0.94))
+	if err != nil {
+		prog.fail("Error moving assets", err)
+		return false
+	}
+
+	// Make sure we have a chromium browser for DEX. If we don't, but we know
+	// where to get one, download it. If we don't know where to get one, we'll
+	// have to deal with it at the UI level. E.g. a message saying "Open DEX in
+	// your browser at ..."
+	_, _, found, err := chromium(eco.outerCtx)
+	if err != nil {
+		prog.fail("Error searching for Chromium", err)
+		return false
+	}
+	if !found {
+		// If we have a file to download, do it.
+		err := downloadChromium(eco.outerCtx, cache, tmpDir, versionDir, prog.subReporter(0.94, 1))
+		if err != nil {
+			log.Errorf("Error downloading Chromium: %v", err)
 		}
+	}
+	return true
+}
 
-		var bcInfo *chainjson.GetBlockChainInfoResult
-		for {
-			if eco.outerCtx.Err() != nil {
-				return
-			}
-			if bcInfo = getInfo(); bcInfo == nil {
-				select {
-				case <-time.After(time.Second):
-					continue
-				case <-eco.outerCtx.Done():
-					return
-				}
-			}
-			break
+func (eco *Eco) saveEcoState() error {
+	return eco.db.EncodeStore(ecoStateKey, eco.state.Eco)
+}
+
+// dcrdRPC is the dcrd RPC client, or nil if dcrd is not connected.
+func (eco *Eco) dcrdRPC() *rpcclient.Client {
+	eco.stateMtx.RLock()
+	defer eco.stateMtx.RUnlock()
+	return eco.dcrd.client
+}
+
+// dcrWalletRPC is the dcrwallet RPC client, or nil if dcrwallet is not
+// connected.
+func (eco *Eco) dcrWalletRPC() *walletclient.Client {
+	eco.stateMtx.RLock()
+	defer eco.stateMtx.RUnlock()
+	return eco.dcrwallet.client
+}
+
+// exePath is the path of an executable in the current version directory.
+func (eco *Eco) exePath(subDir, exeName string) string {
+	eco.stateMtx.RLock()
+	defer eco.stateMtx.RUnlock()
+	return filepath.Join(EcoDir, eco.state.Eco.Version, subDir, exeName)
+}
+
+func (eco *Eco) runContext(dur time.Duration, f func(context.Context)) {
+	callCtx, cancel := context.WithTimeout(eco.outerCtx, dur)
+	defer cancel()
+	f(callCtx)
+}
+
+// stopServices stops all services, dependents first, and waits for their
+// processes to exit. Services can be started again with start.
+func (eco *Eco) stopServices() {
+	eco.sup.stopAll()
+}
+
+// startService starts one of the managed services.
+func (eco *Eco) startService(svc string) error {
+	switch svc {
+	case dcrd:
+		if eco.syncMode() == SyncModeSPV {
+			return fmt.Errorf("dcrd is not used in SPV mode")
 		}
-		startHeight := bcInfo.Blocks
-		syncing := bcInfo.InitialBlockDownload || bcInfo.SyncHeight-startHeight > 1
+		return eco.runDCRD()
+	case dcrwallet:
+		return eco.runDCRWallet()
+	case dexc:
+		return eco.runDEX()
+	case decrediton:
+		return eco.runDecrediton()
+	}
+	return fmt.Errorf("Unknown service %q", svc)
+}
 
-		sendSyncUpdate := func() (synced bool) {
-			if bcInfo = getInfo(); bcInfo == nil {
-				return
-			}
-			h := bcInfo.SyncHeight
-			if bcInfo.Headers > h {
-				h = bcInfo.Headers
+// controlServices is called before stopping or restarting a service from the
+// IPC server. The returned function must be called when done.
+func (eco *Eco) controlServices(svc string) (func(), error) {
+	if !managedServices[svc] {
+		return nil, fmt.Errorf("Unknown service %q", svc)
+	}
+	if eco.syncMode() == SyncModeUninitialized {
+		return nil, fmt.Errorf("Eco is not initialized")
+	}
+	// Don't fight an upgrade over the services.
+	if !atomic.CompareAndSwapUint32(&upgrading, 0, 1) {
+		return nil, fmt.Errorf("Upgrade in progress")
+	}
+	return func() { atomic.StoreUint32(&upgrading, 0) }, nil
+}
+
+// stopService stops the service, after stopping any services that depend on
+// it.
+func (eco *Eco) stopService(svc string) error {
+	done, err := eco.controlServices(svc)
+	if err != nil {
+		return err
+	}
+	defer done()
+	_, err = eco.sup.stopWithDependents(svc)
+	return err
+}
+
+// restartService stops the service and any services that depend on it, and
+// then starts them again. The service is started even if it wasn't running.
+// The dependents will wait for the service to be ready.
+func (eco *Eco) restartService(svc string) error {
+	done, err := eco.controlServices(svc)
+	if err != nil {
+		return err
+	}
+	defer done()
+	stopped, err := eco.sup.stopWithDependents(svc)
+	if err != nil {
+		return err
+	}
+
+	toStart := []string{svc}
+	for i := len(stopped) - 1; i >= 0; i-- {
+		if stopped[i] != svc {
+			toStart = append(toStart, stopped[i])
+		}
+	}
+	var errs []string
+	for _, name := range toStart {
+		if err := eco.startService(name); err != nil {
+			log.Errorf("Error restarting %s: %v", name, err)
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("Error restarting services: %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+func (eco *Eco) runDCRD() error {
+	eco.stateMtx.RLock()
+	userSettings := eco.dcrd.UserSettings
+	network := eco.state.Eco.Network
+	ports := eco.portsLocked()
+	args := append([]string{
+		fmt.Sprintf("--appdata=\"%s\"", dcrdAppDir),
+		fmt.Sprintf("--rpclisten=%s", ports.DCRDRPCListen),
+		fmt.Sprintf("--rpcuser=%s", eco.dcrd.RPCUser),
+		fmt.Sprintf("--rpcpass=%s", eco.dcrd.RPCPass),
+		fmt.Sprintf("--listen=%s", ports.DCRDListen),
+	}, network.args()...)
+	args = append(args, userSettings.args()...)
+	args = append(args, eco.proxyLocked().dcrdArgs()...)
+	remote := eco.dcrd.Remote
+	if eco.state.Eco.SyncMode != SyncModeRemote {
+		remote = nil
+	}
+	eco.stateMtx.RUnlock()
+
+	spec := &serviceSpec{
+		name: dcrd,
+		connect: func() error {
+			// On initial startup, this may fail until the TLS keypair is
+			// generated, which is probably only once.
+			cl, err := eco.dcrdClient()
+			if err != nil {
+				return err
 			}
-			toGo := h - bcInfo.Blocks
-			syncing = bcInfo.InitialBlockDownload || toGo > 1
-			if !syncing {
-				eco.signalDCRDSynced()
-				eco.sendSyncUpdate(&Progress{
-					Service:  dcrd,
-					Status:   "Fully synced",
-					Progress: 1.0,
-				})
-				return true
+			eco.stateMtx.Lock()
+			eco.dcrd.client = cl
+			eco.stateMtx.Unlock()
+			return nil
+		},
+		disconnect: func() {
+			eco.stateMtx.Lock()
+			eco.dcrd.client = nil
+			eco.stateMtx.Unlock()
+		},
+		monitor: eco.monitorDCRD,
+		probe: func(ctx context.Context) error {
+			cl := eco.dcrdRPC()
+			if cl == nil {
+				return fmt.Errorf("dcrd not connected")
 			}
-			progress := 1 - float32(toGo)/float32(h-startHeight)
-			eco.sendSyncUpdate(&Progress{
-				Service:  dcrd,
-				Status:   fmt.Sprintf("Syncing blockchain at block %d", bcInfo.Blocks),
-				Progress: progress,
-			})
-			return
+			_, err := cl.GetBlockChainInfo(ctx)
+			return err
+		},
+	}
+
+	// A remote dcrd isn't ours to run or stop. It's only monitored, for the
+	// sync status and so that dcrwallet waits for it.
+	if remote != nil {
+		if err := writeRemoteCert(remote); err != nil {
+			return fmt.Errorf("Error writing remote dcrd certificate: %w", err)
 		}
+		return eco.sup.start(spec)
+	}
 
-		sendSyncUpdate()
+	spec.exe = func() (*serviceExe, error) {
+		return newExe(eco.innerCtx, eco.exePath(decred, dcrdExeName), args...), nil
+	}
+	spec.shutdown = func(ctx context.Context) error {
+		cl := eco.dcrdRPC()
+		if cl == nil {
+			return fmt.Errorf("Cannot stop dcrd. No client found")
+		}
+		_, err := cl.RawRequest(ctx, "stop", nil)
+		return err
+	}
+	return eco.sup.start(spec)
+}
 
-		delay := time.Second * 5
-		for {
-			timer := time.NewTimer(delay)
-			delay = time.Second * 5
+// monitorDCRD sends dcrd sync updates, and signals that dcrd is ready once it
+// is synced.
+func (eco *Eco) monitorDCRD(ctx context.Context, ready func()) {
+	cl := eco.dcrdRPC()
+
+	getInfo := func() (bci *chainjson.GetBlockChainInfoResult) {
+		var err error
+		eco.runContext(time.Second, func(ctx context.Context) {
+			bci, err = cl.GetBlockChainInfo(ctx)
+		})
+		if err != nil {
+			log.Debugf("GetBlockChainInfo error: %v", err)
+		}
+		return bci
+	}
+
+	var bcInfo *chainjson.GetBlockChainInfoResult
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+		if bcInfo = getInfo(); bcInfo == nil {
 			select {
-			case <-timer.C:
-				if !sendSyncUpdate() {
-					delay = time.Second * 30
-				}
-			case <-eco.innerCtx.Done():
-				timer.Stop()
+			case <-time.After(time.Second):
+				continue
+			case <-ctx.Done():
 				return
 			}
 		}
-	}()
-	return nil
-}
+		break
+	}
+	startHeight := bcInfo.Blocks
+	syncing := bcInfo.InitialBlockDownload || bcInfo.SyncHeight-startHeight > 1
+	var rate syncRateEstimator
+	var rateStage string
 
-func (eco *Eco) signalDCRDSynced() {
-	if atomic.CompareAndSwapUint32(&dcrdSyncedOnce, 0, 1) {
-		close(eco.dcrdSynced)
+	sendSyncUpdate := func() (synced bool) {
+		if bcInfo = getInfo(); bcInfo == nil {
+			return
+		}
+		h := bcInfo.SyncHeight
+		if bcInfo.Headers > h {
+			h = bcInfo.Headers
+		}
+		toGo := h - bcInfo.Blocks
+		syncing = bcInfo.InitialBlockDownload || toGo > 1
+		if !syncing {
+			ready()
+			eco.sendSyncUpdate(&Progress{
+				Service:      dcrd,
+				Stage:        SyncStageSynced,
+				Status:       "Fully synced",
+				Progress:     1.0,
+				Height:       bcInfo.Blocks,
+				TargetHeight: h,
+			})
+			return true
+		}
+		u := &Progress{
+			Service: dcrd,
+			Stage:   SyncStageBlocks,
+			Status:  fmt.Sprintf("Syncing blockchain at block %d", bcInfo.Blocks),
+		}
+		if span := h - startHeight; span > 0 {
+			u.Progress = 1 - float32(toGo)/float32(span)
+		}
+		height, target := bcInfo.Blocks, h
+		// dcrd syncs headers before blocks.
+		if bcInfo.Headers < bcInfo.SyncHeight {
+			u.Stage = SyncStageHeaders
+			u.Status = fmt.Sprintf("Syncing headers at block %d", bcInfo.Headers)
+			u.Progress = 0
+			height, target = bcInfo.Headers, bcInfo.SyncHeight
+		}
+		if u.Stage != rateStage {
+			rate.reset()
+			rateStage = u.Stage
+		}
+		rate.update(u, height, target, time.Now())
+		eco.sendSyncUpdate(u)
+		return
 	}
-}
 
-func (eco *Eco) signalDCRWalletRunning() {
-	if atomic.CompareAndSwapUint32(&dcrwalletRunningOnce, 0, 1) {
-		close(eco.dcrwalletReady)
+	sendSyncUpdate()
+
+	delay := time.Second * 5
+	for {
+		timer := time.NewTimer(delay)
+		delay = time.Second * 5
+		select {
+		case <-timer.C:
+			if !sendSyncUpdate() {
+				delay = time.Second * 30
+			}
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
 	}
 }
 
 func (eco *Eco) runDCRWallet() error {
-	eco.stateMtx.Lock()
-	defer eco.stateMtx.Unlock()
-
-	if !atomic.CompareAndSwapUint32(&dcrWalletRunning, 0, 1) {
-		return fmt.Errorf("dcrwallet already running")
-	}
-
-	eco.sendServiceStatus(&ServiceStatus{
-		Service: dcrwallet,
-		On:      true,
-	})
-
+	eco.stateMtx.RLock()
 	// We use the same rpc name and pass and debug level for dcrd and dcrwallet.
-	userSettings := &eco.dcrd.UserSettings
-
-	args := []string{
+	userSettings := eco.dcrd.UserSettings
+	network := eco.state.Eco.Network
+	ports := eco.portsLocked()
+	args := append([]string{
 		fmt.Sprintf("--appdata=\"%s\"", dcrwalletAppDir),
 		fmt.Sprintf("--debuglevel=%s", userSettings.DebugLevel),
-		fmt.Sprintf("--rpclisten=%s", dcrWalletRPCListen),
+		fmt.Sprintf("--rpclisten=%s", ports.DCRWalletRPCListen),
 		fmt.Sprintf("--username=%s", eco.dcrd.RPCUser),
 		fmt.Sprintf("--password=%s", eco.dcrd.RPCPass),
-		fmt.Sprintf("--rpcconnect=127.0.0.1%s", dcrdRPCListen),
 		fmt.Sprintf("--rpccert=\"%s\"", dcrWalletRPCCert),
 		fmt.Sprintf("--rpckey=\"%s\"", dcrWalletRPCKey),
 		"--nogrpc",
-		fmt.Sprintf("--cafile=\"%s\"", dcrdCertPath),
-	}
-
+	}, network.args()...)
 	spvMode := eco.state.Eco.SyncMode == SyncModeSPV
+	dcrdConn := eco.dcrdConnLocked()
+	proxy := eco.proxyLocked()
+	eco.stateMtx.RUnlock()
+
+	var deps []string
 	if spvMode {
 		args = append(args, "--spv")
-	}
-
-	extraInput := new(pwCache)
-	hasExtraInput, err := eco.db.FetchDecode(extraInputKey, extraInput)
-	if err != nil {
-		log.Errorf("DB error fetching extra input: %v", err)
-		return fmt.Errorf("DB error: %w", err)
-	}
-
-	// A goroutine to actually run the wallet.
-	go func() {
-		defer atomic.StoreUint32(&dcrWalletRunning, 0)
-		defer eco.sendServiceStatus(&ServiceStatus{
-			Service: dcrwallet,
-			On:      false,
-		})
-		if !spvMode {
-			select {
-			case <-eco.dcrdSynced:
-			case <-eco.outerCtx.Done():
-				return
-			}
-		}
-
-		var pwAdded bool
-		for {
-			var svcExe *serviceExe
-			if hasExtraInput && !pwAdded {
-				pw, err := extraInput.PW()
+		args = append(args, proxy.dcrWalletArgs("", false)...)
+	} else {
+		args = append(args, proxy.dcrWalletArgs(dcrdConn.host, dcrdConn.remote)...)
+		args = append(args,
+			fmt.Sprintf("--rpcconnect=%s", dcrdConn.host),
+			fmt.Sprintf("--cafile=\"%s\"", dcrdConn.certPath),
+			fmt.Sprintf("--dcrdusername=%s", dcrdConn.user),
+			fmt.Sprintf("--dcrdpassword=%s", dcrdConn.pass),
+		)
+		// dcrwallet isn't started until dcrd is synced.
+		deps = []string{dcrd}
+	}
+
+	pending := eco.pending()
+	firstStart := pending&PendingWalletStart != 0
+	restoring := pending&PendingWalletRestore != 0
+	if (firstStart || restoring) && !eco.session.unlocked() {
+		return errLocked
+	}
+
+	// dcrwallet's output is the only source of sync progress in SPV mode.
+	syncParser := newWalletSyncParser(network.chainParams())
+
+	// Until the initial sync begins, dcrwallet needs the passphrase at
+	// startup. The passphrase is delivered in a private configuration file,
+	// which is deleted as soon as dcrwallet is up or has exited.
+	passFile := new(walletPassFile)
+	return eco.sup.start(&serviceSpec{
+		name: dcrwallet,
+		deps: deps,
+		exe: func() (*serviceExe, error) {
+			// exe is called again for every restart, by which time the job
+			// may be done and the session cleared, so check every time.
+			needPass := eco.pending()&PendingWalletStart != 0
+			exeArgs := args
+			if needPass {
+				pw, err := eco.session.pw()
 				if err != nil {
-					log.Errorf("Error deserializing crypter: %v", err)
-					return
+					return nil, fmt.Errorf("Error getting wallet password: %w", err)
+				}
+				passArg, err := passFile.write(pw)
+				encode.ClearBytes(pw)
+				if err != nil {
+					return nil, err
+				}
+				exeArgs = append(append([]string(nil), args...), passArg)
+			}
+			svcExe := newExe(eco.innerCtx, eco.exePath(decred, dcrWalletExeName), exeArgs...)
+			lines := &lineWriter{f: func(line []byte) {
+				if u := syncParser.parseLine(string(line)); u != nil {
+					eco.sendSyncUpdate(u)
 				}
-				// I have tried getting this into stdin, but something about the
-				// way dcrwallet accepts input seems to be whacky. This is a
-				// security issue. Should be sure to at least lock the wallet
-				// below.
-				args = append(args, fmt.Sprintf("--pass=\"%s\"", string(pw)))
-				pwAdded = true
-				// Clear the password from the exe.Cmd in a minute.
+			}}
+			svcExe.feed = func(b []byte) { lines.Write(b) }
+			if needPass {
 				go func() {
-					select {
-					case <-time.After(60 * time.Second):
-						if svcExe != nil && svcExe.cmd != nil {
-							svcExe.cmd.Args = nil
-						}
-					}
+					<-svcExe.Done()
+					passFile.remove()
 				}()
 			}
-
-			// We might not have a version until initialized, so we can't create the
-			// command before here.
-			exe := filepath.Join(EcoDir, eco.state.Eco.Version, decred, dcrWalletExeName)
-			svcExe = newExe(eco.innerCtx, exe, args...)
-			eco.dcrwallet.exe = svcExe
-			svcExe.Run()
-			select {
-			case <-time.After(time.Second * 5):
-			case <-eco.outerCtx.Done():
-				return
-			}
-		}
-	}()
-
-	// A goroutine to establish a connection and set the client.
-	go func() {
-		var connectAttempts int
-
-		if !spvMode {
-			select {
-			case <-eco.dcrdSynced:
-			case <-eco.outerCtx.Done():
-				return
+			return svcExe, nil
+		},
+		connect: func() error {
+			// On initial startup, this may fail until the TLS keypair is
+			// generated, which is probably only once.
+			wcl, err := eco.dcrWalletClient()
+			if err != nil {
+				return err
 			}
-		}
-
-		// First, keep trying to get a client until successful. On initial
-		// startup, this may fail until the TLS keypair is generated, which
-		// is probably only once.
-
-		var wcl *walletclient.Client
-		// dcrdClient
-		for {
-			var err error
-			wcl, err = eco.dcrWalletClient()
-			if err == nil {
-				break
+			eco.stateMtx.Lock()
+			eco.dcrwallet.client = wcl
+			eco.stateMtx.Unlock()
+			return nil
+		},
+		disconnect: func() {
+			eco.stateMtx.Lock()
+			eco.dcrwallet.client = nil
+			eco.stateMtx.Unlock()
+		},
+		monitor: eco.dcrWalletMonitor(syncParser),
+		probe: func(ctx context.Context) error {
+			cl := eco.dcrWalletRPC()
+			if cl == nil {
+				return fmt.Errorf("dcrwallet not connected")
 			}
-			connectAttempts++
-			if connectAttempts >= 5 && connectAttempts%5 == 0 {
-				log.Errorf("Error getting dcrwallet RPC client: %v", err)
+			if _, err := cl.GetInfo(ctx); err != nil {
+				return err
 			}
-			select {
-			case <-time.After(time.Second * 5):
-			case <-eco.outerCtx.Done():
-				return
+			// dcrwallet has read its configuration.
+			passFile.remove()
+			return nil
+		},
+		shutdown: func(ctx context.Context) error {
+			cl := eco.dcrWalletRPC()
+			if cl == nil {
+				return fmt.Errorf("Cannot stop dcrwallet. No client found")
 			}
-		}
-		eco.stateMtx.Lock()
-		eco.dcrwallet.client = wcl
-		eco.stateMtx.Unlock()
+			return cl.Call(ctx, "stop", nil)
+		},
+	})
+}
 
-		defer func() {
-			eco.stateMtx.Lock()
-			eco.dcrwallet.client = nil
-			eco.stateMtx.Unlock()
-		}()
+// dcrWalletMonitor creates the monitor for dcrwallet, which sends wallet sync
+// updates in full mode, and restores a wallet created from an existing seed
+// once it's synced. dcrwallet is ready as soon as it is connected.
+func (eco *Eco) dcrWalletMonitor(syncParser *walletSyncParser) func(context.Context, func()) {
+	return func(ctx context.Context, ready func()) {
+		wcl := eco.dcrWalletRPC()
+		ready()
 
-		eco.signalDCRWalletRunning()
+		// The monitor is started again after a restart, when the restore may
+		// already be done.
+		restoring := eco.pending()&PendingWalletRestore != 0
 
 		getWalletInfo := func() *wallettypes.InfoWalletResult {
 			var err error
@@ -1009,7 +1526,7 @@ func (eco *Eco) runDCRWallet() error {
 
 		var walletInfo *wallettypes.InfoWalletResult
 		for {
-			if eco.outerCtx.Err() != nil {
+			if ctx.Err() != nil {
 				return
 			}
 			// dcrwallet will keep requesting the password for initial sync
@@ -1019,26 +1536,24 @@ func (eco *Eco) runDCRWallet() error {
 				select {
 				case <-time.After(time.Second):
 					continue
-				case <-eco.outerCtx.Done():
+				case <-ctx.Done():
 					return
 				}
 			}
 			break
 		}
 
-		// Delete the extraInput from the database, since it may contain
-		// a password.
-		if hasExtraInput {
-			eco.db.Store(extraInputKey, nil)
+		// dcrwallet doesn't need the password anymore.
+		if eco.pending()&PendingWalletStart != 0 {
+			eco.finishJob(PendingWalletStart)
 		}
 
-		// I guess just run a loop to keep checking the connection for now.
-		// Maybe should be checking the walletInfo.Blocks against dcrd's
-		// reported tip height for progress, but not sure what to do in SPV
-		// mode. I don't believe that dcrwallet offers any information via RPC
-		// on wallet sync status.
+		// Keep checking the connection. In full mode, progress is the
+		// walletInfo.Blocks against dcrd's reported tip height. In SPV mode,
+		// progress comes from the walletSyncParser.
 		delay := time.Second * 5
 		synced := false
+		var rate syncRateEstimator
 		for {
 			timer := time.NewTimer(delay)
 			delay = time.Second * 5
@@ -1053,12 +1568,23 @@ func (eco *Eco) runDCRWallet() error {
 				}
 				delay = time.Second * 30
 
+				if restoring && (synced || syncParser.synced()) {
+					if err := eco.restoreWallet(ctx, wcl); err != nil {
+						log.Errorf("Error restoring wallet: %v", err)
+						eco.sendSyncUpdate(&Progress{Service: dcrwallet, Err: "Wallet restore error"})
+					}
+					restoring = false
+					continue
+				}
+
 				eco.stateMtx.RLock()
 				cl := eco.dcrd.client
 				syncMode := eco.state.Eco.SyncMode
 				eco.stateMtx.RUnlock()
 
-				if syncMode != SyncModeFull || cl == nil {
+				// The wallet's block count doesn't reflect a rescan, so
+				// leave the updates to the output parser while it's busy.
+				if syncMode == SyncModeSPV || cl == nil || syncParser.syncing() {
 					continue
 				}
 				var err error
@@ -1077,31 +1603,32 @@ func (eco *Eco) runDCRWallet() error {
 					}
 
 					u.Status = "Syncing"
+					u.Stage = SyncStageBlocks
 					if h > 0 {
 						if int64(walletInfo.Blocks) >= h {
 							u.Progress = 1
+							u.Stage = SyncStageSynced
 							u.Status = "Fully Synced"
 							synced = true
 						} else {
 							u.Progress = float32(walletInfo.Blocks) / float32(h)
 						}
 					}
+					rate.update(u, int64(walletInfo.Blocks), h, time.Now())
 
 				}
 				eco.sendSyncUpdate(u)
 
-			case <-eco.outerCtx.Done():
+			case <-ctx.Done():
 				timer.Stop()
 				return
 			}
 		}
-	}()
-
-	return nil
+	}
 }
 
 func encodeToJSONFile(fp string, thing interface{}) error {
-	f, err := os.OpenFile(decreditonConfigPath, os.O_WRONLY, 0644)
+	f, err := os.OpenFile(fp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
 	if err != nil {
 		return fmt.Errorf("Error reading decrediton configuration file")
 	}
@@ -1115,53 +1642,47 @@ func (eco *Eco) runDecrediton() error {
 	// existing preferences, but overriding a few with command line args. On the
 	// other hand, if there is not a file, we should create one with some
 	// compatible defaults, e.g. dark mode to match Eco GUI.
+	network := eco.network()
+	netName, err := network.decreditonNetwork()
+	if err != nil {
+		return err
+	}
 	if !fileExists(decreditonConfigPath) {
-		err := encodeToJSONFile(decreditonConfigPath, defaultDecreditonConfig())
+		err := encodeToJSONFile(decreditonConfigPath, defaultDecreditonConfig(netName))
 		if err != nil {
 			return fmt.Errorf("Error writing Decrediton config file")
 		}
 	}
-
-	if !atomic.CompareAndSwapUint32(&decreditonRunning, 0, 1) {
-		return fmt.Errorf("Decrediton already running")
+	if err := writeDecreditonProxy(decreditonConfigPath, eco.proxy()); err != nil {
+		return err
 	}
 
-	eco.stateMtx.Lock()
-	defer eco.stateMtx.Unlock()
-
-	eco.sendServiceStatus(&ServiceStatus{
-		Service: decrediton,
-		On:      true,
-	})
-
+	eco.stateMtx.RLock()
+	dcrdConn := eco.dcrdConnLocked()
 	args := []string{
 		fmt.Sprintf("--advanced"),
-		fmt.Sprintf("--rpcuser=%s", eco.dcrd.RPCUser),
-		fmt.Sprintf("--rpcpass=%s", eco.dcrd.RPCPass),
-		fmt.Sprintf("--rpccert=%s", dcrdCertPath),
-		fmt.Sprintf("--rpcconnect=%s", "localhost"+dcrdRPCListen),
+		fmt.Sprintf("--rpcuser=%s", dcrdConn.user),
+		fmt.Sprintf("--rpcpass=%s", dcrdConn.pass),
+		fmt.Sprintf("--rpccert=%s", dcrdConn.certPath),
+		fmt.Sprintf("--rpcconnect=%s", dcrdConn.host),
 		fmt.Sprintf("--custombinpath=%s", filepath.Join(EcoDir, eco.state.Eco.Version, decred)),
 	}
+	// The network flag overrides the network in an existing configuration
+	// file.
+	args = append(args, network.args()...)
 
 	if eco.state.Eco.SyncMode == SyncModeSPV {
 		args = append(args, "--spv")
 	}
+	eco.stateMtx.RUnlock()
 
-	exe := filepath.Join(EcoDir, eco.state.Eco.Version, decrediton, decreditonExeName)
-
-	svcExe := newExe(eco.innerCtx, exe, args...)
-
-	go func() {
-		defer atomic.StoreUint32(&decreditonRunning, 0)
-		defer eco.sendServiceStatus(&ServiceStatus{
-			Service: decrediton,
-			On:      false,
-		})
-
-		svcExe.Run()
-	}()
-
-	return nil
+	return eco.sup.start(&serviceSpec{
+		name:      decrediton,
+		noRestart: true,
+		exe: func() (*serviceExe, error) {
+			return newExe(eco.innerCtx, eco.exePath(decrediton, decreditonExeName), args...), nil
+		},
+	})
 }
 
 type dexNewWalletForm struct {
@@ -1172,58 +1693,38 @@ type dexNewWalletForm struct {
 }
 
 func (eco *Eco) runDEX() error {
-	select {
-	case <-eco.dcrwalletReady:
-	case <-eco.outerCtx.Done():
-		return fmt.Errorf("context canceled")
-	}
-
-	eco.stateMtx.Lock()
-	defer eco.stateMtx.Unlock()
-
+	eco.stateMtx.RLock()
 	rpcUser, rpcPass := eco.dcrd.RPCUser, eco.dcrd.RPCPass
+	syncMode := eco.state.Eco.SyncMode
+	network := eco.state.Eco.Network
+	eco.stateMtx.RUnlock()
+	ports := eco.ports()
 
-	dexInput := new(pwCache)
-	initializing, err := eco.db.FetchDecode(dexInputKey, dexInput)
-	if err != nil {
-		return fmt.Errorf("Error loading dex input: %v", err)
+	initializing := eco.pending()&PendingDEXInit != 0
+	if initializing && !eco.session.unlocked() {
+		return errLocked
 	}
 
-	// Allow initialization in SPV so that we can delete the cached credentials
-	// from the DB.
-	if !initializing && eco.state.Eco.SyncMode == SyncModeSPV {
+	// Allow initialization in SPV so that the pending job is finished.
+	if !initializing && syncMode == SyncModeSPV {
 		return fmt.Errorf("Cannot run DEX in SPV mode")
 	}
 
-	if !atomic.CompareAndSwapUint32(&dexRunning, 0, 1) {
-		return fmt.Errorf("DEX already running")
-	}
-
-	args := []string{
+	args := append([]string{
 		fmt.Sprintf("--appdata=\"%s\"", dexAppDir),
-		fmt.Sprintf("--webaddr=%s", "localhost"+dexWebAddr),
-	}
-
-	exe := filepath.Join(EcoDir, eco.state.Eco.Version, dexc, dexcExeName)
-
-	svcExe := newExe(eco.innerCtx, exe, args...)
-
-	go func() {
-		defer atomic.StoreUint32(&dexRunning, 0)
-		svcExe.Run()
-	}()
+		fmt.Sprintf("--webaddr=%s", "localhost"+ports.DEXWebAddr),
+	}, network.args()...)
+	args = append(args, eco.proxy().dexArgs()...)
 
 	initialize := func() error {
 		// First, try to create a new wallet account.
-		eco.stateMtx.RLock()
-		cl := eco.dcrwallet.client
-		eco.stateMtx.RUnlock()
+		cl := eco.dcrWalletRPC()
 		if cl == nil {
 			return fmt.Errorf("Cannot initialize DEX: No dcrwallet rpc client found")
 		}
-		pwb, err := dexInput.PW()
+		pwb, err := eco.session.pw()
 		if err != nil {
-			return fmt.Errorf("Error getting credentials from cache: %w", err)
+			return fmt.Errorf("Error getting password: %w", err)
 		}
 		pw := encode.PassBytes(pwb)
 		defer pw.Clear()
@@ -1254,7 +1755,7 @@ func (eco *Eco) runDEX() error {
 				Wallet *struct{} `json:"wallet"`
 			} `json:"assets"`
 		}{}
-		resp, err := http.Get("http://localhost" + dexWebAddr + "/api/user")
+		resp, err := http.Get("http://localhost" + ports.DEXWebAddr + "/api/user")
 		if err != nil {
 			return err
 		}
@@ -1270,7 +1771,7 @@ func (eco *Eco) runDEX() error {
 			Pass: pw,
 		}
 
-		request := dexCaller()
+		request := dexCaller(ports.DEXWebAddr)
 
 		if !user.Initialized {
 			_, err := request(eco.outerCtx, "init", pwMsg)
@@ -1293,7 +1794,7 @@ func (eco *Eco) runDEX() error {
 					"account":   dexAcctName,
 					"username":  rpcUser,
 					"password":  rpcPass,
-					"rpclisten": "127.0.0.1" + dcrWalletRPCListen,
+					"rpclisten": "127.0.0.1" + ports.DCRWalletRPCListen,
 					"rpccert":   dcrWalletRPCCert,
 				},
 				Pass:  pw,
@@ -1307,45 +1808,58 @@ func (eco *Eco) runDEX() error {
 		return nil
 	}
 
-	// If we need to initialize, run a second goroutine to attempt initial
-	// setup.
+	// If we need to initialize, the monitor attempts initial setup.
+	var monitor func(context.Context, func())
 	if initializing {
-		go func() {
+		monitor = func(ctx context.Context, ready func()) {
 			for {
 				err := initialize()
 				if err == nil {
-					eco.db.Store(dexInputKey, nil)
-					// Send a ServiceStatus to trigger DEX service availability.
-					eco.sendServiceStatus(&ServiceStatus{
-						Service: dexc,
-						On:      false,
-					})
-
-					// Kill dexc. The service has not even been available until
-					// the serviceExe.Run goroutine exits, so the user does
-					// not expect it to be running. They can now manually
-					// start dexc.
-
-					// Need a cleaner way to do this though.
-					svcExe.cancel()
-					break
+					eco.finishJob(PendingDEXInit)
+					// Stop dexc. The service has not even been available until
+					// now, so the user does not expect it to be running. They
+					// can now manually start dexc. The monitor can't wait for
+					// the service to stop.
+					go func() {
+						if err := eco.sup.stop(dexc); err != nil {
+							log.Errorf("Error stopping dexc after initialization: %v", err)
+						}
+					}()
+					return
 				}
 				log.Error(err)
 				select {
 				case <-time.After(time.Second * 5):
-				case <-eco.outerCtx.Done():
+				case <-ctx.Done():
 					return
 				}
 			}
-
-		}()
+		}
 	}
 
-	return nil
+	return eco.sup.start(&serviceSpec{
+		name: dexc,
+		deps: []string{dcrwallet},
+		exe: func() (*serviceExe, error) {
+			return newExe(eco.innerCtx, eco.exePath(dexc, dexcExeName), args...), nil
+		},
+		monitor: monitor,
+		probe: func(ctx context.Context) error {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://localhost"+ports.DEXWebAddr+"/api/user", nil)
+			if err != nil {
+				return err
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				return err
+			}
+			return resp.Body.Close()
+		},
+	})
 }
 
 func (eco *Eco) openDEXWindow() error {
-	if atomic.LoadUint32(&dexRunning) == 0 {
+	if !eco.sup.running(dexc) {
 		err := eco.runDEX()
 		if err != nil {
 			return fmt.Errorf("Error starting DEX: %w", err)
@@ -1359,24 +1873,25 @@ func (eco *Eco) openDEXWindow() error {
 	if !found {
 		return fmt.Errorf("Failed to locate chromium-based browser")
 	}
+	dexWebAddr := eco.ports().DEXWebAddr
+	// Copy the flags, which are shared, before adding to them.
+	args = append(append([]string(nil), args...), "--app=http://localhost"+dexWebAddr)
 
 	// Or should we allow opening multiple windows?
 	if !atomic.CompareAndSwapUint32(&dexWindowOpen, 0, 1) {
 		return fmt.Errorf("DEX window already open")
 	}
 
-	defer eco.sendServiceStatus(&ServiceStatus{
-		Service: dexc,
-		On:      true,
-	})
-
 	// Keep pinging DEX until a connection is made, before opening the window.
 	go func() {
 		defer atomic.StoreUint32(&dexWindowOpen, 0)
-		defer eco.sendServiceStatus(&ServiceStatus{
-			Service: dexc,
-			On:      false,
-		})
+
+		// dexc isn't started until dcrwallet is ready.
+		select {
+		case <-eco.sup.readyChan(dcrwallet):
+		case <-eco.outerCtx.Done():
+			return
+		}
 
 		var connectAttempts int
 		for {
@@ -1403,6 +1918,7 @@ func (eco *Eco) openDEXWindow() error {
 	}()
 	return nil
 }
+
 func extractMethod(cmd string) string {
 	for _, token := range strings.Split(cmd, " ") {
 		if token != "" {
@@ -1436,22 +1952,25 @@ func (eco *Eco) dcrctl(req *dcrCtlRequest) (*dcrCtlResponse, error) {
 	// Call/RawRequest methods, but we have no idea how to type the args.
 	eco.stateMtx.RLock()
 	version := eco.state.Eco.Version
+	network := eco.state.Eco.Network
 	rpcUser, rpcPass := eco.dcrd.RPCUser, eco.dcrd.RPCPass
+	dcrdConn := eco.dcrdConnLocked()
 	eco.stateMtx.RUnlock()
 	if version == "" {
 		return nil, fmt.Errorf("eco not initialized")
 	}
 
-	preArgs := []string{
+	ports := eco.ports()
+	preArgs := append([]string{
 		fmt.Sprintf("--rpcuser=%s", rpcUser),
 		fmt.Sprintf("--rpcpass=%s", rpcPass),
-	}
+	}, network.args()...)
 
 	exe := filepath.Join(EcoDir, version, decred, dcrctl)
 	var op []byte
 	eco.runContext(time.Second*60, func(ctx context.Context) {
 		args := preArgs
-		args = append(args, fmt.Sprintf("--rpcserver=127.0.0.1%s", dcrWalletRPCListen))
+		args = append(args, fmt.Sprintf("--rpcserver=127.0.0.1%s", ports.DCRWalletRPCListen))
 		args = append(args, fmt.Sprintf("--rpccert=\"%s\"", dcrWalletRPCCert))
 		args = append(args, "--wallet")
 		args = append(args, tokens...)
@@ -1465,9 +1984,12 @@ func (eco *Eco) dcrctl(req *dcrCtlRequest) (*dcrCtlResponse, error) {
 
 	// Try dcrd then.
 	eco.runContext(time.Second*60, func(ctx context.Context) {
-		args := preArgs
-		args = append(args, fmt.Sprintf("--rpcserver=127.0.0.1%s", dcrdRPCListen))
-		args = append(args, fmt.Sprintf("--rpccert=\"%s\"", dcrdCertPath))
+		args := append([]string{
+			fmt.Sprintf("--rpcuser=%s", dcrdConn.user),
+			fmt.Sprintf("--rpcpass=%s", dcrdConn.pass),
+		}, network.args()...)
+		args = append(args, fmt.Sprintf("--rpcserver=%s", dcrdConn.host))
+		args = append(args, fmt.Sprintf("--rpccert=\"%s\"", dcrdConn.certPath))
 		args = append(args, req.Cmd)
 		cmd := exec.CommandContext(ctx, exe, args...)
 		cmd.Dir = filepath.Dir(exe)
@@ -1480,38 +2002,6 @@ func (eco *Eco) dcrctl(req *dcrCtlRequest) (*dcrCtlResponse, error) {
 
 }
 
-func fetchAsset(ctx context.Context, dir string, url, name string) (string, error) {
-	tgt := filepath.Join(dir, name)
-	payload, err := os.OpenFile(tgt, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
-	if err != nil {
-		return "", fmt.Errorf("Error creating file: %w", err)
-	}
-	defer payload.Close()
-
-	// From github API docs...
-	// > To download the asset's binary content, set the "Accept" header of the
-	//   request to "application/octet-stream"
-	client := &http.Client{}
-	log.Infof("Fetching %q to %q", url, tgt)
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return "", fmt.Errorf("Error preparing request: %w", err)
-	}
-	req.Header.Set("Accept", "application/octet-stream")
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("Request error for %q %w", url, err)
-	}
-
-	defer resp.Body.Close()
-
-	_, err = io.Copy(payload, resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("Error saving archive to file: %v", err)
-	}
-	return payload.Name(), nil
-}
-
 func parseAssets(release *githubRelease) (*releaseAssets, error) {
 	// Find all the manifest files
 	assets := &releaseAssets{version: release.Name}
@@ -1520,6 +2010,14 @@ func parseAssets(release *githubRelease) (*releaseAssets, error) {
 			assets.manifests = append(assets.manifests, &releaseAsset{githubAsset: asset})
 		}
 	}
+	for _, m := range assets.manifests {
+		for _, asset := range release.Assets {
+			if asset.Name == m.Name+signatureSuffix {
+				m.sig = asset
+				break
+			}
+		}
+	}
 
 	findAsset := func(re *regexp.Regexp) *releaseAsset {
 		for _, asset := range release.Assets {
@@ -1609,7 +2107,42 @@ func State(ctx context.Context) (state *MetaState, err error) {
 	return
 }
 
-func Init(ctx context.Context, pw string, syncMode SyncMode) (<-chan *Progress, error) {
+// Init initializes Eco, installing the newest release and creating a wallet
+// on the network. If seed is not empty, the wallet is restored from the seed,
+// given as hex or as 33 PGP word list words. The restored wallet's addresses
+// are discovered and rescanned once it's synced, with progress reported in
+// dcrwallet's sync updates.
+func Init(ctx context.Context, pw string, syncMode SyncMode, network Network, seed string) (<-chan *Progress, error) {
+	return progressFeed(ctx, routeInit, &initRequest{
+		SyncMode: syncMode,
+		Network:  network,
+		PW:       []byte(pw),
+		Seed:     []byte(seed),
+	})
+}
+
+// InitRemote is Init for SyncModeRemote, using the dcrd described by remote
+// in place of the bundled dcrd.
+func InitRemote(ctx context.Context, pw string, network Network, remote *RemoteDCRD, seed string) (<-chan *Progress, error) {
+	return progressFeed(ctx, routeInit, &initRequest{
+		SyncMode: SyncModeRemote,
+		Network:  network,
+		PW:       []byte(pw),
+		Remote:   remote,
+		Seed:     []byte(seed),
+	})
+}
+
+// Upgrade upgrades an initialized Eco to the newest release. Progress is
+// reported on the returned channel until Progress = 1 or an error is
+// encountered.
+func Upgrade(ctx context.Context) (<-chan *Progress, error) {
+	return progressFeed(ctx, routeUpgrade, struct{}{})
+}
+
+// progressFeed makes the request and relays the Progress updates from the
+// Eco server.
+func progressFeed(ctx context.Context, route string, req interface{}) (<-chan *Progress, error) {
 	ch := make(chan *Progress, 1)
 	send := func(u *Progress) bool {
 		select {
@@ -1621,24 +2154,21 @@ func Init(ctx context.Context, pw string, syncMode SyncMode) (<-chan *Progress,
 	}
 
 	go func() {
-		err := genericFeed(ctx, routeInit, &initRequest{
-			SyncMode: syncMode,
-			PW:       []byte(pw),
-		}, func(ok bool, b []byte) bool {
+		err := genericFeed(ctx, route, req, func(ok bool, b []byte) bool {
 			if !ok {
 				send(&Progress{
-					Service: routeInit,
-					Err:     fmt.Sprintf("%s channel closed", routeInit),
+					Service: route,
+					Err:     fmt.Sprintf("%s channel closed", route),
 				})
 				return false
 			}
 			u := new(Progress)
 			err := encode.GobDecode(b, u)
 			if err != nil {
-				log.Errorf("Error decoding %s progress update: %v", routeInit, err)
+				log.Errorf("Error decoding %s progress update: %v", route, err)
 				send(&Progress{
-					Service: routeInit,
-					Err:     fmt.Sprintf("%s update decode error", routeInit),
+					Service: route,
+					Err:     fmt.Sprintf("%s update decode error", route),
 				})
 				return false
 			}
@@ -1648,7 +2178,7 @@ func Init(ctx context.Context, pw string, syncMode SyncMode) (<-chan *Progress,
 			return true
 		})
 		if err != nil {
-			log.Errorf("Init feed error: %v", err)
+			log.Errorf("%s feed error: %v", route, err)
 		}
 	}()
 
@@ -1658,6 +2188,7 @@ func Init(ctx context.Context, pw string, syncMode SyncMode) (<-chan *Progress,
 type EcoFeeders struct {
 	SyncStatus    func(*Progress)
 	ServiceStatus func(*ServiceStatus)
+	Notification  func(*Notification)
 }
 
 type FeedMessageType uint16
@@ -1666,12 +2197,14 @@ const (
 	MsgTypeInvalid FeedMessageType = iota
 	MsgTypeSyncStatusUpdate
 	MsgTypeServiceStatus
+	MsgTypeNotification
 )
 
 var feedMsgStrings = []string{
 	"MsgTypeInvalid",
 	"MsgTypeSyncStatusUpdate",
 	"MsgTypeServiceStatus",
+	"MsgTypeNotification",
 }
 
 func (i FeedMessageType) String() string {
@@ -1729,6 +2262,17 @@ func Feed(ctx context.Context, feeders *EcoFeeders) {
 					return false
 				}
 				feeders.ServiceStatus(u)
+			case MsgTypeNotification:
+				if feeders.Notification == nil {
+					break
+				}
+				u := new(Notification)
+				err := encode.GobDecode(msg.Contents, u)
+				if err != nil {
+					log.Errorf("Error decoding Notification: %v", err)
+					return false
+				}
+				feeders.Notification(u)
 			}
 			return true
 		})
@@ -1752,6 +2296,57 @@ func StartDEX(ctx context.Context) {
 	request(ctx, routeStartDEX, struct{}{}, nil)
 }
 
+type serviceRequest struct {
+	Service string
+}
+
+// StopService stops one of dcrd, dcrwallet, dexc, or decrediton. Any running
+// services that depend on it are stopped first.
+func StopService(ctx context.Context, svc string) error {
+	return errorRequest(ctx, routeStopService, &serviceRequest{
+		Service: svc,
+	})
+}
+
+// RestartService restarts one of dcrd, dcrwallet, dexc, or decrediton, along
+// with any running services that depend on it. A service that isn't running
+// is started.
+func RestartService(ctx context.Context, svc string) error {
+	return errorRequest(ctx, routeRestartService, &serviceRequest{
+		Service: svc,
+	})
+}
+
+type releaseChannelRequest struct {
+	Channel ReleaseChannel
+}
+
+// SetReleaseChannel sets the release channel used for initialization and
+// upgrades.
+func SetReleaseChannel(ctx context.Context, channel ReleaseChannel) error {
+	return errorRequest(ctx, routeSetReleaseChannel, &releaseChannelRequest{
+		Channel: channel,
+	})
+}
+
+// SetReleaseSource sets where Eco retrieves releases from during
+// initialization and upgrades.
+func SetReleaseSource(ctx context.Context, cfg *ReleaseSourceConfig) error {
+	return errorRequest(ctx, routeSetReleaseSource, cfg)
+}
+
+type setVersionRequest struct {
+	Version string
+}
+
+// SetVersion switches Eco to one of the known-good versions listed in
+// EcoState.GoodVersions.
+func SetVersion(ctx context.Context, version string) error {
+	return errorRequest(ctx, routeSetVersion, &setVersionRequest{
+		Version: version,
+	})
+}
+
 type dcrCtlRequest struct {
 	Cmd string
 }
@@ -1775,8 +2370,108 @@ func DCRCtl(ctx context.Context, cmd string) (string, error) {
 	return resp.Body, nil
 }
 
-func walletFileExists() bool {
-	return fileExists(filepath.Join(dcrwalletAppDir, "mainnet", "wallet.db"))
+// Storage gets a report of Eco's disk usage.
+func Storage(ctx context.Context) (*StorageReport, error) {
+	resp := new(storageResponse)
+	err := request(ctx, routeStorage, struct{}{}, resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Err != "" {
+		return nil, fmt.Errorf(resp.Err)
+	}
+	return resp.Report, nil
+}
+
+// PruneVersions deletes old version directories according to the retention.
+// The removed versions are returned, even if there is an error.
+func PruneVersions(ctx context.Context) ([]string, error) {
+	resp := new(pruneResponse)
+	err := request(ctx, routePruneVersions, struct{}{}, resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Err != "" {
+		return resp.Removed, fmt.Errorf(resp.Err)
+	}
+	return resp.Removed, nil
+}
+
+// SetVersionRetention sets the number of version directories kept when
+// pruning. Zero resets to the default.
+func SetVersionRetention(ctx context.Context, retention uint32) error {
+	return errorRequest(ctx, routeSetVersionRetention, &versionRetentionRequest{
+		Retention: retention,
+	})
+}
+
+// ClearCache deletes all files from the download cache.
+func ClearCache(ctx context.Context) error {
+	return errorRequest(ctx, routeClearCache, struct{}{})
+}
+
+type cacheLimitRequest struct {
+	Limit int64
+}
+
+// SetCacheLimit sets the download cache size limit, in bytes. Zero resets to
+// the default.
+func SetCacheLimit(ctx context.Context, limit int64) error {
+	return errorRequest(ctx, routeSetCacheLimit, &cacheLimitRequest{
+		Limit: limit,
+	})
+}
+
+// ServiceLogs gets the last n lines of output from the service.
+func ServiceLogs(ctx context.Context, svc string, n int) ([]string, error) {
+	resp := new(logsResponse)
+	err := request(ctx, routeLogs, &logsRequest{
+		Service: svc,
+		Lines:   n,
+	}, resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Err != "" {
+		return nil, fmt.Errorf(resp.Err)
+	}
+	return resp.Lines, nil
+}
+
+// FollowServiceLogs gets the last n lines of output from the service, and then
+// any new lines as they are written. f is called with each batch of lines.
+// FollowServiceLogs blocks until the Context is canceled or there is an error.
+func FollowServiceLogs(ctx context.Context, svc string, n int, f func([]string)) error {
+	var respErr error
+	err := genericFeed(ctx, routeLogs, &logsRequest{
+		Service: svc,
+		Lines:   n,
+		Follow:  true,
+	}, func(ok bool, b []byte) bool {
+		if !ok {
+			respErr = fmt.Errorf("%s log feed closed", svc)
+			return false
+		}
+		resp := new(logsResponse)
+		if err := encode.GobDecode(b, resp); err != nil {
+			respErr = fmt.Errorf("Error decoding log lines: %w", err)
+			return false
+		}
+		if resp.Err != "" {
+			respErr = fmt.Errorf(resp.Err)
+			return false
+		}
+		f(resp.Lines)
+		return true
+	})
+	if respErr != nil {
+		return respErr
+	}
+	return err
+}
+
+func walletFileExists(network Network) bool {
+	return fileExists(network.walletDBPath())
 }
 
 func genericFeed(ctx context.Context, route string, req interface{}, f func(bool, []byte) bool) error {
@@ -1813,7 +2508,7 @@ func statusKey(svc string) string {
 	return "status#" + svc
 }
 
-func dexCaller() func(context.Context, string, interface{}) ([]byte, error) {
+func dexCaller(dexWebAddr string) func(context.Context, string, interface{}) ([]byte, error) {
 	cj, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
 	if err != nil {
 		panic("could not create cookie jar")
@@ -1856,8 +2551,3 @@ func dexCaller() func(context.Context, string, interface{}) ([]byte, error) {
 		return b, nil
 	}
 }
-
-func dirtyEncode(thing interface{}) string {
-	b, _ := json.Marshal(thing)
-	return string(b)
-}
@@ -4,7 +4,6 @@ package eco
 
 import (
 	"archive/tar"
-	"compress/gzip"
 	"context"
 	"fmt"
 	"io"
@@ -42,75 +41,6 @@ var (
 	}
 )
 
-func unpack(archivePath string) (string, error) {
-	archive, err := os.Open(archivePath)
-	if err != nil {
-		return "", fmt.Errorf("Error opening archive: %w", err)
-	}
-	if !strings.HasSuffix(archivePath, ".tar.gz") {
-		return "", fmt.Errorf("Expected tar.gz. Saw %q", archivePath)
-	}
-	dir := filepath.Dir(archivePath)
-
-	topDir, err := ExtractTarGz(dir, archive)
-	if err != nil {
-		return "", fmt.Errorf("Error extracting file: %v", err)
-	}
-	return filepath.Join(dir, topDir), nil
-}
-
-func ExtractTarGz(dir string, gzipStream io.Reader) (string, error) {
-	uncompressedStream, err := gzip.NewReader(gzipStream)
-	if err != nil {
-		return "", fmt.Errorf("ExtractTarGz: NewReader error: %w", err)
-	}
-
-	tarReader := tar.NewReader(uncompressedStream)
-	var topDir string
-
-	for true {
-		header, err := tarReader.Next()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return "", fmt.Errorf("ExtractTarGz: Next() failed: %w", err)
-		}
-
-		log.Infof("Extracting %s", header.Name)
-
-		subDir := filepath.Join(dir, filepath.Dir(header.Name))
-		if header.Typeflag == tar.TypeDir {
-			if topDir == "" {
-				topDir = header.Name
-			}
-			subDir = filepath.Join(dir, header.Name)
-		}
-		if err := os.MkdirAll(subDir, 0755); err != nil {
-			return "", fmt.Errorf("ExtractTarGz: MkdirAll() failed: %w", err)
-		}
-		if header.Typeflag == tar.TypeReg {
-			outFile, err := os.OpenFile(filepath.Join(dir, header.Name), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, guessPerms(header.Name))
-			if err != nil {
-				return "", fmt.Errorf("ExtractTarGz: OpenFile() failed: %w", err)
-			}
-			if _, err := io.Copy(outFile, tarReader); err != nil {
-				return "", fmt.Errorf("ExtractTarGz: Copy() failed: %w", err)
-			}
-			outFile.Close()
-		}
-	}
-	return topDir, nil
-}
-
-func guessPerms(path string) os.FileMode {
-	parts := strings.Split(filepath.Base(path), ".")
-	if len(parts) < 2 {
-		return 0755
-	}
-	return 0644
-}
-
 // Tar takes a source and variable writers and walks 'source' writing each file
 // found to the tar writer; the purpose for accepting multiple writers is to allow
 // for multiple outputs (for example a file, or md5 hash)
@@ -194,20 +124,28 @@ func moveDirectoryContents(fromDir, toDir string) error {
 	})
 }
 
-func moveResources(ctx context.Context, tmpDir string, assets *releaseAssets, hashes map[string][]byte, prog *progressReporter) error {
+func moveResources(ctx context.Context, src ReleaseSource, cache *downloadCache, tmpDir string, assets *releaseAssets, hashes map[string][]byte, prog *progressReporter) error {
 	versionDir := filepath.Join(EcoDir, assets.version)
 	err := os.MkdirAll(versionDir, 0755)
 	if err != nil {
 		return fmt.Errorf("error creating version directory: %w", err)
 	}
 
-	fetchMove := func(subDir string, asset *releaseAsset, prog *progressReporter) error {
-		err = fetchAndUnpack(ctx, tmpDir, asset, hashes, prog.subReporter(0, 0.9))
+	// Fetch and verify all of the archives before unpacking any of them.
+	archives := []*releaseAsset{assets.decred, assets.decrediton, assets.dexc}
+	err = fetchArchives(ctx, src, cache, tmpDir, archives, hashes, prog.subReporter(0, 0.75))
+	if err != nil {
+		return fmt.Errorf("Archive retrieval error: %w", err)
+	}
+
+	unpackMove := func(subDir string, asset *releaseAsset, prog *progressReporter) error {
+		prog.report(0, "Unpacking %s", asset.Name)
+		asset.path, err = unpack(asset.archive)
 		if err != nil {
-			return fmt.Errorf("Archive retrieval error: %w", err)
+			return fmt.Errorf("Error unpacking %q: %w", asset.Name, err)
 		}
 
-		prog.report(0.9, "Installing %s", asset.Name)
+		prog.report(0.6, "Installing %s", asset.Name)
 		tgt := filepath.Join(versionDir, subDir)
 		err = moveDirectoryContents(asset.path, tgt)
 		if err != nil {
@@ -216,12 +154,12 @@ func moveResources(ctx context.Context, tmpDir string, assets *releaseAssets, ha
 		return nil
 	}
 
-	err = fetchMove(decred, assets.decred, prog.subReporter(0.25, 0.60))
+	err = unpackMove(decred, assets.decred, prog.subReporter(0.75, 0.85))
 	if err != nil {
 		return fmt.Errorf("Decred program files: %w", err)
 	}
 
-	err = fetchMove(decrediton, assets.decrediton, prog.subReporter(0.60, 0.85))
+	err = unpackMove(decrediton, assets.decrediton, prog.subReporter(0.85, 0.95))
 	if err != nil {
 		return fmt.Errorf("Decrediton files: %w", err)
 	}
@@ -233,7 +171,7 @@ func moveResources(ctx context.Context, tmpDir string, assets *releaseAssets, ha
 		log.Errorf("Error removing Decrediton bin directory: %v", err)
 	}
 
-	err = fetchMove(dexc, assets.dexc, prog.subReporter(0.85, 1))
+	err = unpackMove(dexc, assets.dexc, prog.subReporter(0.95, 1))
 	if err != nil {
 		return fmt.Errorf("DEX files: %w", err)
 	}
@@ -241,36 +179,6 @@ func moveResources(ctx context.Context, tmpDir string, assets *releaseAssets, ha
 	return nil
 }
 
-// fetchAndUnpack unpacks the archive and sets the asset.path.
-func fetchAndUnpack(ctx context.Context, tmpDir string, asset *releaseAsset, hashes map[string][]byte, prog *progressReporter) error {
-	checkHash, found := hashes[asset.Name]
-	if !found {
-		return fmt.Errorf("No hash in manifest for %s", asset.Name)
-	}
-
-	// Fetch the main asset.
-	prog.report(0, "Fetching %s", asset.Name)
-	archivePath, err := fetchAsset(ctx, tmpDir, asset.URL, asset.Name)
-	if err != nil {
-		return fmt.Errorf("Error fetching %q: %w", asset.Name, err)
-	}
-
-	// Check the hash.
-	prog.report(0.73, "Validating %s", asset.Name)
-	err = checkFileHash(archivePath, checkHash)
-	if err != nil {
-		return fmt.Errorf("Failed file hash check: %w", err)
-	}
-
-	// Unpack.
-	prog.report(0.75, "Unpacking %s", asset.Name)
-	asset.path, err = unpack(archivePath)
-	if err != nil {
-		return fmt.Errorf("Error unpacking %q: %w", asset.Name, err)
-	}
-	return nil
-}
-
 var (
 	linuxCmds = [4]string{"chromium-browser", "brave-browser", "google-chrome"}
 )
@@ -3,7 +3,9 @@ package eco
 import (
 	"context"
 	"encoding/json"
+	"io"
 	"io/ioutil"
+	"net"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -11,6 +13,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/buck54321/eco/db"
+	"github.com/buck54321/eco/encode"
 	"github.com/decred/slog"
 )
 
@@ -24,16 +28,10 @@ func TestServer(t *testing.T) {
 	AppDir = tmpDir
 	KeyPath = filepath.Join(tmpDir, "decred-eco.key")
 	CertPath = filepath.Join(tmpDir, "decred-eco.cert")
-	serverAddress = &NetAddr{
-		Net:  "tcp4",
-		Addr: ":39079",
-	}
+	TokenPath = filepath.Join(tmpDir, "decred-eco.token")
 	dcrdState := dcrdNewState()
-	runTest := func() {
-		serverAddress = &NetAddr{
-			Net:  "tcp4",
-			Addr: ":39079",
-		}
+	runTest := func(addr *NetAddr) {
+		serverAddress = addr
 		srv, err := NewServer(&Eco{
 			dcrd: &DCRD{DCRDState: *dcrdState},
 		})
@@ -48,18 +46,23 @@ func TestServer(t *testing.T) {
 		if err != nil {
 			t.Fatalf("serviceStatus error: %v", err)
 		}
-		if reState.RPCPass != dcrdState.RPCPass {
+		if reState.RPCUser != dcrdState.RPCUser {
 			t.Fatalf("wrong AppDataDir decoded")
 		}
+		if reState.RPCPass != "" {
+			t.Fatalf("RPC password sent to the client")
+		}
 	}
-	runTest()
+	runTest(&NetAddr{
+		Net:  "tcp4",
+		Addr: ":39079",
+	})
 
 	if runtime.GOOS == "linux" {
-		serverAddress = &NetAddr{
+		runTest(&NetAddr{
 			Net:  "unix",
 			Addr: filepath.Join(tmpDir, UnixSocketFilename),
-		}
-		runTest()
+		})
 	}
 }
 
@@ -88,6 +91,11 @@ func TestParseAssets(t *testing.T) {
 	if len(assets.manifests) != 3 {
 		t.Fatalf("Manifest files not parsed")
 	}
+	for _, m := range assets.manifests {
+		if m.sig == nil || m.sig.Name != m.Name+signatureSuffix {
+			t.Fatalf("No signature found for manifest %s", m.Name)
+		}
+	}
 }
 
 var testRelease = []byte(`{
@@ -594,3 +602,77 @@ func TestFindChromiumBrowser(t *testing.T) {
 	}
 	t.Logf("chromium browser found %q", cmd)
 }
+
+func TestServiceStatusConcurrency(t *testing.T) {
+	eco := &Eco{
+		state:     MetaState{Services: map[string]*ServiceStatus{}},
+		syncChans: make(map[chan *FeedMessage]struct{}),
+		syncCache: make(map[string]*FeedMessage),
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			svc := []string{dcrd, dcrwallet, dexc}[i%3]
+			eco.sendServiceStatus(&ServiceStatus{Service: svc, On: true})
+			eco.sendSyncUpdate(&Progress{Service: svc, Progress: float32(i) / 200})
+		}
+	}()
+	for i := 0; i < 200; i++ {
+		if _, err := encode.GobEncode(eco.metaState()); err != nil {
+			t.Fatalf("GobEncode error: %v", err)
+		}
+	}
+	<-done
+	st := eco.metaState().Services[dexc]
+	if st == nil || st.Sync == nil {
+		t.Fatalf("Sync update not kept with the service status")
+	}
+}
+
+func TestInitEcoDeadlock(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(tmpDir)
+	dbb, err := db.NewDB(filepath.Join(tmpDir, "eco.db"), slog.Disabled)
+	if err != nil {
+		t.Fatalf("NewDB error: %v", err)
+	}
+	// A release with no usable assets gets initEco through the release
+	// source and the download cache, then fails.
+	releaseDir := filepath.Join(tmpDir, "releases")
+	os.MkdirAll(filepath.Join(releaseDir, "v1.6.0"), 0755)
+	ioutil.WriteFile(filepath.Join(releaseDir, "v1.6.0", "junk.txt"), []byte("junk"), 0644)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	eco := &Eco{
+		db:       dbb,
+		outerCtx: ctx,
+		innerCtx: ctx,
+		state: MetaState{Eco: EcoState{
+			ReleaseSource: ReleaseSourceConfig{
+				Type:     ReleaseSourceDirectory,
+				Location: releaseDir,
+			},
+		}},
+	}
+
+	cl, srv := net.Pipe()
+	defer cl.Close()
+	go io.Copy(ioutil.Discard, cl)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer srv.Close()
+		eco.initEco(srv, &initRequest{
+			SyncMode: SyncModeSPV,
+			Network:  NetworkTestnet,
+			PW:       []byte("abc"),
+		})
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second * 5):
+		t.Fatalf("initEco deadlocked")
+	}
+}
@@ -31,20 +31,28 @@ var (
 	//   }
 )
 
-func moveResources(ctx context.Context, tmpDir string, assets *releaseAssets, hashes map[string][]byte, prog *progressReporter) error {
+func moveResources(ctx context.Context, src ReleaseSource, cache *downloadCache, tmpDir string, assets *releaseAssets, hashes map[string][]byte, prog *progressReporter) error {
 	versionDir := filepath.Join(EcoDir, assets.version)
 	err := os.MkdirAll(versionDir, 0755)
 	if err != nil {
 		return fmt.Errorf("error creating version directory: %w", err)
 	}
 
-	fetchMove := func(subDir string, asset *releaseAsset, prog *progressReporter) error {
-		err = fetchAndUnpack(ctx, tmpDir, asset, hashes, prog.subReporter(0, 0.9))
+	// Fetch and verify all of the archives before unpacking any of them.
+	archives := []*releaseAsset{assets.decred, assets.decrediton, assets.dexc}
+	err = fetchArchives(ctx, src, cache, tmpDir, archives, hashes, prog.subReporter(0, 0.75))
+	if err != nil {
+		return fmt.Errorf("Archive retrieval error: %w", err)
+	}
+
+	unpackMove := func(subDir string, asset *releaseAsset, prog *progressReporter) error {
+		prog.report(0, "Unpacking %s", asset.Name)
+		asset.path, err = unpack(asset.archive)
 		if err != nil {
-			return fmt.Errorf("Archive retrieval error: %w", err)
+			return fmt.Errorf("Error unpacking %q: %w", asset.Name, err)
 		}
 
-		prog.report(0.9, "Installing %s", asset.Name)
+		prog.report(0.6, "Installing %s", asset.Name)
 		tgt := filepath.Join(versionDir, subDir)
 		err = moveDirectoryContents(asset.path, tgt)
 		if err != nil {
@@ -53,12 +61,12 @@ func moveResources(ctx context.Context, tmpDir string, assets *releaseAssets, ha
 		return nil
 	}
 
-	err = fetchMove(decred, assets.decred, prog.subReporter(0.25, 0.60))
+	err = unpackMove(decred, assets.decred, prog.subReporter(0.75, 0.85))
 	if err != nil {
 		return fmt.Errorf("Decred program files: %w", err)
 	}
 
-	err = fetchMove(decrediton, assets.decrediton, prog.subReporter(0.60, 0.85))
+	err = unpackMove(decrediton, assets.decrediton, prog.subReporter(0.85, 0.95))
 	if err != nil {
 		return fmt.Errorf("Decrediton files: %w", err)
 	}
@@ -70,7 +78,7 @@ func moveResources(ctx context.Context, tmpDir string, assets *releaseAssets, ha
 		log.Errorf("Error removing Decrediton bin directory: %v", err)
 	}
 
-	err = fetchMove(dexc, assets.dexc, prog.subReporter(0.85, 1))
+	err = unpackMove(dexc, assets.dexc, prog.subReporter(0.95, 1))
 	if err != nil {
 		return fmt.Errorf("DEX files: %w", err)
 	}
@@ -0,0 +1,405 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+// Package extract unpacks tar.gz and zip archives. Entries that would be
+// written outside of the target directory, including through symbolic links,
+// are rejected, and limits are enforced on the number and size of the
+// extracted files.
+package extract
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Limits are the limits enforced during extraction. A zero value for any limit
+// means no limit.
+type Limits struct {
+	// MaxFiles is the maximum number of entries in the archive.
+	MaxFiles int
+	// MaxFileSize is the maximum size of any one extracted file.
+	MaxFileSize int64
+	// MaxTotalSize is the maximum total size of the extracted files.
+	MaxTotalSize int64
+}
+
+// DefaultLimits are generous enough for any Decred release archive, but will
+// stop an archive bomb before it fills the disk.
+var DefaultLimits = &Limits{
+	MaxFiles:     100_000,
+	MaxFileSize:  2 << 30, // 2 GiB
+	MaxTotalSize: 4 << 30, // 4 GiB
+}
+
+var (
+	// ErrIllegalPath is returned for an entry that would be written outside of
+	// the target directory.
+	ErrIllegalPath = errors.New("illegal path")
+	// ErrLimitExceeded is returned when the archive exceeds the Limits.
+	ErrLimitExceeded = errors.New("limit exceeded")
+)
+
+// File extracts the tar.gz or zip archive at archivePath into dir, choosing
+// the format by the file extension. The top-level entries are returned.
+func File(archivePath, dir string, limits *Limits) ([]string, error) {
+	switch {
+	case strings.HasSuffix(archivePath, ".tar.gz"), strings.HasSuffix(archivePath, ".tgz"):
+		f, err := os.Open(archivePath)
+		if err != nil {
+			return nil, fmt.Errorf("Error opening archive: %w", err)
+		}
+		defer f.Close()
+		return TarGz(f, dir, limits)
+	case strings.HasSuffix(archivePath, ".zip"):
+		return Zip(archivePath, dir, limits)
+	}
+	return nil, fmt.Errorf("Unknown archive type %q", filepath.Base(archivePath))
+}
+
+// TarGz extracts the gzipped tar stream into dir. The top-level entries, i.e.
+// the first element of each entry's path, are returned in the order they
+// first appear. If limits is nil, the DefaultLimits are used.
+func TarGz(r io.Reader, dir string, limits *Limits) ([]string, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("gzip error: %w", err)
+	}
+	defer gz.Close()
+
+	x, err := newExtractor(dir, limits)
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("tar error: %w", err)
+		}
+		mode := os.FileMode(hdr.Mode).Perm()
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = x.mkdir(hdr.Name, mode)
+		case tar.TypeReg, tar.TypeRegA:
+			err = x.file(hdr.Name, mode, tr)
+		case tar.TypeSymlink:
+			err = x.symlink(hdr.Name, hdr.Linkname)
+		case tar.TypeLink:
+			err = x.hardlink(hdr.Name, hdr.Linkname)
+		default:
+			// Devices, fifos, and the like have no place in a release
+			// archive. Metadata entries, e.g. PAX headers, are handled by
+			// the tar.Reader.
+			err = fmt.Errorf("unsupported entry type %q for %s", hdr.Typeflag, hdr.Name)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return x.top, nil
+}
+
+// Zip extracts the zip archive at archivePath into dir. The top-level entries
+// are returned in the order they first appear. If limits is nil, the
+// DefaultLimits are used.
+func Zip(archivePath, dir string, limits *Limits) ([]string, error) {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("zip error: %w", err)
+	}
+	defer zr.Close()
+
+	x, err := newExtractor(dir, limits)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range zr.File {
+		if err := x.zipEntry(f); err != nil {
+			return nil, err
+		}
+	}
+	return x.top, nil
+}
+
+type extractor struct {
+	dir    string
+	limits Limits
+	files  int
+	total  int64
+	top    []string
+	topSet map[string]bool
+	// linkDirs are the paths that the extracted symbolic links' targets
+	// pass through. A symbolic link can't be created at one of them later.
+	linkDirs map[string]bool
+}
+
+func newExtractor(dir string, limits *Limits) (*extractor, error) {
+	if limits == nil {
+		limits = DefaultLimits
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("Error creating target directory: %w", err)
+	}
+	return &extractor{
+		dir:      dir,
+		limits:   *limits,
+		topSet:   make(map[string]bool),
+		linkDirs: make(map[string]bool),
+	}, nil
+}
+
+// target checks the entry name and returns the path it should be extracted
+// to. An error is returned if the path would be outside of the target
+// directory, including if any parent directory is a symbolic link.
+func (x *extractor) target(name string) (string, error) {
+	x.files++
+	if x.limits.MaxFiles > 0 && x.files > x.limits.MaxFiles {
+		return "", fmt.Errorf("%w: more than %d entries", ErrLimitExceeded, x.limits.MaxFiles)
+	}
+
+	clean, err := x.checkPath(name)
+	if err != nil {
+		return "", err
+	}
+
+	top := strings.Split(clean, string(filepath.Separator))[0]
+	if !x.topSet[top] {
+		x.topSet[top] = true
+		x.top = append(x.top, top)
+	}
+	return filepath.Join(x.dir, clean), nil
+}
+
+// checkPath cleans an archive path, which must be relative and stay inside the
+// target directory. No parent directory can be a symbolic link, else the path
+// could lead anywhere.
+func (x *extractor) checkPath(name string) (string, error) {
+	// Archive paths are always slash-separated.
+	clean := filepath.Clean(filepath.FromSlash(name))
+	if filepath.IsAbs(clean) || filepath.VolumeName(clean) != "" || clean == "." ||
+		clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %s", ErrIllegalPath, name)
+	}
+
+	parts := strings.Split(clean, string(filepath.Separator))
+	p := x.dir
+	for _, part := range parts[:len(parts)-1] {
+		p = filepath.Join(p, part)
+		fi, err := os.Lstat(p)
+		if err != nil {
+			if os.IsNotExist(err) {
+				break
+			}
+			return "", err
+		}
+		if fi.Mode()&os.ModeSymlink != 0 {
+			return "", fmt.Errorf("%w: %s is under symbolic link %s", ErrIllegalPath, name, p)
+		}
+	}
+	return clean, nil
+}
+
+// inside checks that the path is within the target directory.
+func (x *extractor) inside(path string) bool {
+	rel, err := filepath.Rel(x.dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func (x *extractor) mkdir(name string, mode os.FileMode) error {
+	path, err := x.target(name)
+	if err != nil {
+		return err
+	}
+	// Make sure we can always write to our own directories.
+	mode |= 0700
+	if err := os.MkdirAll(path, mode); err != nil {
+		return err
+	}
+	return os.Chmod(path, mode)
+}
+
+func (x *extractor) file(name string, mode os.FileMode, r io.Reader) error {
+	path, err := x.target(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	// Never write through an existing file or link. O_EXCL won't follow a
+	// symlink.
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode|0600)
+	if err != nil {
+		return err
+	}
+
+	// Read one past the limit to detect an oversized entry, since headers can
+	// lie about the size. A negative limit is no limit.
+	limit := int64(-1)
+	if x.limits.MaxFileSize > 0 {
+		limit = x.limits.MaxFileSize
+	}
+	if x.limits.MaxTotalSize > 0 {
+		if remain := x.limits.MaxTotalSize - x.total; limit < 0 || remain < limit {
+			limit = remain
+		}
+	}
+	var n int64
+	if limit >= 0 {
+		n, err = io.Copy(f, io.LimitReader(r, limit+1))
+	} else {
+		n, err = io.Copy(f, r)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("Error extracting %s: %w", name, err)
+	}
+	if limit >= 0 && n > limit {
+		return fmt.Errorf("%w: %s is too large", ErrLimitExceeded, name)
+	}
+	x.total += n
+	// The umask may have stripped permissions.
+	return os.Chmod(path, mode|0600)
+}
+
+// checkLink checks that a symbolic link at path to linkname resolves to
+// somewhere inside the target directory. The target is walked one component
+// at a time, and can't pass through another symbolic link, since a ".." after
+// a link is relative to where the link leads, not to where it is. For the
+// same reason, a link can't be created where an earlier link's target passes
+// through. Only the last component of a target can be a link.
+func (x *extractor) checkLink(path, linkname string) error {
+	if filepath.IsAbs(linkname) || filepath.VolumeName(linkname) != "" {
+		return fmt.Errorf("absolute target")
+	}
+	if x.linkDirs[path] {
+		return fmt.Errorf("another link's target passes through %s", path)
+	}
+	// Don't clean the target, which would remove a link followed by "..".
+	var parts []string
+	for _, part := range strings.Split(filepath.FromSlash(linkname), string(filepath.Separator)) {
+		if part != "" && part != "." {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+	p := filepath.Dir(path)
+	dirs := make([]string, 0, len(parts)-1)
+	for _, part := range parts[:len(parts)-1] {
+		p = filepath.Join(p, part)
+		if !x.inside(p) {
+			return fmt.Errorf("target outside of the directory")
+		}
+		fi, err := os.Lstat(p)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err == nil && fi.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("target passes through symbolic link %s", p)
+		}
+		dirs = append(dirs, p)
+	}
+	if !x.inside(filepath.Join(p, parts[len(parts)-1])) {
+		return fmt.Errorf("target outside of the directory")
+	}
+	for _, dir := range dirs {
+		x.linkDirs[dir] = true
+	}
+	return nil
+}
+
+func (x *extractor) symlink(name, linkname string) error {
+	path, err := x.target(name)
+	if err != nil {
+		return err
+	}
+	if err := x.checkLink(path, linkname); err != nil {
+		return fmt.Errorf("%w: symbolic link %s -> %s: %v", ErrIllegalPath, name, linkname, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Symlink(linkname, path)
+}
+
+func (x *extractor) hardlink(name, linkname string) error {
+	path, err := x.target(name)
+	if err != nil {
+		return err
+	}
+	// Hard link names are relative to the archive root, and must be a file
+	// that was already extracted. The source gets the same checks as an
+	// entry, so an extracted symlink can't lead it outside.
+	clean, err := x.checkPath(linkname)
+	if err != nil {
+		return fmt.Errorf("%w: hard link %s -> %s", ErrIllegalPath, name, linkname)
+	}
+	old := filepath.Join(x.dir, clean)
+	fi, err := os.Lstat(old)
+	if err != nil {
+		return fmt.Errorf("hard link %s -> %s: %w", name, linkname, err)
+	}
+	if !fi.Mode().IsRegular() {
+		return fmt.Errorf("%w: hard link %s to non-regular file %s", ErrIllegalPath, name, linkname)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Link(old, path)
+}
+
+func (x *extractor) zipEntry(f *zip.File) error {
+	mode := f.Mode()
+	switch {
+	case mode.IsDir():
+		return x.mkdir(f.Name, mode.Perm())
+	case mode&os.ModeSymlink != 0:
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		// The link target is the content of the entry.
+		b, err := ioutil.ReadAll(io.LimitReader(rc, 4096))
+		if err != nil {
+			return err
+		}
+		return x.symlink(f.Name, string(b))
+	case mode.IsRegular():
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		return x.file(f.Name, mode.Perm(), rc)
+	}
+	return fmt.Errorf("unsupported entry type %s for %s", mode.Type(), f.Name)
+}
@@ -0,0 +1,398 @@
+package extract
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type tEntry struct {
+	name     string
+	typ      byte
+	mode     int64
+	body     []byte
+	linkname string
+	// size overrides the header size, for lying headers.
+	size int64
+}
+
+func tDir(name string) *tEntry {
+	return &tEntry{name: name, typ: tar.TypeDir, mode: 0755}
+}
+
+func tFile(name string, mode int64, body []byte) *tEntry {
+	return &tEntry{name: name, typ: tar.TypeReg, mode: mode, body: body}
+}
+
+func tSymlink(name, linkname string) *tEntry {
+	return &tEntry{name: name, typ: tar.TypeSymlink, mode: 0777, linkname: linkname}
+}
+
+func tHardlink(name, linkname string) *tEntry {
+	return &tEntry{name: name, typ: tar.TypeLink, mode: 0644, linkname: linkname}
+}
+
+func makeTarGz(t *testing.T, entries ...*tEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		size := int64(len(e.body))
+		if e.size > 0 {
+			size = e.size
+		}
+		err := tw.WriteHeader(&tar.Header{
+			Name:     e.name,
+			Typeflag: e.typ,
+			Mode:     e.mode,
+			Size:     size,
+			Linkname: e.linkname,
+		})
+		if err != nil {
+			t.Fatalf("WriteHeader error: %v", err)
+		}
+		if len(e.body) > 0 {
+			if _, err := tw.Write(e.body); err != nil {
+				t.Fatalf("tar Write error: %v", err)
+			}
+		}
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+func makeZip(t *testing.T, dir string, entries ...*tEntry) string {
+	t.Helper()
+	path := filepath.Join(dir, "test.zip")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for _, e := range entries {
+		hdr := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		body := e.body
+		switch e.typ {
+		case tar.TypeDir:
+			hdr.SetMode(os.ModeDir | os.FileMode(e.mode))
+		case tar.TypeSymlink:
+			hdr.SetMode(os.ModeSymlink | os.FileMode(e.mode))
+			body = []byte(e.linkname)
+		default:
+			hdr.SetMode(os.FileMode(e.mode))
+		}
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatalf("CreateHeader error: %v", err)
+		}
+		if _, err := w.Write(body); err != nil {
+			t.Fatalf("zip Write error: %v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip Close error: %v", err)
+	}
+	return path
+}
+
+func checkTop(t *testing.T, top []string, exp ...string) {
+	t.Helper()
+	if len(top) != len(exp) {
+		t.Fatalf("Expected top-level entries %v, got %v", exp, top)
+	}
+	for i := range exp {
+		if top[i] != exp[i] {
+			t.Fatalf("Expected top-level entries %v, got %v", exp, top)
+		}
+	}
+}
+
+func checkPerm(t *testing.T, path string, exp os.FileMode) {
+	t.Helper()
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat error: %v", err)
+	}
+	if fi.Mode().Perm() != exp {
+		t.Fatalf("Wrong permissions for %s. Expected %s, got %s", path, exp, fi.Mode().Perm())
+	}
+}
+
+func TestTarGz(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(tmpDir)
+
+	b := makeTarGz(t,
+		tDir("dcrinstall/"),
+		tFile("dcrinstall/dcrd", 0755, []byte("dcrd")),
+		tFile("dcrinstall/sample.conf", 0644, []byte("conf")),
+		tFile("dcrinstall/suid", 04755, []byte("suid")),
+		tSymlink("dcrinstall/link", "dcrd"),
+		tHardlink("dcrinstall/hard", "dcrinstall/dcrd"),
+		tFile("README", 0644, []byte("readme")),
+	)
+	dir := filepath.Join(tmpDir, "out")
+	top, err := TarGz(bytes.NewReader(b), dir, nil)
+	if err != nil {
+		t.Fatalf("TarGz error: %v", err)
+	}
+	checkTop(t, top, "dcrinstall", "README")
+	checkPerm(t, filepath.Join(dir, "dcrinstall", "dcrd"), 0755)
+	checkPerm(t, filepath.Join(dir, "dcrinstall", "sample.conf"), 0644)
+	// Special bits are stripped.
+	checkPerm(t, filepath.Join(dir, "dcrinstall", "suid"), 0755)
+
+	for _, name := range []string{"link", "hard"} {
+		b, err := ioutil.ReadFile(filepath.Join(dir, "dcrinstall", name))
+		if err != nil {
+			t.Fatalf("Error reading %s: %v", name, err)
+		}
+		if string(b) != "dcrd" {
+			t.Fatalf("Wrong contents for %s: %q", name, string(b))
+		}
+	}
+
+	// Extracting again overwrites.
+	if _, err := TarGz(bytes.NewReader(b), dir, nil); err != nil {
+		t.Fatalf("TarGz error on second extraction: %v", err)
+	}
+}
+
+func TestTarGzIllegal(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(tmpDir)
+
+	outside := filepath.Join(tmpDir, "outside")
+	os.Mkdir(outside, 0755)
+	// secret is next to the target directories.
+	if err := ioutil.WriteFile(filepath.Join(tmpDir, "secret"), []byte("secret"), 0600); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		entries []*tEntry
+	}{
+		{
+			name:    "traversal",
+			entries: []*tEntry{tFile("../evil", 0644, []byte("evil"))},
+		},
+		{
+			name:    "nested traversal",
+			entries: []*tEntry{tFile("a/../../evil", 0644, []byte("evil"))},
+		},
+		{
+			name:    "absolute path",
+			entries: []*tEntry{tFile(filepath.Join(outside, "evil"), 0644, []byte("evil"))},
+		},
+		{
+			name:    "absolute symlink",
+			entries: []*tEntry{tSymlink("link", outside)},
+		},
+		{
+			name:    "relative symlink outside",
+			entries: []*tEntry{tSymlink("a/link", "../../outside")},
+		},
+		{
+			name: "write through symlink",
+			entries: []*tEntry{
+				tDir("a/"),
+				tSymlink("a/link", "."),
+				tFile("a/link/evil", 0644, []byte("evil")),
+			},
+		},
+		{
+			// a/b/u/../.. looks like a, but u leads to a, so it's a/.. on
+			// disk.
+			name: "symlink through symlink",
+			entries: []*tEntry{
+				tDir("a/b/"),
+				tSymlink("a/b/u", ".."),
+				tSymlink("x", "a/b/u/../.."),
+			},
+		},
+		{
+			// The same escape, with the inner link added after the outer.
+			name: "symlink through later symlink",
+			entries: []*tEntry{
+				tDir("a/b/"),
+				tSymlink("a/b/x", "d/../.."),
+				tSymlink("a/b/d", ".."),
+			},
+		},
+		{
+			name:    "hard link outside",
+			entries: []*tEntry{tHardlink("hard", "../outside/secret")},
+		},
+		{
+			// Each symlink looks like it stays inside, but s2/.. is the
+			// parent of the target directory.
+			name: "hard link through symlink",
+			entries: []*tEntry{
+				tSymlink("s2", "."),
+				tSymlink("s1", "s2/.."),
+				tHardlink("hard", "s1/secret"),
+			},
+		},
+		{
+			name: "hard link to directory",
+			entries: []*tEntry{
+				tDir("a/"),
+				tHardlink("hard", "a"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		dir := filepath.Join(tmpDir, "out-"+tt.name)
+		_, err := TarGz(bytes.NewReader(makeTarGz(t, tt.entries...)), dir, nil)
+		if !errors.Is(err, ErrIllegalPath) {
+			t.Fatalf("%s: Expected ErrIllegalPath, got %v", tt.name, err)
+		}
+	}
+
+	fis, _ := ioutil.ReadDir(outside)
+	if len(fis) != 0 {
+		t.Fatalf("Files written outside of the target directory")
+	}
+}
+
+func TestTarGzLimits(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(tmpDir)
+
+	limits := &Limits{
+		MaxFiles:     3,
+		MaxFileSize:  100,
+		MaxTotalSize: 150,
+	}
+
+	tests := []struct {
+		name    string
+		entries []*tEntry
+		ok      bool
+	}{
+		{
+			name: "within limits",
+			entries: []*tEntry{
+				tFile("a", 0644, make([]byte, 100)),
+				tFile("b", 0644, make([]byte, 50)),
+			},
+			ok: true,
+		},
+		{
+			name:    "file too large",
+			entries: []*tEntry{tFile("a", 0644, make([]byte, 101))},
+		},
+		{
+			name: "total too large",
+			entries: []*tEntry{
+				tFile("a", 0644, make([]byte, 100)),
+				tFile("b", 0644, make([]byte, 51)),
+			},
+		},
+		{
+			name: "too many files",
+			entries: []*tEntry{
+				tDir("a/"),
+				tFile("a/b", 0644, nil),
+				tFile("a/c", 0644, nil),
+				tFile("a/d", 0644, nil),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		dir := filepath.Join(tmpDir, "out-"+tt.name)
+		_, err := TarGz(bytes.NewReader(makeTarGz(t, tt.entries...)), dir, limits)
+		if tt.ok {
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", tt.name, err)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrLimitExceeded) {
+			t.Fatalf("%s: Expected ErrLimitExceeded, got %v", tt.name, err)
+		}
+	}
+}
+
+func TestZip(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(tmpDir)
+
+	archive := makeZip(t, tmpDir,
+		tDir("chrome-linux/"),
+		tFile("chrome-linux/chrome", 0755, []byte("chrome")),
+		tFile("chrome-linux/resources.pak", 0644, []byte("pak")),
+		tSymlink("chrome-linux/link", "chrome"),
+	)
+	dir := filepath.Join(tmpDir, "out")
+	top, err := File(archive, dir, nil)
+	if err != nil {
+		t.Fatalf("File error: %v", err)
+	}
+	checkTop(t, top, "chrome-linux")
+	checkPerm(t, filepath.Join(dir, "chrome-linux", "chrome"), 0755)
+	checkPerm(t, filepath.Join(dir, "chrome-linux", "resources.pak"), 0644)
+	b, err := ioutil.ReadFile(filepath.Join(dir, "chrome-linux", "link"))
+	if err != nil {
+		t.Fatalf("Error reading through symlink: %v", err)
+	}
+	if string(b) != "chrome" {
+		t.Fatalf("Wrong contents through symlink: %q", string(b))
+	}
+
+	for _, tt := range []struct {
+		name    string
+		entries []*tEntry
+		expErr  error
+	}{
+		{
+			name:    "traversal",
+			entries: []*tEntry{tFile("../evil", 0644, []byte("evil"))},
+			expErr:  ErrIllegalPath,
+		},
+		{
+			name:    "symlink outside",
+			entries: []*tEntry{tSymlink("link", "../../outside")},
+			expErr:  ErrIllegalPath,
+		},
+		{
+			name: "write through symlink",
+			entries: []*tEntry{
+				tSymlink("link", "."),
+				tFile("link/evil", 0644, []byte("evil")),
+			},
+			expErr: ErrIllegalPath,
+		},
+		{
+			name:    "file too large",
+			entries: []*tEntry{tFile("big", 0644, make([]byte, DefaultLimits.MaxFileSize/1024+1))},
+			expErr:  ErrLimitExceeded,
+		},
+	} {
+		zipDir := filepath.Join(tmpDir, tt.name)
+		os.Mkdir(zipDir, 0755)
+		archive := makeZip(t, zipDir, tt.entries...)
+		_, err := Zip(archive, filepath.Join(zipDir, "out"), &Limits{MaxFileSize: DefaultLimits.MaxFileSize / 1024})
+		if !errors.Is(err, tt.expErr) {
+			t.Fatalf("%s: Expected %v, got %v", tt.name, tt.expErr, err)
+		}
+	}
+}
+
+func TestFileUnknownType(t *testing.T) {
+	if _, err := File("archive.rar", "out", nil); err == nil {
+		t.Fatalf("No error for unknown archive type")
+	}
+}
@@ -18,6 +18,8 @@ var (
 	logRotator  *rotator.Rotator
 	log         = slog.Disabled
 	maxLogRolls = 16
+	// logDir is where the Eco and service logs are written.
+	logDir = filepath.Join(AppDir, "eco", "logs")
 )
 
 // logWriter implements an io.Writer that outputs to stdout
@@ -34,7 +36,6 @@ func (w logWriter) Write(p []byte) (n int, err error) {
 // create roll files in the same directory. initLogging must be called before
 // the package-global log rotator variables are used.
 func InitLogging(name string) slog.Logger {
-	logDir := filepath.Join(AppDir, "eco", "logs")
 	err := os.MkdirAll(logDir, 0700)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "failed to create log directory: %v\n", err)
@@ -58,9 +59,10 @@ func UseLogger(logger slog.Logger) {
 	log = logger
 }
 
-// closeFileLogger closes the log rotator.
+// closeFileLogger closes the log rotator and the service logs.
 func closeFileLogger() {
 	if logRotator != nil {
 		logRotator.Close()
 	}
+	closeServiceLogs()
 }
@@ -0,0 +1,110 @@
+package eco
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/decred/dcrd/chaincfg/v3"
+)
+
+// ServicePorts are the listen addresses for the services, e.g. ":19703".
+type ServicePorts struct {
+	DCRDRPCListen       string
+	DCRDListen          string
+	DCRWalletRPCListen  string
+	DCRWalletGRPCListen string
+	DEXWebAddr          string
+}
+
+// networkPorts are the default ports for each network. Each network has its
+// own ports, so a testnet stack never talks to a mainnet service left running
+// on the same machine.
+var networkPorts = map[Network]*ServicePorts{
+	NetworkMainnet: {
+		DCRDRPCListen:       ":19703",
+		DCRDListen:          ":19704",
+		DCRWalletRPCListen:  ":19705",
+		DCRWalletGRPCListen: ":19706",
+		DEXWebAddr:          ":26270",
+	},
+	NetworkTestnet: {
+		DCRDRPCListen:       ":19713",
+		DCRDListen:          ":19714",
+		DCRWalletRPCListen:  ":19715",
+		DCRWalletGRPCListen: ":19716",
+		DEXWebAddr:          ":26271",
+	},
+	NetworkSimnet: {
+		DCRDRPCListen:       ":19723",
+		DCRDListen:          ":19724",
+		DCRWalletRPCListen:  ":19725",
+		DCRWalletGRPCListen: ":19726",
+		DEXWebAddr:          ":26272",
+	},
+}
+
+func (n Network) valid() bool {
+	_, found := networkPorts[n]
+	return found
+}
+
+// defaultPorts are the network's default ports. Eco uses different ports if
+// these are taken. See allocatePorts.
+func (n Network) defaultPorts() ServicePorts {
+	if p, found := networkPorts[n]; found {
+		return *p
+	}
+	return *networkPorts[NetworkMainnet]
+}
+
+func (n Network) chainParams() *chaincfg.Params {
+	switch n {
+	case NetworkTestnet:
+		return chaincfg.TestNet3Params()
+	case NetworkSimnet:
+		return chaincfg.SimNetParams()
+	}
+	return chaincfg.MainNetParams()
+}
+
+// args are the command line arguments that select the network for dcrd,
+// dcrwallet, dcrctl and dexc. There are no arguments for mainnet.
+func (n Network) args() []string {
+	switch n {
+	case NetworkTestnet:
+		return []string{"--testnet"}
+	case NetworkSimnet:
+		return []string{"--simnet"}
+	}
+	return nil
+}
+
+// decreditonNetwork is the network name used in the Decrediton configuration
+// file. Decrediton does not support simnet.
+func (n Network) decreditonNetwork() (string, error) {
+	switch n {
+	case NetworkMainnet:
+		return "mainnet", nil
+	case NetworkTestnet:
+		return "testnet", nil
+	}
+	return "", fmt.Errorf("Decrediton does not support %s", n)
+}
+
+// HasDecrediton is true if Decrediton can run on the network. There's no
+// Decrediton for simnet.
+func (n Network) HasDecrediton() bool {
+	_, err := n.decreditonNetwork()
+	return err == nil
+}
+
+// walletDBPath is the path of the dcrwallet database for the network.
+func (n Network) walletDBPath() string {
+	return filepath.Join(dcrwalletAppDir, n.chainParams().Name, "wallet.db")
+}
+
+func (eco *Eco) network() Network {
+	eco.stateMtx.RLock()
+	defer eco.stateMtx.RUnlock()
+	return eco.state.Eco.Network
+}
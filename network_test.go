@@ -0,0 +1,54 @@
+package eco
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestNetworks(t *testing.T) {
+	networks := []Network{NetworkMainnet, NetworkTestnet, NetworkSimnet}
+
+	// No two networks can share a port.
+	seen := make(map[string]Network)
+	for _, n := range networks {
+		if !n.valid() {
+			t.Fatalf("%s not valid", n)
+		}
+		p := n.defaultPorts()
+		for _, addr := range p.addrs() {
+			if other, found := seen[*addr]; found {
+				t.Fatalf("%s and %s both use %s", other, n, *addr)
+			}
+			seen[*addr] = n
+		}
+	}
+	if Network(255).valid() {
+		t.Fatalf("Unknown network is valid")
+	}
+
+	if len(NetworkMainnet.args()) != 0 {
+		t.Fatalf("Unexpected mainnet args %v", NetworkMainnet.args())
+	}
+	if args := NetworkTestnet.args(); len(args) != 1 || args[0] != "--testnet" {
+		t.Fatalf("Wrong testnet args %v", args)
+	}
+	if args := NetworkSimnet.args(); len(args) != 1 || args[0] != "--simnet" {
+		t.Fatalf("Wrong simnet args %v", args)
+	}
+
+	// dcrwallet names the testnet directory after the chain.
+	expDBPath := filepath.Join(dcrwalletAppDir, "testnet3", "wallet.db")
+	if p := NetworkTestnet.walletDBPath(); p != expDBPath {
+		t.Fatalf("Wrong testnet wallet path. Expected %s, got %s", expDBPath, p)
+	}
+
+	if _, err := NetworkSimnet.decreditonNetwork(); err == nil {
+		t.Fatalf("No error for Decrediton on simnet")
+	}
+	if name, _ := NetworkTestnet.decreditonNetwork(); name != "testnet" {
+		t.Fatalf("Wrong Decrediton network name %q", name)
+	}
+	if NetworkSimnet.HasDecrediton() || !NetworkMainnet.HasDecrediton() {
+		t.Fatalf("Wrong Decrediton availability")
+	}
+}
@@ -0,0 +1,121 @@
+package eco
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// addrs are pointers to each of the listen addresses.
+func (p *ServicePorts) addrs() []*string {
+	return []*string{&p.DCRDRPCListen, &p.DCRDListen, &p.DCRWalletRPCListen, &p.DCRWalletGRPCListen, &p.DEXWebAddr}
+}
+
+// portFree checks whether the address can be listened on. The services bind
+// all interfaces, e.g. ":19703", so this is a conflict with any address
+// holding the port.
+func portFree(addr string) bool {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return false
+	}
+	l.Close()
+	return true
+}
+
+// randomFreePort asks the OS for a free port.
+func randomFreePort() (string, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	defer l.Close()
+	return fmt.Sprintf(":%d", l.Addr().(*net.TCPAddr).Port), nil
+}
+
+// allocatePorts checks each of the preferred ports, and replaces any that are
+// taken with a free port. The returned ServicePorts is always a new copy. The
+// names of the changed addresses are returned too.
+func allocatePorts(preferred ServicePorts, free func(string) bool, randomPort func() (string, error)) (*ServicePorts, []string, error) {
+	ports := preferred
+	names := []string{"dcrd RPC", "dcrd P2P", "dcrwallet RPC", "dcrwallet gRPC", "DEX web"}
+	used := make(map[string]bool)
+	var changed []string
+	for i, addr := range ports.addrs() {
+		if *addr != "" && !used[*addr] && free(*addr) {
+			used[*addr] = true
+			continue
+		}
+		// A random port could be handed out twice, since the probe listener
+		// is closed right away.
+		for tries := 0; ; tries++ {
+			if tries >= 10 {
+				return nil, nil, fmt.Errorf("Unable to find a free port for %s", names[i])
+			}
+			newAddr, err := randomPort()
+			if err != nil {
+				return nil, nil, fmt.Errorf("Error finding a free port for %s: %w", names[i], err)
+			}
+			if used[newAddr] || !free(newAddr) {
+				continue
+			}
+			log.Warnf("%s port %s is not available. Using %s", names[i], *addr, newAddr)
+			*addr = newAddr
+			used[newAddr] = true
+			changed = append(changed, names[i])
+			break
+		}
+	}
+	return &ports, changed, nil
+}
+
+// ports are the service ports. If ports haven't been allocated yet, they are
+// the network's defaults.
+func (eco *Eco) ports() ServicePorts {
+	eco.stateMtx.RLock()
+	defer eco.stateMtx.RUnlock()
+	return eco.portsLocked()
+}
+
+// portsLocked is ports for a caller that holds the stateMtx.
+func (eco *Eco) portsLocked() ServicePorts {
+	if p := eco.state.Eco.Ports; p != nil {
+		return *p
+	}
+	return eco.state.Eco.Network.defaultPorts()
+}
+
+// allocatePorts makes sure the service ports are free, falling back to free
+// ports for any that are taken, and saves the ports. allocatePorts must be
+// called with the services stopped, or they'll find their own ports taken.
+func (eco *Eco) allocatePorts() error {
+	eco.stateMtx.Lock()
+	oldPorts := eco.state.Eco.Ports
+	ports, changed, err := allocatePorts(eco.portsLocked(), portFree, randomFreePort)
+	if err != nil {
+		eco.stateMtx.Unlock()
+		return err
+	}
+	if oldPorts != nil && len(changed) == 0 {
+		eco.stateMtx.Unlock()
+		return nil
+	}
+	eco.state.Eco.Ports = ports
+	err = eco.saveEcoState()
+	eco.stateMtx.Unlock()
+	if err != nil {
+		return fmt.Errorf("Error saving ports: %w", err)
+	}
+
+	// dexc keeps its own copy of the dcrwallet address, which can only be
+	// changed with the DEX password.
+	if oldPorts != nil && oldPorts.DCRWalletRPCListen != ports.DCRWalletRPCListen {
+		eco.sendNotification("DEX wallet address changed",
+			fmt.Sprintf("The port for dcrwallet was taken, so dcrwallet is now at 127.0.0.1%s. "+
+				"Update the Decred wallet settings in the DEX to use the new address.", ports.DCRWalletRPCListen))
+	}
+	if len(changed) > 0 {
+		log.Infof("Changed ports: %s", strings.Join(changed, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package eco
+
+import (
+	"fmt"
+	"net"
+	"testing"
+)
+
+func TestAllocatePorts(t *testing.T) {
+	defaults := NetworkMainnet.defaultPorts()
+
+	// All free.
+	allFree := func(string) bool { return true }
+	var next int
+	randomPort := func() (string, error) {
+		next++
+		return fmt.Sprintf(":%d", 40000+next), nil
+	}
+	ports, changed, err := allocatePorts(defaults, allFree, randomPort)
+	if err != nil {
+		t.Fatalf("allocatePorts error: %v", err)
+	}
+	if *ports != defaults || len(changed) != 0 {
+		t.Fatalf("Ports changed when all were free: %+v, %v", ports, changed)
+	}
+
+	// dcrd RPC and DEX ports taken. The first random port is also taken.
+	taken := map[string]bool{
+		defaults.DCRDRPCListen: true,
+		defaults.DEXWebAddr:    true,
+		":40001":               true,
+	}
+	ports, changed, err = allocatePorts(defaults, func(addr string) bool { return !taken[addr] }, randomPort)
+	if err != nil {
+		t.Fatalf("allocatePorts error: %v", err)
+	}
+	if ports.DCRDRPCListen != ":40002" || ports.DEXWebAddr != ":40003" {
+		t.Fatalf("Wrong replacement ports: %+v", ports)
+	}
+	if ports.DCRDListen != defaults.DCRDListen || ports.DCRWalletRPCListen != defaults.DCRWalletRPCListen {
+		t.Fatalf("Free ports changed: %+v", ports)
+	}
+	if len(changed) != 2 {
+		t.Fatalf("Expected 2 changed ports, got %v", changed)
+	}
+	// The preferred ports are not modified.
+	if defaults.DCRDRPCListen == ports.DCRDRPCListen {
+		t.Fatalf("Preferred ports modified")
+	}
+
+	// Duplicates are replaced.
+	dupes := defaults
+	dupes.DCRWalletRPCListen = dupes.DCRDRPCListen
+	ports, _, err = allocatePorts(dupes, allFree, randomPort)
+	if err != nil {
+		t.Fatalf("allocatePorts error: %v", err)
+	}
+	if ports.DCRWalletRPCListen == ports.DCRDRPCListen {
+		t.Fatalf("Duplicate port not replaced")
+	}
+
+	// Give up eventually.
+	_, _, err = allocatePorts(defaults, func(string) bool { return false }, randomPort)
+	if err == nil {
+		t.Fatalf("No error when no ports are free")
+	}
+
+	// The real probe.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen error: %v", err)
+	}
+	addr := fmt.Sprintf(":%d", l.Addr().(*net.TCPAddr).Port)
+	if portFree(addr) {
+		t.Fatalf("Port in use reported free")
+	}
+	l.Close()
+	if !portFree(addr) {
+		t.Fatalf("Closed port reported taken")
+	}
+	if newAddr, err := randomFreePort(); err != nil || !portFree(newAddr) {
+		t.Fatalf("randomFreePort error: %v", err)
+	}
+}
@@ -0,0 +1,282 @@
+package eco
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/decred/dcrd/rpcclient/v6"
+	"golang.org/x/net/proxy"
+)
+
+// ProxyConfig is a SOCKS5 proxy, e.g. Tor, for the services and for Eco's own
+// downloads.
+type ProxyConfig struct {
+	// Addr is the proxy address, host:port, e.g. 127.0.0.1:9050.
+	Addr string
+	// User and Pass are optional credentials. With Tor, different
+	// credentials get different circuits.
+	User string
+	Pass string
+	// OnionOnly sends only connections to .onion hosts through the proxy.
+	// Everything else connects directly. dcrwallet and Decrediton can't split
+	// their traffic, so they only use the proxy for an onion dcrd.
+	OnionOnly bool
+}
+
+// validate checks the proxy address.
+func (p *ProxyConfig) validate() error {
+	p.Addr = strings.TrimSpace(p.Addr)
+	host, port, err := net.SplitHostPort(p.Addr)
+	if err != nil || host == "" || port == "" {
+		return fmt.Errorf("Proxy address must be host:port")
+	}
+	if p.Pass != "" && p.User == "" {
+		return fmt.Errorf("Proxy password provided without a username")
+	}
+	return nil
+}
+
+// proxies checks whether connections to the host go through the proxy. host
+// can include a port. A nil ProxyConfig proxies nothing.
+func (p *ProxyConfig) proxies(host string) bool {
+	if p == nil {
+		return false
+	}
+	if !p.OnionOnly {
+		return true
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.HasSuffix(strings.ToLower(host), ".onion")
+}
+
+// dcrdArgs are the dcrd arguments for the proxy.
+func (p *ProxyConfig) dcrdArgs() []string {
+	if p == nil {
+		return nil
+	}
+	flag := "proxy"
+	if p.OnionOnly {
+		flag = "onion"
+	}
+	args := []string{fmt.Sprintf("--%s=%s", flag, p.Addr)}
+	if p.User != "" {
+		args = append(args,
+			fmt.Sprintf("--%suser=%s", flag, p.User),
+			fmt.Sprintf("--%spass=%s", flag, p.Pass),
+		)
+	}
+	return args
+}
+
+// dcrWalletArgs are the dcrwallet arguments for the proxy. dcrdHost is the
+// dcrd that dcrwallet connects to, or empty in SPV mode. The bundled dcrd is
+// never dialed through the proxy, since Tor won't connect to localhost.
+func (p *ProxyConfig) dcrWalletArgs(dcrdHost string, remote bool) []string {
+	if p == nil {
+		return nil
+	}
+	dcrdProxied := remote && p.proxies(dcrdHost)
+	// dcrwallet's only other connections are to SPV peers, which aren't
+	// onion hosts.
+	if p.OnionOnly && !dcrdProxied {
+		return nil
+	}
+	args := []string{fmt.Sprintf("--proxy=%s", p.Addr)}
+	if p.User != "" {
+		args = append(args,
+			fmt.Sprintf("--proxyuser=%s", p.User),
+			fmt.Sprintf("--proxypass=%s", p.Pass),
+		)
+	}
+	if dcrdHost != "" && !dcrdProxied {
+		args = append(args, "--nodcrdproxy")
+	}
+	return args
+}
+
+// dexArgs are the dexc arguments for the proxy. dexc doesn't take proxy
+// credentials, so it uses Tor stream isolation instead.
+func (p *ProxyConfig) dexArgs() []string {
+	if p == nil {
+		return nil
+	}
+	if p.OnionOnly {
+		return []string{fmt.Sprintf("--onion=%s", p.Addr)}
+	}
+	return []string{fmt.Sprintf("--torproxy=%s", p.Addr), "--torisolation"}
+}
+
+// decreditonProxy is the proxy_type and proxy_location for the Decrediton
+// configuration. Decrediton can't proxy only onion hosts, so it connects
+// directly in OnionOnly mode.
+func (p *ProxyConfig) decreditonProxy() (proxyType, location *string) {
+	if p == nil || p.OnionOnly {
+		return nil, nil
+	}
+	t, loc := "socks5", "socks5://"+p.Addr
+	return &t, &loc
+}
+
+// applyRPC routes the RPC client through the proxy if its host is proxied. The
+// client must be in HTTP POST mode, which takes the proxy as a URL.
+func (p *ProxyConfig) applyRPC(cfg *rpcclient.ConnConfig) {
+	if !p.proxies(cfg.Host) {
+		return
+	}
+	u := &url.URL{Scheme: "socks5", Host: p.Addr}
+	if p.User != "" {
+		u.User = url.UserPassword(p.User, p.Pass)
+	}
+	cfg.Proxy = u.String()
+}
+
+// dialer creates a dialer that connects through the proxy, or directly for
+// hosts that aren't proxied.
+func (p *ProxyConfig) dialer() (func(ctx context.Context, network, addr string) (net.Conn, error), error) {
+	direct := &net.Dialer{}
+	if p == nil {
+		return direct.DialContext, nil
+	}
+	var auth *proxy.Auth
+	if p.User != "" {
+		auth = &proxy.Auth{User: p.User, Password: p.Pass}
+	}
+	d, err := proxy.SOCKS5("tcp", p.Addr, auth, direct)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating proxy dialer: %w", err)
+	}
+	socks, ok := d.(proxy.ContextDialer)
+	if !ok {
+		return nil, fmt.Errorf("Proxy dialer does not support contexts")
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if p.proxies(addr) {
+			return socks.DialContext(ctx, network, addr)
+		}
+		return direct.DialContext(ctx, network, addr)
+	}, nil
+}
+
+var (
+	httpClientMtx sync.RWMutex
+	httpClient    = http.DefaultClient
+)
+
+// webClient is the HTTP client for Eco's downloads.
+func webClient() *http.Client {
+	httpClientMtx.RLock()
+	defer httpClientMtx.RUnlock()
+	return httpClient
+}
+
+// setHTTPProxy routes Eco's downloads through the proxy. A nil proxy restores
+// the default client.
+func setHTTPProxy(p *ProxyConfig) error {
+	cl := http.DefaultClient
+	if p != nil {
+		dial, err := p.dialer()
+		if err != nil {
+			return err
+		}
+		tr := http.DefaultTransport.(*http.Transport).Clone()
+		// Never fall back to a proxy from the environment.
+		tr.Proxy = nil
+		tr.DialContext = dial
+		cl = &http.Client{Transport: tr}
+	}
+	httpClientMtx.Lock()
+	httpClient = cl
+	httpClientMtx.Unlock()
+	return nil
+}
+
+// writeDecreditonProxy sets the proxy in the Decrediton configuration file.
+// The file is decoded generically, so that settings this version of Eco
+// doesn't know about are kept.
+func writeDecreditonProxy(cfgPath string, p *ProxyConfig) error {
+	b, err := ioutil.ReadFile(cfgPath)
+	if err != nil {
+		return fmt.Errorf("Error reading Decrediton configuration: %w", err)
+	}
+	cfg := make(map[string]interface{})
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return fmt.Errorf("Error decoding Decrediton configuration: %w", err)
+	}
+	proxyType, location := p.decreditonProxy()
+	cfg["proxy_type"], cfg["proxy_location"] = proxyType, location
+	b, err = json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Error encoding Decrediton configuration: %w", err)
+	}
+	return ioutil.WriteFile(cfgPath, b, 0644)
+}
+
+// proxy is the configured proxy, or nil if there isn't one.
+func (eco *Eco) proxy() *ProxyConfig {
+	eco.stateMtx.RLock()
+	defer eco.stateMtx.RUnlock()
+	return eco.proxyLocked()
+}
+
+// redacted is a copy of the proxy without the password, for clients. A nil
+// ProxyConfig returns nil.
+func (p *ProxyConfig) redacted() *ProxyConfig {
+	if p == nil {
+		return nil
+	}
+	pCopy := *p
+	pCopy.Pass = ""
+	return &pCopy
+}
+
+// proxyLocked is proxy for a caller that holds the stateMtx.
+func (eco *Eco) proxyLocked() *ProxyConfig {
+	if eco.state.Eco.Proxy == nil {
+		return nil
+	}
+	p := *eco.state.Eco.Proxy
+	return &p
+}
+
+// setProxy sets and saves the proxy. A nil proxy disables it. Eco's downloads
+// use the new proxy right away, but the services only pick it up when they
+// are restarted. Clients never get the saved password, so an empty Pass with
+// the saved User keeps the saved password.
+func (eco *Eco) setProxy(p *ProxyConfig) error {
+	eco.stateMtx.Lock()
+	defer eco.stateMtx.Unlock()
+	if p != nil {
+		if old := eco.state.Eco.Proxy; old != nil && p.Pass == "" && p.User != "" && p.User == old.User {
+			p.Pass = old.Pass
+		}
+		if err := p.validate(); err != nil {
+			return err
+		}
+	}
+	if err := setHTTPProxy(p); err != nil {
+		return err
+	}
+	eco.state.Eco.Proxy = p
+	return eco.saveEcoState()
+}
+
+type proxyRequest struct {
+	// Proxy is nil to disable the proxy.
+	Proxy *ProxyConfig
+}
+
+// SetProxy sets the SOCKS5 proxy for the services and Eco's downloads. A nil
+// proxy disables it. An empty Pass keeps the saved password if the User is
+// unchanged. Running services must be restarted to use the new proxy.
+func SetProxy(ctx context.Context, p *ProxyConfig) error {
+	return errorRequest(ctx, routeSetProxy, &proxyRequest{Proxy: p})
+}
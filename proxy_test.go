@@ -0,0 +1,255 @@
+package eco
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/buck54321/eco/db"
+	"github.com/decred/slog"
+)
+
+func TestProxyArgs(t *testing.T) {
+	var none *ProxyConfig
+	full := &ProxyConfig{Addr: "127.0.0.1:9050", User: "u", Pass: "p"}
+	onion := &ProxyConfig{Addr: "127.0.0.1:9050", OnionOnly: true}
+
+	tests := []struct {
+		name string
+		got  []string
+		exp  []string
+	}{
+		{"dcrd none", none.dcrdArgs(), nil},
+		{"dcrd full", full.dcrdArgs(), []string{"--proxy=127.0.0.1:9050", "--proxyuser=u", "--proxypass=p"}},
+		{"dcrd onion", onion.dcrdArgs(), []string{"--onion=127.0.0.1:9050"}},
+		{"wallet spv", full.dcrWalletArgs("", false), []string{"--proxy=127.0.0.1:9050", "--proxyuser=u", "--proxypass=p"}},
+		{"wallet local dcrd", full.dcrWalletArgs("localhost:19703", false), []string{"--proxy=127.0.0.1:9050", "--proxyuser=u", "--proxypass=p", "--nodcrdproxy"}},
+		{"wallet remote dcrd", full.dcrWalletArgs("10.0.0.5:9109", true), []string{"--proxy=127.0.0.1:9050", "--proxyuser=u", "--proxypass=p"}},
+		{"wallet onion spv", onion.dcrWalletArgs("", false), nil},
+		{"wallet onion clearnet dcrd", onion.dcrWalletArgs("10.0.0.5:9109", true), nil},
+		{"wallet onion dcrd", onion.dcrWalletArgs("abc.onion:9109", true), []string{"--proxy=127.0.0.1:9050"}},
+		{"dex full", full.dexArgs(), []string{"--torproxy=127.0.0.1:9050", "--torisolation"}},
+		{"dex onion", onion.dexArgs(), []string{"--onion=127.0.0.1:9050"}},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.got, tt.exp) {
+			t.Fatalf("%s: expected %v, got %v", tt.name, tt.exp, tt.got)
+		}
+	}
+
+	if err := (&ProxyConfig{Addr: "127.0.0.1"}).validate(); err == nil {
+		t.Fatalf("No error for a proxy address without a port")
+	}
+	if err := (&ProxyConfig{Addr: " 127.0.0.1:9050 "}).validate(); err != nil {
+		t.Fatalf("validate error: %v", err)
+	}
+}
+
+func TestWriteDecreditonProxy(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(tmpDir)
+	cfgPath := filepath.Join(tmpDir, "config.json")
+	if err := encodeToJSONFile(cfgPath, defaultDecreditonConfig("mainnet")); err != nil {
+		t.Fatalf("encodeToJSONFile error: %v", err)
+	}
+	// A setting Eco doesn't know about.
+	b, _ := ioutil.ReadFile(cfgPath)
+	cfg := make(map[string]interface{})
+	json.Unmarshal(b, &cfg)
+	cfg["future_setting"] = "keep me"
+	b, _ = json.Marshal(cfg)
+	ioutil.WriteFile(cfgPath, b, 0644)
+
+	read := func() map[string]interface{} {
+		b, err := ioutil.ReadFile(cfgPath)
+		if err != nil {
+			t.Fatalf("ReadFile error: %v", err)
+		}
+		cfg := make(map[string]interface{})
+		if err := json.Unmarshal(b, &cfg); err != nil {
+			t.Fatalf("Unmarshal error: %v", err)
+		}
+		return cfg
+	}
+
+	if err := writeDecreditonProxy(cfgPath, &ProxyConfig{Addr: "127.0.0.1:9050"}); err != nil {
+		t.Fatalf("writeDecreditonProxy error: %v", err)
+	}
+	cfg = read()
+	if cfg["proxy_type"] != "socks5" || cfg["proxy_location"] != "socks5://127.0.0.1:9050" {
+		t.Fatalf("Wrong proxy in config: %v, %v", cfg["proxy_type"], cfg["proxy_location"])
+	}
+	if cfg["future_setting"] != "keep me" || cfg["theme"] != "theme-dark" {
+		t.Fatalf("Other settings not kept")
+	}
+
+	if err := writeDecreditonProxy(cfgPath, nil); err != nil {
+		t.Fatalf("writeDecreditonProxy error: %v", err)
+	}
+	cfg = read()
+	if cfg["proxy_type"] != nil || cfg["proxy_location"] != nil {
+		t.Fatalf("Proxy not cleared: %v, %v", cfg["proxy_type"], cfg["proxy_location"])
+	}
+}
+
+// tSOCKS5 is a minimal SOCKS5 server, without authentication, that records the
+// addresses it connects to.
+type tSOCKS5 struct {
+	l     net.Listener
+	mtx   sync.Mutex
+	addrs []string
+}
+
+func newTSOCKS5(t *testing.T) *tSOCKS5 {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen error: %v", err)
+	}
+	s := &tSOCKS5{l: l}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.handle(conn)
+		}
+	}()
+	return s
+}
+
+func (s *tSOCKS5) handle(conn net.Conn) {
+	defer conn.Close()
+	// Greeting: version, method count, methods.
+	hdr := make([]byte, 2)
+	if _, err := io.ReadFull(conn, hdr); err != nil {
+		return
+	}
+	if _, err := io.ReadFull(conn, make([]byte, hdr[1])); err != nil {
+		return
+	}
+	conn.Write([]byte{5, 0})
+	// Request: version, command, reserved, address type.
+	req := make([]byte, 4)
+	if _, err := io.ReadFull(conn, req); err != nil {
+		return
+	}
+	var host string
+	switch req[3] {
+	case 1:
+		ip := make([]byte, 4)
+		io.ReadFull(conn, ip)
+		host = net.IP(ip).String()
+	case 3:
+		n := make([]byte, 1)
+		io.ReadFull(conn, n)
+		name := make([]byte, n[0])
+		io.ReadFull(conn, name)
+		host = string(name)
+	default:
+		return
+	}
+	portB := make([]byte, 2)
+	io.ReadFull(conn, portB)
+	addr := net.JoinHostPort(host, fmt.Sprint(binary.BigEndian.Uint16(portB)))
+	s.mtx.Lock()
+	s.addrs = append(s.addrs, addr)
+	s.mtx.Unlock()
+
+	target, err := net.Dial("tcp", addr)
+	if err != nil {
+		conn.Write([]byte{5, 1, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer target.Close()
+	conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+	go io.Copy(target, conn)
+	io.Copy(conn, target)
+}
+
+func (s *tSOCKS5) dialed() []string {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return append([]string(nil), s.addrs...)
+}
+
+func TestHTTPProxy(t *testing.T) {
+	socks := newTSOCKS5(t)
+	defer socks.l.Close()
+	defer setHTTPProxy(nil)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	}))
+	defer ts.Close()
+	tsAddr := ts.Listener.Addr().String()
+
+	if err := setHTTPProxy(&ProxyConfig{Addr: socks.l.Addr().String()}); err != nil {
+		t.Fatalf("setHTTPProxy error: %v", err)
+	}
+	if _, err := fetchReleases(context.Background(), ts.URL); err != nil {
+		t.Fatalf("fetchReleases error: %v", err)
+	}
+	if dialed := socks.dialed(); len(dialed) != 1 || dialed[0] != tsAddr {
+		t.Fatalf("Expected the proxy to dial %s, got %v", tsAddr, dialed)
+	}
+
+	// Only onion hosts are proxied in onion-only mode.
+	if err := setHTTPProxy(&ProxyConfig{Addr: socks.l.Addr().String(), OnionOnly: true}); err != nil {
+		t.Fatalf("setHTTPProxy error: %v", err)
+	}
+	if _, err := fetchReleases(context.Background(), ts.URL); err != nil {
+		t.Fatalf("fetchReleases error: %v", err)
+	}
+	if dialed := socks.dialed(); len(dialed) != 1 {
+		t.Fatalf("Clearnet request went through the proxy in onion-only mode")
+	}
+}
+
+func TestSetProxyPassword(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(tmpDir)
+	dbb, err := db.NewDB(filepath.Join(tmpDir, "eco.db"), slog.Disabled)
+	if err != nil {
+		t.Fatalf("NewDB error: %v", err)
+	}
+	defer setHTTPProxy(nil)
+	eco := &Eco{db: dbb}
+
+	if err := eco.setProxy(&ProxyConfig{Addr: "127.0.0.1:9050", User: "u", Pass: "secret"}); err != nil {
+		t.Fatalf("setProxy error: %v", err)
+	}
+	if p := eco.metaState().Eco.Proxy; p == nil || p.User != "u" || p.Pass != "" {
+		t.Fatalf("Expected a proxy without a password in the state, got %+v", p)
+	}
+	if p := eco.proxy(); p.Pass != "secret" {
+		t.Fatalf("Saved proxy password changed to %q", p.Pass)
+	}
+
+	// A client sends back the redacted proxy, e.g. to change the address.
+	if err := eco.setProxy(&ProxyConfig{Addr: "127.0.0.1:9150", User: "u"}); err != nil {
+		t.Fatalf("setProxy error: %v", err)
+	}
+	if p := eco.proxy(); p.Addr != "127.0.0.1:9150" || p.Pass != "secret" {
+		t.Fatalf("Password not kept: %+v", p)
+	}
+
+	// A new username doesn't get the old password.
+	if err := eco.setProxy(&ProxyConfig{Addr: "127.0.0.1:9150", User: "v"}); err != nil {
+		t.Fatalf("setProxy error: %v", err)
+	}
+	if p := eco.proxy(); p.Pass != "" {
+		t.Fatalf("Password kept for a new username")
+	}
+}
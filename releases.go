@@ -1,10 +1,6 @@
 package eco
 
 import (
-	"encoding/json"
-	"fmt"
-	"net/http"
-	"sort"
 	"time"
 )
 
@@ -36,21 +32,14 @@ type githubUser struct {
 	AvatarURL string `json:"avatar_url"`
 }
 
-func fetchReleases() ([]*githubRelease, error) {
-	resp, err := http.Get(releasesURL)
-	if err != nil {
-		return nil, fmt.Errorf("Error fetching releases: %w", err)
+// selectRelease picks the newest release for the channel. The releases should
+// be sorted newest first, as returned from a ReleaseSource.
+func selectRelease(releases []*githubRelease, channel ReleaseChannel) *githubRelease {
+	for _, release := range releases {
+		if release.Prerelease && channel != ReleaseChannelPrerelease {
+			continue
+		}
+		return release
 	}
-
-	defer resp.Body.Close()
-	var releases []*githubRelease
-	err = json.NewDecoder(resp.Body).Decode(&releases)
-	if err != nil {
-		return nil, fmt.Errorf("JSON decode error: %w", err)
-	}
-	//  Should already be sorted newest first, but just make sure.
-	sort.Slice(releases, func(i, j int) bool {
-		return releases[i].Published.After(releases[j].Published)
-	})
-	return releases, nil
+	return nil
 }
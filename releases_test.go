@@ -1,64 +1,20 @@
-// +build live
-
 package eco
 
-import (
-	"context"
-	"encoding/json"
-	"fmt"
-	"io/ioutil"
-	"os"
-	"path/filepath"
-	"strings"
-	"testing"
-	"time"
-)
-
-// go test -v -tags live -run FetchReleases
-func TestFetchReleases(t *testing.T) {
-	releases, err := fetchReleases()
-	if err != nil {
-		t.Fatalf("fetchReleases error: %v", err)
-	}
-	b, _ := json.MarshalIndent(releases[:5], "", "    ")
-	fmt.Println(string(b))
-}
+import "testing"
 
-// go test -v -tags live -run FetchAsset
-func TestFetchAsset(t *testing.T) {
-	tmpDir, _ := ioutil.TempDir("", "")
-	defer os.RemoveAll(tmpDir)
-	asset := &releaseAsset{
-		githubAsset: &githubAsset{
-			Name: "decred-v1.6.0-rc3-manifest.txt",
-			URL:  "https://api.github.com/repos/decred/decred-binaries/releases/assets/28254571",
-		},
+func TestSelectRelease(t *testing.T) {
+	releases := []*githubRelease{
+		{Name: "v1.6.0-rc3", Prerelease: true},
+		{Name: "v1.5.1"},
+		{Name: "v1.5.0"},
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
-	defer cancel()
-	path, err := fetchAsset(ctx, tmpDir, asset.URL, asset.Name)
-	if err != nil {
-		t.Fatalf("fetchAsset error: %v", err)
+	if r := selectRelease(releases, ReleaseChannelStable); r == nil || r.Name != "v1.5.1" {
+		t.Fatalf("wrong stable release selected: %v", r)
 	}
-	if !fileExists(path) {
-		t.Fatalf("no file where fetchAsset reported")
+	if r := selectRelease(releases, ReleaseChannelPrerelease); r == nil || r.Name != "v1.6.0-rc3" {
+		t.Fatalf("wrong pre-release selected: %v", r)
 	}
-	b, _ := ioutil.ReadFile(path)
-	content := string(b)
-	if !strings.HasPrefix(content, "c33b26de3c5f2b24a5d423cbdc631405f591776596052e5cf5fd9669f3e5e5cf  decred-darwin-amd64-v1.6.0-rc3.tar.gz") {
-		t.Fatalf("Wrong file contents")
-	}
-}
-
-// go test -v -tags live -run DownloadChromium
-func TestDownloadChromium(t *testing.T) {
-	tmpDir, _ := ioutil.TempDir("", "")
-	defer os.RemoveAll(tmpDir)
-	versionDir := filepath.Join(tmpDir, "version")
-	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
-	defer cancel()
-	err := downloadChromium(ctx, tmpDir, versionDir)
-	if err != nil {
-		t.Fatalf("downloadChromium error: %v", err)
+	if r := selectRelease(releases[:1], ReleaseChannelStable); r != nil {
+		t.Fatalf("pre-release selected for stable channel")
 	}
 }
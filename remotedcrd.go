@@ -0,0 +1,126 @@
+package eco
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/decred/dcrd/rpcclient/v6"
+)
+
+// remoteRPCPorts are dcrd's default RPC ports for each network. A remote dcrd
+// is not run by Eco, so it probably uses dcrd's defaults, not Eco's ports.
+var remoteRPCPorts = map[Network]string{
+	NetworkMainnet: "9109",
+	NetworkTestnet: "19109",
+	NetworkSimnet:  "19556",
+}
+
+// validate checks the configuration, and adds the network's default RPC port
+// to the Host if there isn't one.
+func (r *RemoteDCRD) validate(network Network) error {
+	r.Host = strings.TrimSpace(r.Host)
+	if r.Host == "" {
+		return fmt.Errorf("No dcrd host provided")
+	}
+	if _, _, err := net.SplitHostPort(r.Host); err != nil {
+		port, found := remoteRPCPorts[network]
+		if !found {
+			return fmt.Errorf("Unknown network %d", network)
+		}
+		r.Host = net.JoinHostPort(strings.Trim(r.Host, "[]"), port)
+	}
+	if r.RPCUser == "" || r.RPCPass == "" {
+		return fmt.Errorf("dcrd RPC username and password are required")
+	}
+	block, _ := pem.Decode(r.Cert)
+	if block == nil {
+		return fmt.Errorf("dcrd TLS certificate is not PEM-encoded")
+	}
+	if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+		return fmt.Errorf("Error parsing dcrd TLS certificate: %w", err)
+	}
+	return nil
+}
+
+// checkRemoteDCRD connects to the remote dcrd and checks that it's on the
+// network. proxy can be nil.
+func checkRemoteDCRD(ctx context.Context, r *RemoteDCRD, network Network, proxy *ProxyConfig) error {
+	cfg := &rpcclient.ConnConfig{
+		Host:         r.Host,
+		HTTPPostMode: true,
+		User:         r.RPCUser,
+		Pass:         r.RPCPass,
+		Certificates: r.Cert,
+	}
+	proxy.applyRPC(cfg)
+	cl, err := rpcclient.New(cfg, nil)
+	if err != nil {
+		return fmt.Errorf("Error creating dcrd client: %w", err)
+	}
+	defer cl.Shutdown()
+	bci, err := cl.GetBlockChainInfo(ctx)
+	if err != nil {
+		return fmt.Errorf("Error connecting to dcrd at %s: %w", r.Host, err)
+	}
+	if netName := network.chainParams().Name; bci.Chain != netName {
+		return fmt.Errorf("dcrd at %s is on %s, not %s", r.Host, bci.Chain, netName)
+	}
+	return nil
+}
+
+// writeRemoteCert writes the remote dcrd's certificate to the file that
+// dcrwallet, Decrediton and dcrctl read it from.
+func writeRemoteCert(r *RemoteDCRD) error {
+	if err := os.MkdirAll(filepath.Dir(remoteCertPath), 0700); err != nil {
+		return fmt.Errorf("Error creating certificate directory: %w", err)
+	}
+	return ioutil.WriteFile(remoteCertPath, r.Cert, 0600)
+}
+
+// dcrdConn is how the services and clients reach dcrd's RPC server.
+type dcrdConn struct {
+	// host is the RPC address, host:port.
+	host     string
+	user     string
+	pass     string
+	certPath string
+	remote   bool
+	// proxy is the proxy for a remote dcrd. The bundled dcrd is never
+	// proxied.
+	proxy *ProxyConfig
+}
+
+// dcrdConnLocked is the dcrdConn for the bundled dcrd, or the remote dcrd in
+// SyncModeRemote. The stateMtx must be held.
+func (eco *Eco) dcrdConnLocked() *dcrdConn {
+	if r := eco.dcrd.Remote; r != nil && eco.state.Eco.SyncMode == SyncModeRemote {
+		return &dcrdConn{
+			host:     r.Host,
+			user:     r.RPCUser,
+			pass:     r.RPCPass,
+			certPath: remoteCertPath,
+			remote:   true,
+			proxy:    eco.proxyLocked(),
+		}
+	}
+	return &dcrdConn{
+		host:     "localhost" + eco.portsLocked().DCRDRPCListen,
+		user:     eco.dcrd.RPCUser,
+		pass:     eco.dcrd.RPCPass,
+		certPath: dcrdCertPath,
+	}
+}
+
+// dcrdConn is dcrdConnLocked for a caller that doesn't hold the stateMtx.
+func (eco *Eco) dcrdConn() *dcrdConn {
+	eco.stateMtx.RLock()
+	defer eco.stateMtx.RUnlock()
+	return eco.dcrdConnLocked()
+}
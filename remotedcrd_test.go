@@ -0,0 +1,99 @@
+package eco
+
+import (
+	"crypto/elliptic"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/certgen"
+)
+
+func TestRemoteDCRDValidate(t *testing.T) {
+	cert, _, err := certgen.NewTLSCertPair(elliptic.P256(), "eco test", time.Now().Add(time.Hour), nil)
+	if err != nil {
+		t.Fatalf("NewTLSCertPair error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		remote  RemoteDCRD
+		network Network
+		host    string
+		wantErr bool
+	}{
+		{
+			name:   "default port",
+			remote: RemoteDCRD{Host: " 10.0.0.5 ", RPCUser: "u", RPCPass: "p", Cert: cert},
+			host:   "10.0.0.5:9109",
+		},
+		{
+			name:    "testnet default port",
+			remote:  RemoteDCRD{Host: "node.example.com", RPCUser: "u", RPCPass: "p", Cert: cert},
+			network: NetworkTestnet,
+			host:    "node.example.com:19109",
+		},
+		{
+			name:   "ipv6",
+			remote: RemoteDCRD{Host: "[::1]", RPCUser: "u", RPCPass: "p", Cert: cert},
+			host:   "[::1]:9109",
+		},
+		{
+			name:   "explicit port",
+			remote: RemoteDCRD{Host: "node.example.com:1234", RPCUser: "u", RPCPass: "p", Cert: cert},
+			host:   "node.example.com:1234",
+		},
+		{
+			name:    "no host",
+			remote:  RemoteDCRD{RPCUser: "u", RPCPass: "p", Cert: cert},
+			wantErr: true,
+		},
+		{
+			name:    "no credentials",
+			remote:  RemoteDCRD{Host: "node.example.com", Cert: cert},
+			wantErr: true,
+		},
+		{
+			name:    "bad cert",
+			remote:  RemoteDCRD{Host: "node.example.com", RPCUser: "u", RPCPass: "p", Cert: []byte("not a cert")},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		r := tt.remote
+		err := r.validate(tt.network)
+		if tt.wantErr {
+			if err == nil {
+				t.Fatalf("%s: no error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: validate error: %v", tt.name, err)
+		}
+		if r.Host != tt.host {
+			t.Fatalf("%s: expected host %s, got %s", tt.name, tt.host, r.Host)
+		}
+	}
+}
+
+func TestRemoteDCRDState(t *testing.T) {
+	st := dcrdNewState()
+	st.Remote = &RemoteDCRD{Host: "10.0.0.5:9109", RPCUser: "u", RPCPass: "remote secret"}
+	eco := &Eco{
+		state: MetaState{Eco: EcoState{SyncMode: SyncModeRemote}},
+		dcrd:  &DCRD{DCRDState: *st},
+	}
+
+	clientState := eco.dcrdState()
+	if clientState.Remote == nil || clientState.Remote.Host != "10.0.0.5:9109" {
+		t.Fatalf("Remote dcrd missing from the state: %+v", clientState.Remote)
+	}
+	if clientState.Remote.RPCPass != "" {
+		t.Fatalf("Remote RPC password in the state")
+	}
+	// The redaction doesn't touch Eco's copy, which dcrwallet still needs.
+	if eco.dcrdConn().pass != "remote secret" {
+		t.Fatalf("Remote RPC password removed from Eco's state")
+	}
+}
@@ -0,0 +1,81 @@
+package eco
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	walletclient "decred.org/dcrwallet/rpc/client/dcrwallet"
+	"decred.org/dcrwallet/walletseed"
+	"github.com/buck54321/eco/encode"
+)
+
+// walletSeedSize is the size of the seeds that Eco creates wallets from.
+const walletSeedSize = 32
+
+// decodeSeed decodes a wallet seed entered by the user, either as hex or as
+// PGP word list words. The last word of a mnemonic is a checksum, which is
+// checked.
+func decodeSeed(input []byte) ([]byte, error) {
+	// Words may be separated by any whitespace, e.g. one per line.
+	words := strings.Fields(string(input))
+	if len(words) == 0 {
+		return nil, fmt.Errorf("Empty wallet seed")
+	}
+	if len(words) > 1 && len(words) != walletSeedSize+1 {
+		return nil, fmt.Errorf("Expected %d seed words, got %d", walletSeedSize+1, len(words))
+	}
+	seed, err := walletseed.DecodeUserInput(strings.Join(words, " "))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid wallet seed: %w", err)
+	}
+	if len(seed) != walletSeedSize {
+		encode.ClearBytes(seed)
+		return nil, fmt.Errorf("Expected a %d byte wallet seed, got %d bytes", walletSeedSize, len(seed))
+	}
+	return seed, nil
+}
+
+// restoreProgress is a sync update for a stage of a wallet restore.
+func restoreProgress(stage, status string) *Progress {
+	return &Progress{
+		Service:  dcrwallet,
+		Stage:    stage,
+		Status:   status,
+		Progress: walletSyncStages[stage].start,
+	}
+}
+
+// restoreWallet finds the accounts and addresses used by a wallet that was
+// restored from seed, and rescans the chain for their transactions. The
+// wallet must be synced first. If the restore fails, the job stays pending and
+// is tried again the next time dcrwallet starts.
+func (eco *Eco) restoreWallet(ctx context.Context, wcl *walletclient.Client) error {
+	pw, err := eco.session.pw()
+	if err != nil {
+		return err
+	}
+	// Account discovery needs the wallet unlocked. With a timeout, dcrwallet
+	// would relock the wallet under the DEX, which keeps it unlocked.
+	err = wcl.WalletPassphrase(ctx, string(pw), 0)
+	encode.ClearBytes(pw)
+	if err != nil {
+		return fmt.Errorf("Error unlocking wallet: %w", err)
+	}
+
+	eco.sendSyncUpdate(restoreProgress(SyncStageDiscovery, "Discovering used accounts and addresses"))
+	// Arguments are the start block, account discovery, and gap limit.
+	// dcrwallet's defaults are used for the start block and gap limit.
+	if err := wcl.Call(ctx, "discoverusage", nil, nil, true); err != nil {
+		return fmt.Errorf("discoverusage error: %w", err)
+	}
+
+	eco.sendSyncUpdate(restoreProgress(SyncStageRescan, "Rescanning the blockchain"))
+	if err := wcl.Call(ctx, "rescanwallet", nil, 0); err != nil {
+		return fmt.Errorf("rescanwallet error: %w", err)
+	}
+
+	eco.finishJob(PendingWalletRestore)
+	eco.sendSyncUpdate(restoreProgress(SyncStageSynced, "Wallet restored"))
+	return nil
+}
@@ -0,0 +1,58 @@
+package eco
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"decred.org/dcrwallet/pgpwordlist"
+	"decred.org/dcrwallet/walletseed"
+	"github.com/buck54321/eco/encode"
+)
+
+func TestDecodeSeed(t *testing.T) {
+	seed := encode.RandomBytes(walletSeedSize)
+	words := walletseed.EncodeMnemonicSlice(seed)
+
+	for _, tt := range []struct {
+		name  string
+		input string
+	}{
+		{"hex", hex.EncodeToString(seed)},
+		{"words", strings.Join(words, " ")},
+		{"one word per line", strings.ToUpper(strings.Join(words, "\n")) + "\n"},
+	} {
+		decoded, err := decodeSeed([]byte(tt.input))
+		if err != nil {
+			t.Fatalf("%s: decodeSeed error: %v", tt.name, err)
+		}
+		if !bytes.Equal(decoded, seed) {
+			t.Fatalf("%s: wrong seed decoded", tt.name)
+		}
+	}
+
+	// Swap the checksum word for another word that's valid at its position.
+	decoded, err := pgpwordlist.DecodeMnemonics(words)
+	if err != nil {
+		t.Fatalf("DecodeMnemonics error: %v", err)
+	}
+	badChecksum := append([]string(nil), words...)
+	badChecksum[walletSeedSize] = pgpwordlist.ByteToMnemonic(decoded[walletSeedSize]+1, walletSeedSize)
+
+	for _, tt := range []struct {
+		name  string
+		input string
+	}{
+		{"empty", " \n"},
+		{"short hex", hex.EncodeToString(seed[:16])},
+		{"bad hex", "zz" + hex.EncodeToString(seed[1:])},
+		{"missing word", strings.Join(words[1:], " ")},
+		{"bad checksum", strings.Join(badChecksum, " ")},
+		{"unknown word", strings.Join(append([]string{"notaword"}, words[1:]...), " ")},
+	} {
+		if _, err := decodeSeed([]byte(tt.input)); err == nil {
+			t.Fatalf("%s: no error", tt.name)
+		}
+	}
+}
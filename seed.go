@@ -0,0 +1,160 @@
+package eco
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"decred.org/dcrwallet/walletseed"
+	"github.com/buck54321/eco/encode"
+	"github.com/buck54321/eco/encrypt"
+)
+
+// seedQuizWords is the number of seed words the user must enter before the
+// seed is deleted.
+const seedQuizWords = 4
+
+// seedWords decrypts the stored wallet seed, and encodes it as PGP word list
+// words. The last word is a checksum.
+func (eco *Eco) seedWords(pw []byte) ([]string, error) {
+	encSeed, err := eco.db.Fetch(walletSeedKey)
+	if err != nil {
+		return nil, fmt.Errorf("DB error: %w", err)
+	}
+	if len(encSeed) == 0 {
+		return nil, fmt.Errorf("No wallet seed is stored")
+	}
+	b, err := eco.db.Fetch(crypterKey)
+	if err != nil {
+		return nil, fmt.Errorf("DB error: %w", err)
+	}
+	crypter, err := encrypt.Deserialize(pw, b)
+	if err != nil {
+		return nil, fmt.Errorf("Incorrect password")
+	}
+	defer crypter.Close()
+	seed, err := crypter.Decrypt(encSeed)
+	if err != nil {
+		return nil, fmt.Errorf("Error decrypting wallet seed: %w", err)
+	}
+	defer encode.ClearBytes(seed)
+	return walletseed.EncodeMnemonicSlice(seed), nil
+}
+
+// newSeedQuiz picks the seed words the user must enter, by index, and
+// remembers them for deleteSeed. A new quiz replaces the last one.
+func (eco *Eco) newSeedQuiz() ([]int, error) {
+	encSeed, err := eco.db.Fetch(walletSeedKey)
+	if err != nil {
+		return nil, fmt.Errorf("DB error: %w", err)
+	}
+	if len(encSeed) == 0 {
+		return nil, fmt.Errorf("No wallet seed is stored")
+	}
+	// The seed is 32 bytes, plus the checksum word.
+	indices := make([]int, 33)
+	for i := range indices {
+		indices[i] = i
+	}
+	// Partial Fisher-Yates shuffle.
+	for i := 0; i < seedQuizWords; i++ {
+		j := i + int(randInt()%uint64(len(indices)-i))
+		indices[i], indices[j] = indices[j], indices[i]
+	}
+	quiz := indices[:seedQuizWords]
+	sort.Ints(quiz)
+	eco.quizMtx.Lock()
+	eco.seedQuiz = quiz
+	eco.quizMtx.Unlock()
+	return append([]int(nil), quiz...), nil
+}
+
+// deleteSeed checks the answers to the current seed quiz and, if they're all
+// correct, deletes the stored seed and records the backup. A failed attempt
+// ends the quiz, so the user must start a new one.
+func (eco *Eco) deleteSeed(pw []byte, answers []string) error {
+	eco.quizMtx.Lock()
+	quiz := eco.seedQuiz
+	eco.seedQuiz = nil
+	eco.quizMtx.Unlock()
+	if len(quiz) == 0 {
+		return fmt.Errorf("No seed quiz has been started")
+	}
+	if len(answers) != len(quiz) {
+		return fmt.Errorf("Expected %d words, got %d", len(quiz), len(answers))
+	}
+
+	words, err := eco.seedWords(pw)
+	if err != nil {
+		return err
+	}
+	for i, idx := range quiz {
+		if !strings.EqualFold(strings.TrimSpace(answers[i]), words[idx]) {
+			return fmt.Errorf("Incorrect seed words")
+		}
+	}
+
+	eco.stateMtx.Lock()
+	defer eco.stateMtx.Unlock()
+	if err := eco.db.Store(walletSeedKey, nil); err != nil {
+		return fmt.Errorf("Error deleting wallet seed: %w", err)
+	}
+	eco.state.Eco.SeedBackedUp = true
+	if err := eco.saveEcoState(); err != nil {
+		return fmt.Errorf("Seed deleted, but the backup status could not be saved: %w", err)
+	}
+	return nil
+}
+
+type seedRequest struct {
+	PW []byte
+}
+
+type revealSeedResponse struct {
+	Words []string
+	Err   string
+}
+
+type seedQuizResponse struct {
+	// Indices are the 0-based positions of the words to enter.
+	Indices []int
+	Err     string
+}
+
+type deleteSeedRequest struct {
+	PW      []byte
+	Answers []string
+}
+
+// RevealSeed decrypts the wallet seed with the user's password, and returns
+// it as PGP word list words.
+func RevealSeed(ctx context.Context, pw []byte) ([]string, error) {
+	resp := new(revealSeedResponse)
+	if err := request(ctx, routeRevealSeed, &seedRequest{PW: pw}, resp); err != nil {
+		return nil, err
+	}
+	if resp.Err != "" {
+		return nil, fmt.Errorf(resp.Err)
+	}
+	return resp.Words, nil
+}
+
+// SeedQuiz starts a seed quiz, and returns the 0-based positions of the seed
+// words that must be passed to DeleteSeed.
+func SeedQuiz(ctx context.Context) ([]int, error) {
+	resp := new(seedQuizResponse)
+	if err := request(ctx, routeSeedQuiz, struct{}{}, resp); err != nil {
+		return nil, err
+	}
+	if resp.Err != "" {
+		return nil, fmt.Errorf(resp.Err)
+	}
+	return resp.Indices, nil
+}
+
+// DeleteSeed deletes the stored wallet seed if the answers, the words at the
+// positions from SeedQuiz in order, are correct. Each quiz allows one attempt.
+func DeleteSeed(ctx context.Context, pw []byte, answers []string) error {
+	return errorRequest(ctx, routeDeleteSeed, &deleteSeedRequest{PW: pw, Answers: answers})
+}
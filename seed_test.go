@@ -0,0 +1,99 @@
+package eco
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"decred.org/dcrwallet/walletseed"
+	"github.com/buck54321/eco/db"
+	"github.com/buck54321/eco/encode"
+	"github.com/buck54321/eco/encrypt"
+	"github.com/decred/slog"
+)
+
+func TestSeedBackup(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(tmpDir)
+	dbb, err := db.NewDB(filepath.Join(tmpDir, "eco.db"), slog.Disabled)
+	if err != nil {
+		t.Fatalf("NewDB error: %v", err)
+	}
+	pw := []byte("abc")
+	crypter := encrypt.NewCrypter(pw)
+	seed := encode.RandomBytes(32)
+	encSeed, _ := crypter.Encrypt(seed)
+	dbb.Store(crypterKey, crypter.Serialize())
+	dbb.Store(walletSeedKey, encSeed)
+	eco := &Eco{db: dbb}
+
+	if _, err := eco.seedWords([]byte("abd")); err == nil {
+		t.Fatalf("No error for the wrong password")
+	}
+	words, err := eco.seedWords(pw)
+	if err != nil {
+		t.Fatalf("seedWords error: %v", err)
+	}
+	if exp := walletseed.EncodeMnemonicSlice(seed); !reflect.DeepEqual(words, exp) {
+		t.Fatalf("Expected words %v, got %v", exp, words)
+	}
+
+	if err := eco.deleteSeed(pw, nil); err == nil {
+		t.Fatalf("Seed deleted without a quiz")
+	}
+
+	quiz, err := eco.newSeedQuiz()
+	if err != nil {
+		t.Fatalf("newSeedQuiz error: %v", err)
+	}
+	if len(quiz) != seedQuizWords {
+		t.Fatalf("Expected %d quiz words, got %d", seedQuizWords, len(quiz))
+	}
+	for i, idx := range quiz {
+		if idx < 0 || idx >= len(words) || (i > 0 && idx <= quiz[i-1]) {
+			t.Fatalf("Bad quiz indices %v", quiz)
+		}
+	}
+	answers := func() []string {
+		a := make([]string, len(quiz))
+		for i, idx := range quiz {
+			a[i] = words[idx]
+		}
+		return a
+	}
+
+	// A wrong answer ends the quiz.
+	wrong := answers()
+	wrong[len(wrong)-1] = "wrong"
+	if err := eco.deleteSeed(pw, wrong); err == nil {
+		t.Fatalf("Seed deleted with a wrong answer")
+	}
+	if err := eco.deleteSeed(pw, answers()); err == nil {
+		t.Fatalf("Seed deleted after a failed quiz")
+	}
+
+	quiz, _ = eco.newSeedQuiz()
+	right := answers()
+	for i := range right {
+		right[i] = " " + strings.ToUpper(right[i])
+	}
+	if err := eco.deleteSeed(pw, right); err != nil {
+		t.Fatalf("deleteSeed error: %v", err)
+	}
+	if b, _ := dbb.Fetch(walletSeedKey); len(b) != 0 {
+		t.Fatalf("Seed not deleted")
+	}
+	stored := new(EcoState)
+	if _, err := dbb.FetchDecode(ecoStateKey, stored); err != nil {
+		t.Fatalf("FetchDecode error: %v", err)
+	}
+	if !stored.SeedBackedUp {
+		t.Fatalf("Backup not recorded")
+	}
+	if _, err := eco.newSeedQuiz(); err == nil {
+		t.Fatalf("Quiz started for a deleted seed")
+	}
+}
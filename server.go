@@ -26,18 +26,41 @@ const (
 	// is closed.
 	rpcTimeoutSeconds = 10
 
-	routeServiceStatus   = "service_status"
-	routeInit            = "init"
-	routeSync            = "sync"
-	routeStartDecrediton = "start_decrediton"
-	routeStartDEX        = "start_dex"
-	routeDCRCtl          = "dcrctl"
+	routeServiceStatus       = "service_status"
+	routeInit                = "init"
+	routeSync                = "sync"
+	routeStartDecrediton     = "start_decrediton"
+	routeStartDEX            = "start_dex"
+	routeDCRCtl              = "dcrctl"
+	routeUpgrade             = "upgrade"
+	routeSetVersion          = "set_version"
+	routeSetReleaseChannel   = "set_release_channel"
+	routeSetReleaseSource    = "set_release_source"
+	routeStorage             = "storage"
+	routePruneVersions       = "prune_versions"
+	routeSetVersionRetention = "set_version_retention"
+	routeClearCache          = "clear_cache"
+	routeSetCacheLimit       = "set_cache_limit"
+	routeLogs                = "logs"
+	routeStopService         = "stop_service"
+	routeRestartService      = "restart_service"
+	routeSetProxy            = "set_proxy"
+	routeGetSettings         = "get_settings"
+	routeSetSettings         = "set_settings"
+	routeUnlock              = "unlock"
+	routeAddClient           = "add_client"
+	routeRevokeClient        = "revoke_client"
+	routeRevealSeed          = "reveal_seed"
+	routeSeedQuiz            = "seed_quiz"
+	routeDeleteSeed          = "delete_seed"
 )
 
 type Server struct {
 	listener net.Listener
 	eco      *Eco
 	ctx      context.Context
+	// token is the owner's token, which has every scope.
+	token []byte
 }
 
 // NewServer is a constructor for an Server.
@@ -58,6 +81,10 @@ func NewServer(eco *Eco) (*Server, error) {
 	if err != nil {
 		return nil, err
 	}
+	token, err := loadToken(TokenPath)
+	if err != nil {
+		return nil, err
+	}
 
 	// Prepare the TLS configuration.
 	tlsConfig := tls.Config{
@@ -78,10 +105,17 @@ func NewServer(eco *Eco) (*Server, error) {
 	if err != nil {
 		return nil, fmt.Errorf("Can't listen on %s %s: %w", serverAddress.Net, serverAddress.Addr, err)
 	}
+	if serverAddress.Net == "unix" {
+		if err := os.Chmod(serverAddress.Addr, 0600); err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("error setting unix socket permissions: %v", err)
+		}
+	}
 
 	return &Server{
 		listener: listener,
 		eco:      eco,
+		token:    token,
 	}, nil
 }
 
@@ -126,19 +160,31 @@ func readN(conn net.Conn, n int) ([]byte, error) {
 }
 
 func (s *Server) handleRequest(conn net.Conn) {
+	defer conn.Close()
 	packet, err := nextPacket(conn)
 	if err != nil {
 		log.Error(err)
 		return
 	}
 
+	token, packet := popToken(packet)
 	route, payload := popRoute(packet)
 	if route == "" {
 		log.Errorf("could not decode route from request from %s", conn.RemoteAddr())
 		return
 	}
 
-	defer conn.Close()
+	// Every request is answered with an authResponse first.
+	if err := s.authorize(token, route); err != nil {
+		log.Warnf("Rejected %s request: %v", route, err)
+		sendPacket(conn, &authResponse{Err: err.Error()})
+		return
+	}
+	if err := sendPacket(conn, &authResponse{}); err != nil {
+		log.Errorf("Error sending auth response: %v", err)
+		return
+	}
+
 	switch route {
 	case routeServiceStatus:
 		s.handleServiceRequest(conn, payload)
@@ -152,6 +198,48 @@ func (s *Server) handleRequest(conn net.Conn) {
 		s.handleStartDEX(conn)
 	case routeDCRCtl:
 		s.handleDCRCtl(conn, payload)
+	case routeUpgrade:
+		s.handleUpgradeRequest(conn)
+	case routeSetVersion:
+		s.handleSetVersion(conn, payload)
+	case routeSetReleaseChannel:
+		s.handleSetReleaseChannel(conn, payload)
+	case routeSetReleaseSource:
+		s.handleSetReleaseSource(conn, payload)
+	case routeStorage:
+		s.handleStorage(conn)
+	case routePruneVersions:
+		s.handlePruneVersions(conn)
+	case routeSetVersionRetention:
+		s.handleSetVersionRetention(conn, payload)
+	case routeClearCache:
+		s.handleClearCache(conn)
+	case routeSetCacheLimit:
+		s.handleSetCacheLimit(conn, payload)
+	case routeLogs:
+		s.handleLogs(conn, payload)
+	case routeStopService:
+		s.handleStopService(conn, payload)
+	case routeRestartService:
+		s.handleRestartService(conn, payload)
+	case routeSetProxy:
+		s.handleSetProxy(conn, payload)
+	case routeGetSettings:
+		s.handleGetSettings(conn)
+	case routeSetSettings:
+		s.handleSetSettings(conn, payload)
+	case routeUnlock:
+		s.handleUnlock(conn, payload)
+	case routeAddClient:
+		s.handleAddClient(conn, payload)
+	case routeRevokeClient:
+		s.handleRevokeClient(conn, payload)
+	case routeRevealSeed:
+		s.handleRevealSeed(conn, payload)
+	case routeSeedQuiz:
+		s.handleSeedQuiz(conn)
+	case routeDeleteSeed:
+		s.handleDeleteSeed(conn, payload)
 	default:
 		log.Errorf("unknown route: %s", route)
 	}
@@ -223,7 +311,14 @@ func (s *Server) handleServiceRequest(conn net.Conn, payload []byte) {
 
 type initRequest struct {
 	SyncMode SyncMode
+	Network  Network
 	PW       []byte
+	// Remote is the dcrd to use in SyncModeRemote.
+	Remote *RemoteDCRD
+	// Seed is an existing wallet seed to restore, as entered by the user,
+	// either hex or PGP word list words. A new seed is generated if Seed is
+	// empty.
+	Seed []byte
 }
 
 func sendProgress(conn net.Conn, svc, status, errStr string, progress float32) error {
@@ -261,15 +356,39 @@ func (s *Server) handleInitRequest(conn net.Conn, payload []byte) {
 		return
 	}
 
+	if !req.Network.valid() {
+		log.Errorf("Unknown network requested: %d", req.Network)
+		sendProgress(conn, "eco", "", "Unknown network requested", 0)
+		return
+	}
+
 	switch req.SyncMode {
 	case SyncModeFull, SyncModeSPV:
 		s.eco.initEco(conn, req)
+	case SyncModeRemote:
+		if req.Remote == nil {
+			sendProgress(conn, "eco", "", "No remote dcrd configuration", 0)
+			return
+		}
+		if err := req.Remote.validate(req.Network); err != nil {
+			sendProgress(conn, "eco", "", err.Error(), 0)
+			return
+		}
+		s.eco.initEco(conn, req)
 	default:
 		log.Errorf("Unknown sync mode requested: %d", req.SyncMode)
 		sendProgress(conn, "eco", "", "Unknown sync mode requested", 0)
 	}
 }
 
+func (s *Server) handleUpgradeRequest(conn net.Conn) {
+	if s.eco.syncMode() == SyncModeUninitialized {
+		sendProgress(conn, "eco", "", "Eco is not initialized", 0)
+		return
+	}
+	s.eco.upgradeEco(conn)
+}
+
 func (s *Server) handleSyncRequest(conn net.Conn) {
 	// ch := make(chan *ecotypes.ProgressUpdate, 1)
 	ch := s.eco.syncChan()
@@ -316,6 +435,296 @@ func (s *Server) handleStartDEX(conn net.Conn) {
 	writeConn(conn, b)
 }
 
+func (s *Server) handleStopService(conn net.Conn, payload []byte) {
+	req := new(serviceRequest)
+	err := encode.GobDecode(payload, req)
+	if err == nil {
+		err = s.eco.stopService(req.Service)
+	}
+	writeError(conn, err)
+}
+
+func (s *Server) handleRestartService(conn net.Conn, payload []byte) {
+	req := new(serviceRequest)
+	err := encode.GobDecode(payload, req)
+	if err == nil {
+		err = s.eco.restartService(req.Service)
+	}
+	writeError(conn, err)
+}
+
+func (s *Server) handleSetVersion(conn net.Conn, payload []byte) {
+	req := new(setVersionRequest)
+	err := encode.GobDecode(payload, req)
+	if err == nil {
+		err = s.eco.setVersion(req.Version)
+	}
+	writeError(conn, err)
+}
+
+func (s *Server) handleSetReleaseChannel(conn net.Conn, payload []byte) {
+	req := new(releaseChannelRequest)
+	err := encode.GobDecode(payload, req)
+	if err == nil {
+		err = s.eco.setReleaseChannel(req.Channel)
+	}
+	writeError(conn, err)
+}
+
+func (s *Server) handleSetReleaseSource(conn net.Conn, payload []byte) {
+	req := new(ReleaseSourceConfig)
+	err := encode.GobDecode(payload, req)
+	if err == nil {
+		err = s.eco.setReleaseSource(req)
+	}
+	writeError(conn, err)
+}
+
+func (s *Server) handleSetProxy(conn net.Conn, payload []byte) {
+	req := new(proxyRequest)
+	err := encode.GobDecode(payload, req)
+	if err == nil {
+		err = s.eco.setProxy(req.Proxy)
+	}
+	writeError(conn, err)
+}
+
+func (s *Server) handleGetSettings(conn net.Conn) {
+	b, err := encode.GobEncode(&settingsResponse{Settings: s.eco.settings()})
+	if err != nil {
+		log.Errorf("GobEncode(resp) error in handleGetSettings: %v", err)
+		return
+	}
+	writeConn(conn, b)
+}
+
+func (s *Server) handleSetSettings(conn net.Conn, payload []byte) {
+	resp := new(setSettingsResponse)
+	req := new(Settings)
+	err := encode.GobDecode(payload, req)
+	if err == nil {
+		resp.Restarted, err = s.eco.setSettings(req)
+	}
+	if err != nil {
+		resp.Err = err.Error()
+	}
+	b, err := encode.GobEncode(resp)
+	if err != nil {
+		log.Errorf("GobEncode(resp) error in handleSetSettings: %v", err)
+		return
+	}
+	writeConn(conn, b)
+}
+
+func (s *Server) handleUnlock(conn net.Conn, payload []byte) {
+	req := new(unlockRequest)
+	err := encode.GobDecode(payload, req)
+	if err == nil {
+		err = s.eco.unlock(req.PW)
+		encode.ClearBytes(req.PW)
+	}
+	writeError(conn, err)
+}
+
+func (s *Server) handleAddClient(conn net.Conn, payload []byte) {
+	resp := new(addClientResponse)
+	req := new(addClientRequest)
+	err := encode.GobDecode(payload, req)
+	if err == nil {
+		resp.Token, err = s.eco.addClient(req.Name, req.Scopes)
+	}
+	if err != nil {
+		resp.Err = err.Error()
+	}
+	b, err := encode.GobEncode(resp)
+	if err != nil {
+		log.Errorf("GobEncode(resp) error in handleAddClient: %v", err)
+		return
+	}
+	writeConn(conn, b)
+}
+
+func (s *Server) handleRevokeClient(conn net.Conn, payload []byte) {
+	req := new(revokeClientRequest)
+	err := encode.GobDecode(payload, req)
+	if err == nil {
+		err = s.eco.revokeClient(req.Name)
+	}
+	writeError(conn, err)
+}
+
+func (s *Server) handleRevealSeed(conn net.Conn, payload []byte) {
+	resp := new(revealSeedResponse)
+	req := new(seedRequest)
+	err := encode.GobDecode(payload, req)
+	if err == nil {
+		resp.Words, err = s.eco.seedWords(req.PW)
+		encode.ClearBytes(req.PW)
+	}
+	if err != nil {
+		resp.Err = err.Error()
+	}
+	b, err := encode.GobEncode(resp)
+	if err != nil {
+		log.Errorf("GobEncode(resp) error in handleRevealSeed: %v", err)
+		return
+	}
+	writeConn(conn, b)
+}
+
+func (s *Server) handleSeedQuiz(conn net.Conn) {
+	resp := new(seedQuizResponse)
+	var err error
+	resp.Indices, err = s.eco.newSeedQuiz()
+	if err != nil {
+		resp.Err = err.Error()
+	}
+	b, err := encode.GobEncode(resp)
+	if err != nil {
+		log.Errorf("GobEncode(resp) error in handleSeedQuiz: %v", err)
+		return
+	}
+	writeConn(conn, b)
+}
+
+func (s *Server) handleDeleteSeed(conn net.Conn, payload []byte) {
+	req := new(deleteSeedRequest)
+	err := encode.GobDecode(payload, req)
+	if err == nil {
+		err = s.eco.deleteSeed(req.PW, req.Answers)
+		encode.ClearBytes(req.PW)
+	}
+	writeError(conn, err)
+}
+
+func (s *Server) handleStorage(conn net.Conn) {
+	resp := new(storageResponse)
+	report, err := s.eco.storageReport()
+	if err != nil {
+		resp.Err = err.Error()
+	} else {
+		resp.Report = report
+	}
+	b, err := encode.GobEncode(resp)
+	if err != nil {
+		log.Errorf("GobEncode(resp) error in handleStorage: %v", err)
+		return
+	}
+	writeConn(conn, b)
+}
+
+func (s *Server) handlePruneVersions(conn net.Conn) {
+	resp := new(pruneResponse)
+	removed, err := s.eco.pruneVersions()
+	resp.Removed = removed
+	if err != nil {
+		resp.Err = err.Error()
+	}
+	b, err := encode.GobEncode(resp)
+	if err != nil {
+		log.Errorf("GobEncode(resp) error in handlePruneVersions: %v", err)
+		return
+	}
+	writeConn(conn, b)
+}
+
+func (s *Server) handleSetVersionRetention(conn net.Conn, payload []byte) {
+	req := new(versionRetentionRequest)
+	err := encode.GobDecode(payload, req)
+	if err == nil {
+		err = s.eco.setVersionRetention(req.Retention)
+	}
+	writeError(conn, err)
+}
+
+func (s *Server) handleClearCache(conn net.Conn) {
+	writeError(conn, s.eco.downloadCache().clear())
+}
+
+func (s *Server) handleSetCacheLimit(conn net.Conn, payload []byte) {
+	req := new(cacheLimitRequest)
+	err := encode.GobDecode(payload, req)
+	if err == nil {
+		err = s.eco.setDownloadCacheLimit(req.Limit)
+	}
+	writeError(conn, err)
+}
+
+// handleLogs sends the tail of a service log. For a follow request, the
+// response is a stream of packets, the first with the tail, and then one for
+// each new line.
+func (s *Server) handleLogs(conn net.Conn, payload []byte) {
+	req := new(logsRequest)
+	err := encode.GobDecode(payload, req)
+	if err == nil {
+		err = req.validate()
+	}
+	var svcLog *serviceLog
+	if err == nil {
+		svcLog, err = serviceLogger(req.Service)
+	}
+
+	if !req.Follow {
+		resp := new(logsResponse)
+		if err == nil {
+			resp.Lines, err = svcLog.tail(req.Lines)
+		}
+		if err != nil {
+			resp.Err = err.Error()
+		}
+		b, err := encode.GobEncode(resp)
+		if err != nil {
+			log.Errorf("GobEncode(resp) error in handleLogs: %v", err)
+			return
+		}
+		writeConn(conn, b)
+		return
+	}
+
+	if err != nil {
+		sendPacket(conn, &logsResponse{Err: err.Error()})
+		return
+	}
+	lines, ch, unsub, err := svcLog.follow(req.Lines)
+	if err != nil {
+		sendPacket(conn, &logsResponse{Err: err.Error()})
+		return
+	}
+	defer unsub()
+	if err := sendPacket(conn, &logsResponse{Lines: lines}); err != nil {
+		log.Debugf("error sending log lines: %v", err)
+		return
+	}
+	for {
+		select {
+		case line, ok := <-ch:
+			if !ok {
+				return
+			}
+			// Send any other lines that are ready in the same packet.
+			lines := []string{line}
+		out:
+			for {
+				select {
+				case line, ok := <-ch:
+					if !ok {
+						break out
+					}
+					lines = append(lines, line)
+				default:
+					break out
+				}
+			}
+			if err := sendPacket(conn, &logsResponse{Lines: lines}); err != nil {
+				log.Debugf("error sending log lines: %v", err)
+				return
+			}
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
 func (s *Server) handleDCRCtl(conn net.Conn, payload []byte) {
 	req := new(dcrCtlRequest)
 	err := encode.GobDecode(payload, req)
@@ -337,6 +746,20 @@ func (s *Server) handleDCRCtl(conn net.Conn, payload []byte) {
 	writeConn(conn, b)
 }
 
+// writeError writes an *Error response. The Msg is empty if err is nil.
+func writeError(conn net.Conn, err error) {
+	resp := &Error{}
+	if err != nil {
+		resp.Msg = err.Error()
+	}
+	b, err := encode.GobEncode(resp)
+	if err != nil {
+		log.Errorf("GobEncode(resp) error: %v", err)
+		return
+	}
+	writeConn(conn, b)
+}
+
 func writeConn(conn net.Conn, b []byte) error {
 	_, err := io.Copy(conn, bytes.NewReader(b))
 	return err
@@ -345,8 +768,10 @@ func writeConn(conn net.Conn, b []byte) error {
 type Client struct {
 	netAddr   *NetAddr
 	tlsConfig *tls.Config
+	token     []byte
 }
 
+// NewClient creates a client that authenticates with the token at TokenPath.
 func NewClient() (*Client, error) {
 	pem, err := ioutil.ReadFile(CertPath)
 	if err != nil {
@@ -362,14 +787,35 @@ func NewClient() (*Client, error) {
 		RootCAs:    pool,
 		ServerName: "localhost",
 	}
+	b, err := ioutil.ReadFile(TokenPath)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading IPC token: %w", err)
+	}
 	return &Client{
 		netAddr:   serverAddress,
 		tlsConfig: tlsConfig,
+		token:     bytes.TrimSpace(b),
 	}, nil
 }
 
+// readAuth reads the server's authResponse.
+func readAuth(conn net.Conn) error {
+	packet, err := nextPacket(conn)
+	if err != nil {
+		return fmt.Errorf("Error reading auth response: %w", err)
+	}
+	resp := new(authResponse)
+	if err := encode.GobDecode(packet, resp); err != nil {
+		return fmt.Errorf("Error decoding auth response: %w", err)
+	}
+	if resp.Err != "" {
+		return errors.New(resp.Err)
+	}
+	return nil
+}
+
 func (c *Client) request(ctx context.Context, route string, thing, resp interface{}) error {
-	req := encodeRequest(route, thing)
+	req := encodeRequest(c.token, route, thing)
 	if req == nil {
 		return fmt.Errorf("Could not encode request")
 	}
@@ -393,6 +839,9 @@ func (c *Client) request(ctx context.Context, route string, thing, resp interfac
 			err = fmt.Errorf("Write error: %w", err)
 			return
 		}
+		if err = readAuth(conn); err != nil {
+			return
+		}
 		// read
 		var buf bytes.Buffer
 		_, err = io.Copy(&buf, conn)
@@ -415,7 +864,7 @@ func (c *Client) request(ctx context.Context, route string, thing, resp interfac
 }
 
 func (c *Client) subscribe(ctx context.Context, route string, subscription interface{}) (<-chan []byte, error) {
-	req := encodeRequest(route, subscription)
+	req := encodeRequest(c.token, route, subscription)
 	if req == nil {
 		return nil, fmt.Errorf("Could not encode subscription request")
 	}
@@ -430,10 +879,16 @@ func (c *Client) subscribe(ctx context.Context, route string, subscription inter
 		conn.Close()
 		return nil, fmt.Errorf("Write error: %w", err)
 	}
+	if err := readAuth(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
 
 	ch := make(chan []byte, 1)
 	go func() {
 		defer conn.Close()
+		// Close the channel so the subscriber knows the feed has ended.
+		defer close(ch)
 		for {
 			if ctx.Err() != nil {
 				return
@@ -445,7 +900,7 @@ func (c *Client) subscribe(ctx context.Context, route string, subscription inter
 			}
 			select {
 			case ch <- packet:
-			default:
+			case <-ctx.Done():
 				return
 			}
 		}
@@ -476,6 +931,19 @@ func request(ctx context.Context, route string, thing, resp interface{}) error {
 	return cl.request(ctx, route, thing, resp)
 }
 
+// errorRequest makes a request to a route that responds with an *Error.
+func errorRequest(ctx context.Context, route string, thing interface{}) error {
+	resp := new(Error)
+	err := request(ctx, route, thing, resp)
+	if err != nil {
+		return err
+	}
+	if resp.Msg != "" {
+		return resp
+	}
+	return nil
+}
+
 // filesExists reports whether the named file or directory exists.
 func fileExists(name string) bool {
 	_, err := os.Stat(name)
@@ -507,9 +975,16 @@ func genCertPair(certFile, keyFile string) error {
 	return nil
 }
 
-func encodeRequest(route string, thing interface{}) []byte {
+// encodeRequest encodes the request packet. The packet is the token and the
+// route, each prefixed with its length, and then the gob-encoded thing.
+func encodeRequest(token []byte, route string, thing interface{}) []byte {
 	routeB := []byte(route)
 	routeLen := len(routeB)
+	tokenLen := len(token)
+	if tokenLen > 255 {
+		log.Errorf("IPC token too long")
+		return nil
+	}
 
 	b, err := encode.GobEncode(thing)
 	if err != nil {
@@ -518,16 +993,19 @@ func encodeRequest(route string, thing interface{}) []byte {
 	}
 
 	bLen := len(b)
-	packetLen := 1 + routeLen + bLen
+	packetLen := 1 + tokenLen + 1 + routeLen + bLen
 	req := make([]byte, 4+packetLen)
 
 	lenB := make([]byte, 4)
 	binary.BigEndian.PutUint32(lenB, uint32(packetLen))
 
 	copy(req, lenB)
-	copy(req[4:], []byte{byte(routeLen)})
-	copy(req[4+1:], routeB)
-	copy(req[4+1+routeLen:], b)
+	copy(req[4:], []byte{byte(tokenLen)})
+	copy(req[4+1:], token)
+	routeStart := 4 + 1 + tokenLen
+	copy(req[routeStart:], []byte{byte(routeLen)})
+	copy(req[routeStart+1:], routeB)
+	copy(req[routeStart+1+routeLen:], b)
 	return req
 }
 
@@ -546,6 +1024,17 @@ func encodePacket(thing interface{}) []byte {
 	return packet
 }
 
+func popToken(req []byte) (token, rest []byte) {
+	if len(req) < 1 {
+		return nil, nil
+	}
+	tokenLen := int(req[0])
+	if len(req) < tokenLen+1 {
+		return nil, nil
+	}
+	return req[1 : 1+tokenLen], req[1+tokenLen:]
+}
+
 func popRoute(req []byte) (route string, payload []byte) {
 	if len(req) < 2 {
 		return "", nil
@@ -0,0 +1,257 @@
+package eco
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/jrick/logrotate/rotator"
+)
+
+const (
+	// serviceLogRollKB is the size at which a service log file is rolled.
+	serviceLogRollKB = 1024
+	// maxServiceLogLines is the most lines that can be requested from a
+	// service log.
+	maxServiceLogLines = 5000
+	// maxServiceLogLine is the longest line that will be buffered. Longer
+	// lines are broken up.
+	maxServiceLogLine = 64 * 1024
+	// serviceLogSubBuffer is the number of lines buffered for a follower
+	// before lines are dropped.
+	serviceLogSubBuffer = 256
+	// tailChunkSize is how much of the file is read at a time when looking
+	// for the last lines.
+	tailChunkSize = 16 * 1024
+)
+
+// managedServices are the services that can be controlled through the IPC
+// server, and whose logs can be requested.
+var managedServices = map[string]bool{
+	dcrd:       true,
+	dcrwallet:  true,
+	dexc:       true,
+	decrediton: true,
+}
+
+// serviceLogs are the open service logs, by service name. A log stays open
+// across service restarts.
+var serviceLogs = struct {
+	sync.Mutex
+	logs map[string]*serviceLog
+}{logs: make(map[string]*serviceLog)}
+
+// serviceLog captures the stdout and stderr of a service to a rotating log
+// file, and passes new lines to any followers.
+type serviceLog struct {
+	path    string
+	mtx     sync.Mutex
+	rotator *rotator.Rotator
+	subs    map[chan string]struct{}
+}
+
+func serviceLogPath(svc string) string {
+	return filepath.Join(logDir, svc+".log")
+}
+
+// serviceLogger gets the log for the service, opening the file if necessary.
+func serviceLogger(svc string) (*serviceLog, error) {
+	serviceLogs.Lock()
+	defer serviceLogs.Unlock()
+	if l, found := serviceLogs.logs[svc]; found {
+		return l, nil
+	}
+	if err := os.MkdirAll(logDir, 0700); err != nil {
+		return nil, fmt.Errorf("Error creating log directory: %w", err)
+	}
+	path := serviceLogPath(svc)
+	r, err := rotator.New(path, serviceLogRollKB, false, maxLogRolls)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating log rotator for %s: %w", svc, err)
+	}
+	l := &serviceLog{
+		path:    path,
+		rotator: r,
+		subs:    make(map[chan string]struct{}),
+	}
+	serviceLogs.logs[svc] = l
+	return l, nil
+}
+
+// closeServiceLogs closes all of the service log files.
+func closeServiceLogs() {
+	serviceLogs.Lock()
+	defer serviceLogs.Unlock()
+	for svc, l := range serviceLogs.logs {
+		l.mtx.Lock()
+		l.rotator.Close()
+		for ch := range l.subs {
+			close(ch)
+		}
+		l.subs = nil
+		l.mtx.Unlock()
+		delete(serviceLogs.logs, svc)
+	}
+}
+
+// writer creates a writer for one of the service's output streams. Each
+// stream needs its own writer, so that partial lines aren't interleaved.
+func (l *serviceLog) writer() io.Writer {
+	return &lineWriter{f: l.writeLine}
+}
+
+func (l *serviceLog) writeLine(line []byte) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	// Write the newline with the line, so the rotator can roll the file.
+	b := make([]byte, len(line)+1)
+	copy(b, line)
+	b[len(line)] = '\n'
+	if _, err := l.rotator.Write(b); err != nil {
+		log.Debugf("Error writing to %s: %v", l.path, err)
+	}
+	s := string(line)
+	for ch := range l.subs {
+		select {
+		case ch <- s:
+		default:
+			// A slow follower misses lines rather than blocking the
+			// service.
+		}
+	}
+}
+
+// tail gets the last n lines of the log file.
+func (l *serviceLog) tail(n int) ([]string, error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return tailFile(l.path, n)
+}
+
+// follow gets the last n lines of the log file and a channel that will receive
+// any new lines. No lines are lost or repeated between the tail and the
+// channel. The returned function must be called to unsubscribe.
+func (l *serviceLog) follow(n int) ([]string, <-chan string, func(), error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if l.subs == nil {
+		return nil, nil, nil, fmt.Errorf("Log is closed")
+	}
+	lines, err := tailFile(l.path, n)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	ch := make(chan string, serviceLogSubBuffer)
+	l.subs[ch] = struct{}{}
+	return lines, ch, func() {
+		l.mtx.Lock()
+		defer l.mtx.Unlock()
+		if _, found := l.subs[ch]; found {
+			delete(l.subs, ch)
+			close(ch)
+		}
+	}, nil
+}
+
+// lineWriter is an io.Writer that passes complete lines to f. exec.Cmd copies
+// each output stream from a single goroutine, so there is no locking.
+type lineWriter struct {
+	f       func([]byte)
+	partial []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		w.f(bytes.TrimSuffix(w.partial[:i], []byte{'\r'}))
+		w.partial = w.partial[i+1:]
+	}
+	if len(w.partial) >= maxServiceLogLine {
+		w.f(w.partial)
+		w.partial = nil
+	}
+	// Don't hang on to the old array.
+	if len(w.partial) == 0 {
+		w.partial = nil
+	}
+	return len(p), nil
+}
+
+// tailFile reads the last n lines of the file. There is no error if the file
+// does not exist.
+func tailFile(path string, n int) ([]string, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	// Read backwards until we have more than n newlines, so that the first
+	// line, which might be partial, can be discarded.
+	var buf []byte
+	off := fi.Size()
+	for off > 0 && bytes.Count(buf, []byte{'\n'}) <= n {
+		chunk := int64(tailChunkSize)
+		if off < chunk {
+			chunk = off
+		}
+		off -= chunk
+		b := make([]byte, int(chunk)+len(buf))
+		if _, err := f.ReadAt(b[:chunk], off); err != nil {
+			return nil, err
+		}
+		copy(b[chunk:], buf)
+		buf = b
+	}
+
+	s := strings.TrimSuffix(string(buf), "\n")
+	if s == "" {
+		return nil, nil
+	}
+	lines := strings.Split(s, "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines, nil
+}
+
+// logsRequest is a request for the last Lines lines of a service's log. If
+// Follow is true, new lines are streamed until the connection is closed.
+type logsRequest struct {
+	Service string
+	Lines   int
+	Follow  bool
+}
+
+type logsResponse struct {
+	Lines []string
+	Err   string
+}
+
+func (req *logsRequest) validate() error {
+	if !managedServices[req.Service] {
+		return fmt.Errorf("Unknown service %q", req.Service)
+	}
+	if req.Lines < 0 || req.Lines > maxServiceLogLines {
+		return fmt.Errorf("Number of lines must be between 0 and %d", maxServiceLogLines)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package eco
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tLogDir points the service logs at a temporary directory. The returned
+// function closes the logs and restores the directory.
+func tLogDir(t *testing.T) func() {
+	t.Helper()
+	tmpDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("TempDir error: %v", err)
+	}
+	oldDir := logDir
+	logDir = tmpDir
+	return func() {
+		closeServiceLogs()
+		logDir = oldDir
+		os.RemoveAll(tmpDir)
+	}
+}
+
+func TestTailFile(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(tmpDir)
+
+	writeLines := func(name string, n int, trailingNewline bool) string {
+		lines := make([]string, n)
+		for i := range lines {
+			lines[i] = fmt.Sprintf("line %d %s", i, strings.Repeat("x", i%100))
+		}
+		s := strings.Join(lines, "\n")
+		if trailingNewline && n > 0 {
+			s += "\n"
+		}
+		path := filepath.Join(tmpDir, name)
+		if err := ioutil.WriteFile(path, []byte(s), 0600); err != nil {
+			t.Fatalf("WriteFile error: %v", err)
+		}
+		return path
+	}
+
+	// Enough lines to span several chunks.
+	big := writeLines("big", 2000, true)
+	noNewline := writeLines("no-newline", 10, false)
+	empty := writeLines("empty", 0, false)
+
+	tests := []struct {
+		name  string
+		path  string
+		n     int
+		first int
+		count int
+	}{
+		{name: "last few", path: big, n: 5, first: 1995, count: 5},
+		{name: "across chunks", path: big, n: 1500, first: 500, count: 1500},
+		{name: "more than file", path: big, n: 3000, first: 0, count: 2000},
+		{name: "no trailing newline", path: noNewline, n: 3, first: 7, count: 3},
+		{name: "zero lines", path: big, n: 0, count: 0},
+		{name: "empty file", path: empty, n: 10, count: 0},
+		{name: "missing file", path: filepath.Join(tmpDir, "missing"), n: 10, count: 0},
+	}
+
+	for _, tt := range tests {
+		lines, err := tailFile(tt.path, tt.n)
+		if err != nil {
+			t.Fatalf("%s: tailFile error: %v", tt.name, err)
+		}
+		if len(lines) != tt.count {
+			t.Fatalf("%s: expected %d lines, got %d", tt.name, tt.count, len(lines))
+		}
+		for i, line := range lines {
+			if !strings.HasPrefix(line, fmt.Sprintf("line %d ", tt.first+i)) {
+				t.Fatalf("%s: wrong line at %d: %q", tt.name, i, line)
+			}
+		}
+	}
+}
+
+func TestServiceLog(t *testing.T) {
+	defer tLogDir(t)()
+
+	l, err := serviceLogger(dcrd)
+	if err != nil {
+		t.Fatalf("serviceLogger error: %v", err)
+	}
+	if l2, _ := serviceLogger(dcrd); l2 != l {
+		t.Fatalf("Different logger for the same service")
+	}
+
+	stdout, stderr := l.writer(), l.writer()
+	// Partial lines from different streams aren't mixed.
+	stdout.Write([]byte("out 1\nout "))
+	stderr.Write([]byte("err 1\r\n"))
+	stdout.Write([]byte("2\n"))
+
+	lines, ch, unsub, err := l.follow(10)
+	if err != nil {
+		t.Fatalf("follow error: %v", err)
+	}
+	exp := []string{"out 1", "err 1", "out 2"}
+	if strings.Join(lines, "|") != strings.Join(exp, "|") {
+		t.Fatalf("Expected lines %v, got %v", exp, lines)
+	}
+
+	stderr.Write([]byte("err 2\n"))
+	select {
+	case line := <-ch:
+		if line != "err 2" {
+			t.Fatalf("Wrong followed line %q", line)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("No followed line")
+	}
+	unsub()
+	if _, ok := <-ch; ok {
+		t.Fatalf("Channel not closed after unsubscribing")
+	}
+	// Writing after unsubscribing is fine.
+	stdout.Write([]byte("out 3\n"))
+
+	lines, err = l.tail(2)
+	if err != nil {
+		t.Fatalf("tail error: %v", err)
+	}
+	if strings.Join(lines, "|") != "err 2|out 3" {
+		t.Fatalf("Wrong tail %v", lines)
+	}
+
+	req := &logsRequest{Service: "sh", Lines: 10}
+	if req.validate() == nil {
+		t.Fatalf("No error for unknown service")
+	}
+	req = &logsRequest{Service: dcrd, Lines: maxServiceLogLines + 1}
+	if req.validate() == nil {
+		t.Fatalf("No error for too many lines")
+	}
+}
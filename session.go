@@ -0,0 +1,186 @@
+package eco
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/buck54321/eco/db"
+	"github.com/buck54321/eco/encode"
+	"github.com/buck54321/eco/encrypt"
+)
+
+// Keys where older versions stored the password, encrypted next to its own
+// key, until the jobs that needed it were done. They're only read to migrate
+// to PendingJobs.
+const (
+	legacyExtraInputKey = "extraInput"
+	legacyDEXInputKey   = "dexInput"
+)
+
+// errLocked is returned when a job needs the user's password, but Eco hasn't
+// been unlocked since it started.
+var errLocked = errors.New("Eco is locked")
+
+// PendingJobs are the deferred setup steps that need the user's password. Only
+// the fact that a job is pending is saved, never the password, so after a
+// restart the jobs wait until Eco is unlocked.
+type PendingJobs uint8
+
+const (
+	// PendingWalletStart is dcrwallet's first start. dcrwallet needs the
+	// password until its initial sync has begun.
+	PendingWalletStart PendingJobs = 1 << iota
+	// PendingDEXInit is the DEX account and wallet setup.
+	PendingDEXInit
+	// PendingWalletRestore is the address discovery and rescan for a wallet
+	// restored from seed. Account discovery needs the wallet unlocked.
+	PendingWalletRestore
+)
+
+// session holds the user's password in memory, encrypted with a random key
+// that is never saved, while jobs that need the password are pending.
+type session struct {
+	mtx     sync.Mutex
+	crypter encrypt.Crypter
+	encPW   []byte
+}
+
+// set stores the password for the session, replacing any previous password.
+func (s *session) set(pw []byte) error {
+	crypter := encrypt.NewCrypter(encode.RandomBytes(32))
+	encPW, err := crypter.Encrypt(pw)
+	if err != nil {
+		crypter.Close()
+		return fmt.Errorf("Error encrypting session password: %w", err)
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.clearLocked()
+	s.crypter, s.encPW = crypter, encPW
+	return nil
+}
+
+// pw is the session password. The caller should clear it after use.
+func (s *session) pw() ([]byte, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.crypter == nil {
+		return nil, errLocked
+	}
+	return s.crypter.Decrypt(s.encPW)
+}
+
+func (s *session) unlocked() bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.crypter != nil
+}
+
+// clear ends the session.
+func (s *session) clear() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.clearLocked()
+}
+
+func (s *session) clearLocked() {
+	if s.crypter != nil {
+		s.crypter.Close()
+	}
+	s.crypter, s.encPW = nil, nil
+}
+
+// migratePWCache deletes any password stored by an older version, and marks
+// its job as pending instead. migratePWCache returns true if the state was
+// changed.
+func migratePWCache(dbb *db.DB, state *EcoState) bool {
+	var changed bool
+	for k, job := range map[string]PendingJobs{
+		legacyExtraInputKey: PendingWalletStart,
+		legacyDEXInputKey:   PendingDEXInit,
+	} {
+		b, err := dbb.Fetch(k)
+		if err != nil {
+			log.Errorf("Error reading %s: %v", k, err)
+			continue
+		}
+		if len(b) == 0 {
+			continue
+		}
+		if err := dbb.Store(k, nil); err != nil {
+			log.Errorf("Error deleting %s: %v", k, err)
+		}
+		state.Pending |= job
+		changed = true
+	}
+	return changed
+}
+
+// pending are the pending jobs.
+func (eco *Eco) pending() PendingJobs {
+	eco.stateMtx.RLock()
+	defer eco.stateMtx.RUnlock()
+	return eco.state.Eco.Pending
+}
+
+// finishJob marks the job done. The session ends once no jobs are pending.
+func (eco *Eco) finishJob(job PendingJobs) {
+	eco.stateMtx.Lock()
+	defer eco.stateMtx.Unlock()
+	eco.state.Eco.Pending &^= job
+	if err := eco.saveEcoState(); err != nil {
+		log.Errorf("Error saving finished job: %v", err)
+	}
+	if eco.state.Eco.Pending == 0 {
+		eco.session.clear()
+	}
+}
+
+// unlock checks the password against the stored crypter, and starts any
+// pending jobs that were waiting for it.
+func (eco *Eco) unlock(pw []byte) error {
+	b, err := eco.db.Fetch(crypterKey)
+	if err != nil {
+		return fmt.Errorf("DB error: %w", err)
+	}
+	if len(b) == 0 {
+		return fmt.Errorf("Eco is not initialized")
+	}
+	crypter, err := encrypt.Deserialize(pw, b)
+	if err != nil {
+		return fmt.Errorf("Incorrect password")
+	}
+	crypter.Close()
+
+	pending := eco.pending()
+	if pending == 0 {
+		return nil
+	}
+	if err := eco.session.set(pw); err != nil {
+		return err
+	}
+	if pending&(PendingWalletStart|PendingWalletRestore) != 0 && !eco.sup.running(dcrwallet) {
+		if err := eco.runDCRWallet(); err != nil {
+			return fmt.Errorf("Error starting dcrwallet: %w", err)
+		}
+	}
+	if pending&PendingDEXInit != 0 && !eco.sup.running(dexc) {
+		if err := eco.runDEX(); err != nil {
+			return fmt.Errorf("Error initializing DEX: %w", err)
+		}
+	}
+	return nil
+}
+
+type unlockRequest struct {
+	PW []byte
+}
+
+// Unlock unlocks Eco with the user's password, so that setup steps that were
+// interrupted by a restart can finish. Unlock is only needed when
+// MetaState.Locked is true.
+func Unlock(ctx context.Context, pw []byte) error {
+	return errorRequest(ctx, routeUnlock, &unlockRequest{PW: pw})
+}
@@ -0,0 +1,140 @@
+package eco
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/buck54321/eco/db"
+	"github.com/buck54321/eco/encrypt"
+	"github.com/decred/slog"
+)
+
+func TestSession(t *testing.T) {
+	var s session
+	if _, err := s.pw(); !errors.Is(err, errLocked) {
+		t.Fatalf("Expected errLocked, got %v", err)
+	}
+	if err := s.set([]byte("abc")); err != nil {
+		t.Fatalf("set error: %v", err)
+	}
+	pw, err := s.pw()
+	if err != nil {
+		t.Fatalf("pw error: %v", err)
+	}
+	if string(pw) != "abc" {
+		t.Fatalf("Wrong password %q", string(pw))
+	}
+	s.clear()
+	if s.unlocked() {
+		t.Fatalf("Still unlocked after clear")
+	}
+}
+
+func TestMigratePWCache(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(tmpDir)
+	dbb, err := db.NewDB(filepath.Join(tmpDir, "eco.db"), slog.Disabled)
+	if err != nil {
+		t.Fatalf("NewDB error: %v", err)
+	}
+
+	state := new(EcoState)
+	if migratePWCache(dbb, state) {
+		t.Fatalf("Migrated an empty database")
+	}
+
+	dbb.Store(legacyDEXInputKey, []byte{0x01})
+	if !migratePWCache(dbb, state) {
+		t.Fatalf("Legacy DEX input not migrated")
+	}
+	if state.Pending != PendingDEXInit {
+		t.Fatalf("Expected pending jobs %d, got %d", PendingDEXInit, state.Pending)
+	}
+	if b, _ := dbb.Fetch(legacyDEXInputKey); len(b) != 0 {
+		t.Fatalf("Legacy DEX input not deleted")
+	}
+}
+
+func TestUnlock(t *testing.T) {
+	defer fastSupervisor()()
+	defer tLogDir(t)()
+
+	tmpDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(tmpDir)
+	defer func(ecoDir, walletDir string) {
+		EcoDir, dcrwalletAppDir = ecoDir, walletDir
+	}(EcoDir, dcrwalletAppDir)
+	EcoDir = filepath.Join(tmpDir, "eco")
+	dcrwalletAppDir = filepath.Join(tmpDir, dcrwallet)
+
+	dbb, err := db.NewDB(filepath.Join(tmpDir, "eco.db"), slog.Disabled)
+	if err != nil {
+		t.Fatalf("NewDB error: %v", err)
+	}
+	pw := []byte("abc")
+	if err := dbb.Store(crypterKey, encrypt.NewCrypter(pw).Serialize()); err != nil {
+		t.Fatalf("Store error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	eco := &Eco{
+		db:       dbb,
+		outerCtx: ctx,
+		innerCtx: ctx,
+		state: MetaState{Eco: EcoState{
+			SyncMode: SyncModeSPV,
+			Pending:  PendingWalletStart,
+		}},
+		dcrd:      &DCRD{DCRDState: *dcrdNewState()},
+		dcrwallet: new(DCRWallet),
+		sup:       newSupervisor(ctx, nil),
+	}
+	defer eco.sup.stopAll()
+
+	// The first dcrwallet start waits for an unlock.
+	if err := eco.runDCRWallet(); !errors.Is(err, errLocked) {
+		t.Fatalf("Expected errLocked, got %v", err)
+	}
+	if !eco.metaState().Locked {
+		t.Fatalf("Not reported as locked")
+	}
+
+	if err := eco.unlock([]byte("abd")); err == nil {
+		t.Fatalf("No error for the wrong password")
+	}
+	if err := eco.unlock(pw); err != nil {
+		t.Fatalf("unlock error: %v", err)
+	}
+	if !eco.sup.running(dcrwallet) {
+		t.Fatalf("dcrwallet not started after unlock")
+	}
+	if eco.metaState().Locked {
+		t.Fatalf("Still reported as locked")
+	}
+
+	// The session ends with the last pending job.
+	eco.finishJob(PendingWalletStart)
+	if eco.session.unlocked() {
+		t.Fatalf("Session not cleared")
+	}
+	stored := new(EcoState)
+	if _, err := dbb.FetchDecode(ecoStateKey, stored); err != nil {
+		t.Fatalf("FetchDecode error: %v", err)
+	}
+	if stored.Pending != 0 {
+		t.Fatalf("Finished job still stored as pending")
+	}
+
+	// Nothing is pending, so unlock only checks the password.
+	if err := eco.unlock(pw); err != nil {
+		t.Fatalf("unlock error: %v", err)
+	}
+	if eco.session.unlocked() {
+		t.Fatalf("Session started with no pending jobs")
+	}
+}
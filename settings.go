@@ -0,0 +1,206 @@
+package eco
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/decred/slog"
+)
+
+// maxDCRDPeers is the most peers dcrd may be configured for. dcrd's default
+// is 125.
+const maxDCRDPeers = 1000
+
+// Settings are the user's settings for the services.
+type Settings struct {
+	DCRD DCRDUserSettings
+}
+
+type settingsResponse struct {
+	Settings *Settings
+	Err      string
+}
+
+type setSettingsResponse struct {
+	// Restarted is true if dcrd was restarted to apply the settings.
+	Restarted bool
+	Err       string
+}
+
+// validate checks the settings, and cleans up the peer lists.
+func (s *DCRDUserSettings) validate() error {
+	// dcrwallet shares the debug level, so a dcrd subsystem spec would break
+	// dcrwallet.
+	if _, ok := slog.LevelFromString(s.DebugLevel); !ok {
+		return fmt.Errorf("Unknown debug level %q", s.DebugLevel)
+	}
+	if s.AddrIndex && !s.TxIndex {
+		return fmt.Errorf("The address index requires the transaction index")
+	}
+	if s.MaxPeers < 0 || s.MaxPeers > maxDCRDPeers {
+		return fmt.Errorf("Max peers must be between 0 and %d", maxDCRDPeers)
+	}
+	cleanPeers := func(peers []string) ([]string, error) {
+		var cleaned []string
+		for _, p := range peers {
+			p = strings.TrimSpace(p)
+			if p == "" {
+				continue
+			}
+			host := p
+			if h, port, err := net.SplitHostPort(p); err == nil {
+				if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+					return nil, fmt.Errorf("Invalid port in peer address %q", p)
+				}
+				host = h
+			}
+			if host == "" || strings.ContainsAny(host, " /") {
+				return nil, fmt.Errorf("Invalid peer address %q", p)
+			}
+			cleaned = append(cleaned, p)
+		}
+		return cleaned, nil
+	}
+	var err error
+	if s.AddPeers, err = cleanPeers(s.AddPeers); err != nil {
+		return err
+	}
+	if s.ConnectPeers, err = cleanPeers(s.ConnectPeers); err != nil {
+		return err
+	}
+	if len(s.AddPeers) > 0 && len(s.ConnectPeers) > 0 {
+		return fmt.Errorf("Persistent peers and connect-only peers can't both be set")
+	}
+	s.DataDir = strings.TrimSpace(s.DataDir)
+	if s.DataDir != "" && !filepath.IsAbs(s.DataDir) {
+		return fmt.Errorf("The data directory must be an absolute path")
+	}
+	return nil
+}
+
+// args are the dcrd arguments for the settings.
+func (s *DCRDUserSettings) args() []string {
+	args := []string{fmt.Sprintf("--debuglevel=%s", s.DebugLevel)}
+	if s.TxIndex {
+		args = append(args, "--txindex")
+	}
+	if s.AddrIndex {
+		args = append(args, "--addrindex")
+	}
+	if s.MaxPeers > 0 {
+		args = append(args, fmt.Sprintf("--maxpeers=%d", s.MaxPeers))
+	}
+	for _, p := range s.AddPeers {
+		args = append(args, fmt.Sprintf("--addpeer=%s", p))
+	}
+	for _, p := range s.ConnectPeers {
+		args = append(args, fmt.Sprintf("--connect=%s", p))
+	}
+	if s.BlocksOnly {
+		args = append(args, "--blocksonly")
+	}
+	if s.DataDir != "" {
+		args = append(args, fmt.Sprintf("--datadir=\"%s\"", s.DataDir))
+	}
+	return args
+}
+
+// needsRestart checks whether changing the settings from old requires
+// restarting dcrd. The debug level can be changed over RPC.
+func (s DCRDUserSettings) needsRestart(old DCRDUserSettings) bool {
+	s.DebugLevel, old.DebugLevel = "", ""
+	return !reflect.DeepEqual(s.args(), old.args())
+}
+
+func (eco *Eco) saveDCRDState() error {
+	return eco.db.EncodeStore(svcKey(dcrd), &eco.dcrd.DCRDState)
+}
+
+// settings are the current settings.
+func (eco *Eco) settings() *Settings {
+	eco.stateMtx.RLock()
+	defer eco.stateMtx.RUnlock()
+	s := &Settings{DCRD: eco.dcrd.UserSettings}
+	s.DCRD.AddPeers = append([]string(nil), s.DCRD.AddPeers...)
+	s.DCRD.ConnectPeers = append([]string(nil), s.DCRD.ConnectPeers...)
+	return s
+}
+
+// setSettings validates and saves the settings. If dcrd is running, a new
+// debug level is set over RPC, and dcrd is restarted for any other change.
+func (eco *Eco) setSettings(s *Settings) (restarted bool, err error) {
+	newSettings := s.DCRD
+	if err := newSettings.validate(); err != nil {
+		return false, err
+	}
+
+	eco.stateMtx.Lock()
+	old := eco.dcrd.UserSettings
+	eco.dcrd.UserSettings = newSettings
+	err = eco.saveDCRDState()
+	if err != nil {
+		eco.dcrd.UserSettings = old
+	}
+	// Only the bundled dcrd uses the settings.
+	local := eco.state.Eco.SyncMode == SyncModeFull
+	eco.stateMtx.Unlock()
+	if err != nil {
+		return false, fmt.Errorf("Error saving dcrd settings: %w", err)
+	}
+
+	if !local || !eco.sup.running(dcrd) {
+		return false, nil
+	}
+	if newSettings.needsRestart(old) {
+		if err := eco.restartService(dcrd); err != nil {
+			return false, fmt.Errorf("Settings saved, but dcrd could not be restarted: %w", err)
+		}
+		return true, nil
+	}
+	if newSettings.DebugLevel != old.DebugLevel {
+		// dcrwallet picks up the debug level the next time it starts.
+		cl := eco.dcrdRPC()
+		if cl == nil {
+			return false, nil
+		}
+		eco.runContext(probeTimeout, func(ctx context.Context) {
+			_, err = cl.DebugLevel(ctx, newSettings.DebugLevel)
+		})
+		if err != nil {
+			return false, fmt.Errorf("Settings saved, but the dcrd debug level could not be set: %w", err)
+		}
+	}
+	return false, nil
+}
+
+// GetSettings gets the service settings.
+func GetSettings(ctx context.Context) (*Settings, error) {
+	resp := new(settingsResponse)
+	err := request(ctx, routeGetSettings, struct{}{}, resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Err != "" {
+		return nil, fmt.Errorf(resp.Err)
+	}
+	return resp.Settings, nil
+}
+
+// SetSettings saves the service settings, and applies them to a running dcrd.
+// restarted is true if dcrd was restarted to apply them.
+func SetSettings(ctx context.Context, s *Settings) (restarted bool, err error) {
+	resp := new(setSettingsResponse)
+	err = request(ctx, routeSetSettings, s, resp)
+	if err != nil {
+		return false, err
+	}
+	if resp.Err != "" {
+		return resp.Restarted, fmt.Errorf(resp.Err)
+	}
+	return resp.Restarted, nil
+}
@@ -0,0 +1,131 @@
+package eco
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/buck54321/eco/db"
+	"github.com/decred/slog"
+)
+
+func TestDCRDSettingsValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings DCRDUserSettings
+		wantErr  bool
+	}{
+		{name: "defaults", settings: dcrdDefaultUserSettings()},
+		{name: "bad level", settings: DCRDUserSettings{DebugLevel: "loud"}, wantErr: true},
+		{name: "subsystem level", settings: DCRDUserSettings{DebugLevel: "PEER=trace"}, wantErr: true},
+		{name: "addrindex without txindex", settings: DCRDUserSettings{DebugLevel: "info", AddrIndex: true}, wantErr: true},
+		{name: "indexes", settings: DCRDUserSettings{DebugLevel: "info", TxIndex: true, AddrIndex: true}},
+		{name: "negative peers", settings: DCRDUserSettings{DebugLevel: "info", MaxPeers: -1}, wantErr: true},
+		{name: "too many peers", settings: DCRDUserSettings{DebugLevel: "info", MaxPeers: maxDCRDPeers + 1}, wantErr: true},
+		{
+			name:     "add and connect",
+			settings: DCRDUserSettings{DebugLevel: "info", AddPeers: []string{"1.2.3.4"}, ConnectPeers: []string{"5.6.7.8"}},
+			wantErr:  true,
+		},
+		{name: "bad peer", settings: DCRDUserSettings{DebugLevel: "info", AddPeers: []string{"http://1.2.3.4"}}, wantErr: true},
+		{name: "relative datadir", settings: DCRDUserSettings{DebugLevel: "info", DataDir: "data"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		err := tt.settings.validate()
+		if tt.wantErr != (err != nil) {
+			t.Fatalf("%s: wantErr = %t, err = %v", tt.name, tt.wantErr, err)
+		}
+	}
+
+	// Empty peers are dropped.
+	s := DCRDUserSettings{DebugLevel: "info", AddPeers: []string{" 1.2.3.4:9108 ", "", "[::1]"}}
+	if err := s.validate(); err != nil {
+		t.Fatalf("validate error: %v", err)
+	}
+	if exp := []string{"1.2.3.4:9108", "[::1]"}; !reflect.DeepEqual(s.AddPeers, exp) {
+		t.Fatalf("Expected peers %v, got %v", exp, s.AddPeers)
+	}
+}
+
+func TestDCRDSettingsArgs(t *testing.T) {
+	s := DCRDUserSettings{
+		DebugLevel:   "info",
+		TxIndex:      true,
+		AddrIndex:    true,
+		MaxPeers:     20,
+		ConnectPeers: []string{"1.2.3.4", "5.6.7.8"},
+		BlocksOnly:   true,
+		DataDir:      "/data/dcrd",
+	}
+	exp := []string{
+		"--debuglevel=info",
+		"--txindex",
+		"--addrindex",
+		"--maxpeers=20",
+		"--connect=1.2.3.4",
+		"--connect=5.6.7.8",
+		"--blocksonly",
+		`--datadir="/data/dcrd"`,
+	}
+	if args := s.args(); !reflect.DeepEqual(args, exp) {
+		t.Fatalf("Expected args %v, got %v", exp, args)
+	}
+
+	levelOnly := s
+	levelOnly.DebugLevel = "trace"
+	if levelOnly.needsRestart(s) {
+		t.Fatalf("Restart required for a debug level change")
+	}
+	peers := s
+	peers.MaxPeers = 8
+	if !peers.needsRestart(s) {
+		t.Fatalf("No restart required for a max peers change")
+	}
+}
+
+func TestSetSettings(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(tmpDir)
+	dbb, err := db.NewDB(filepath.Join(tmpDir, "eco.db"), slog.Disabled)
+	if err != nil {
+		t.Fatalf("NewDB error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	eco := &Eco{
+		db:       dbb,
+		outerCtx: ctx,
+		state:    MetaState{Eco: EcoState{SyncMode: SyncModeFull}},
+		dcrd:     &DCRD{DCRDState: *dcrdNewState()},
+		sup:      newSupervisor(ctx, nil),
+	}
+
+	_, err = eco.setSettings(&Settings{DCRD: DCRDUserSettings{DebugLevel: "info", AddrIndex: true}})
+	if err == nil {
+		t.Fatalf("No error for invalid settings")
+	}
+
+	newSettings := DCRDUserSettings{DebugLevel: "info", TxIndex: true, MaxPeers: 16}
+	restarted, err := eco.setSettings(&Settings{DCRD: newSettings})
+	if err != nil {
+		t.Fatalf("setSettings error: %v", err)
+	}
+	// dcrd isn't running.
+	if restarted {
+		t.Fatalf("Restarted dcrd that wasn't running")
+	}
+	if got := eco.settings().DCRD; !reflect.DeepEqual(got, newSettings) {
+		t.Fatalf("Expected settings %+v, got %+v", newSettings, got)
+	}
+
+	stored := new(DCRDState)
+	if _, err := dbb.FetchDecode(svcKey(dcrd), stored); err != nil {
+		t.Fatalf("FetchDecode error: %v", err)
+	}
+	if !reflect.DeepEqual(stored.UserSettings, newSettings) {
+		t.Fatalf("Expected stored settings %+v, got %+v", newSettings, stored.UserSettings)
+	}
+}
@@ -0,0 +1,104 @@
+package eco
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/openpgp"
+)
+
+// signatureSuffix is the file name suffix of the detached, armored PGP
+// signatures published alongside the release manifests.
+const signatureSuffix = ".asc"
+
+var (
+	// releaseSigningKeys are the armored PGP public keys trusted to sign the
+	// decred-binaries release manifests. A manifest signed by any one of these
+	// keys is accepted. To rotate keys, add the new key here in a release
+	// before the new key is put into use, and remove the old key once it has
+	// been retired.
+	//
+	// TODO: Add the Decred release signing key (release@decred.org). Until
+	// a key is added here, or to the extraReleaseKeysPath file with
+	// TrustExtraKeys set, every manifest will fail verification.
+	releaseSigningKeys = []string{}
+
+	// extraReleaseKeysPath is an optional file of armored PGP public keys to
+	// trust in addition to the releaseSigningKeys, if the user has opted in
+	// with ReleaseSourceConfig.TrustExtraKeys. This allows a new signing key
+	// to be trusted without upgrading Eco, and allows mirrors with their own
+	// signing keys.
+	extraReleaseKeysPath = filepath.Join(AppDir, "release-keys.asc")
+)
+
+// releaseKeyring parses the trusted release signing keys. The keys in the
+// extraReleaseKeysPath file are only loaded if trustExtra is true, and their
+// identities are returned so the user can be told about them. An error is
+// returned if there are no keys, since no manifest could be verified.
+func releaseKeyring(trustExtra bool) (keyring openpgp.EntityList, extra []string, err error) {
+	for i, armored := range releaseSigningKeys {
+		keys, err := openpgp.ReadArmoredKeyRing(strings.NewReader(armored))
+		if err != nil {
+			return nil, nil, fmt.Errorf("Error parsing release signing key %d: %w", i, err)
+		}
+		keyring = append(keyring, keys...)
+	}
+
+	b, err := ioutil.ReadFile(extraReleaseKeysPath)
+	switch {
+	case err == nil && !trustExtra:
+		log.Warnf("Ignoring release signing keys in %s. Extra keys are not trusted", extraReleaseKeysPath)
+	case err == nil:
+		keys, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(b))
+		if err != nil {
+			return nil, nil, fmt.Errorf("Error parsing release signing keys from %s: %w", extraReleaseKeysPath, err)
+		}
+		for _, k := range keys {
+			id := fmt.Sprintf("%X", k.PrimaryKey.Fingerprint)
+			for name := range k.Identities {
+				id = fmt.Sprintf("%s (%s)", name, id)
+				break
+			}
+			log.Warnf("Trusting extra release signing key %s from %s", id, extraReleaseKeysPath)
+			extra = append(extra, id)
+		}
+		keyring = append(keyring, keys...)
+	case !os.IsNotExist(err):
+		return nil, nil, fmt.Errorf("Error reading release signing keys file: %w", err)
+	}
+
+	if len(keyring) == 0 {
+		return nil, nil, fmt.Errorf("No release signing keys are configured")
+	}
+	return keyring, extra, nil
+}
+
+// verifyManifest checks the detached, armored signature at sigPath for the
+// manifest at manifestPath. The manifest must be signed by a key in the
+// keyring.
+func verifyManifest(keyring openpgp.EntityList, manifestPath, sigPath string) error {
+	manifest, err := os.Open(manifestPath)
+	if err != nil {
+		return fmt.Errorf("Error opening manifest: %w", err)
+	}
+	defer manifest.Close()
+	sig, err := os.Open(sigPath)
+	if err != nil {
+		return fmt.Errorf("Error opening manifest signature: %w", err)
+	}
+	defer sig.Close()
+
+	signer, err := openpgp.CheckArmoredDetachedSignature(keyring, manifest, sig)
+	if err != nil {
+		return fmt.Errorf("Signature check failed for %s: %w", filepath.Base(manifestPath), err)
+	}
+	for name := range signer.Identities {
+		log.Infof("Manifest %s signed by %s", filepath.Base(manifestPath), name)
+		break
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package eco
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+)
+
+func armoredPublicKey(t *testing.T, e *openpgp.Entity) string {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatalf("armor.Encode error: %v", err)
+	}
+	if err := e.Serialize(w); err != nil {
+		t.Fatalf("Serialize error: %v", err)
+	}
+	w.Close()
+	return buf.String()
+}
+
+func TestVerifyManifest(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(tmpDir)
+
+	defer func(keys []string, extraPath string) {
+		releaseSigningKeys = keys
+		extraReleaseKeysPath = extraPath
+	}(releaseSigningKeys, extraReleaseKeysPath)
+	releaseSigningKeys = nil
+	extraReleaseKeysPath = filepath.Join(tmpDir, "release-keys.asc")
+
+	if _, _, err := releaseKeyring(true); err == nil {
+		t.Fatalf("No error for empty keyring")
+	}
+
+	newEntity := func(name string) *openpgp.Entity {
+		e, err := openpgp.NewEntity(name, "", name+"@example.com", nil)
+		if err != nil {
+			t.Fatalf("NewEntity error: %v", err)
+		}
+		return e
+	}
+	oldKey, newKey, badKey := newEntity("old"), newEntity("new"), newEntity("bad")
+
+	// The old key is embedded, and the new key is added through the extra keys
+	// file, as it would be during a key rotation.
+	releaseSigningKeys = []string{armoredPublicKey(t, oldKey)}
+	err := ioutil.WriteFile(extraReleaseKeysPath, []byte(armoredPublicKey(t, newKey)), 0600)
+	if err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	// The extra keys are only trusted if the user opted in.
+	keyring, extra, err := releaseKeyring(false)
+	if err != nil {
+		t.Fatalf("releaseKeyring error: %v", err)
+	}
+	if len(keyring) != 1 || len(extra) != 0 {
+		t.Fatalf("Expected only the embedded key, got %d keys, %d extra", len(keyring), len(extra))
+	}
+	keyring, extra, err = releaseKeyring(true)
+	if err != nil {
+		t.Fatalf("releaseKeyring error: %v", err)
+	}
+	if len(keyring) != 2 {
+		t.Fatalf("Expected 2 keys, got %d", len(keyring))
+	}
+	if len(extra) != 1 || !strings.Contains(extra[0], "new@example.com") {
+		t.Fatalf("Extra key not reported: %v", extra)
+	}
+
+	manifest := tManifestContents
+	manifestPath := filepath.Join(tmpDir, tManifestName)
+	if err := ioutil.WriteFile(manifestPath, manifest, 0600); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+
+	sign := func(signer *openpgp.Entity, msg []byte) string {
+		var sig bytes.Buffer
+		if err := openpgp.ArmoredDetachSign(&sig, signer, bytes.NewReader(msg), nil); err != nil {
+			t.Fatalf("ArmoredDetachSign error: %v", err)
+		}
+		sigPath := filepath.Join(tmpDir, tManifestName+signatureSuffix)
+		if err := ioutil.WriteFile(sigPath, sig.Bytes(), 0600); err != nil {
+			t.Fatalf("WriteFile error: %v", err)
+		}
+		return sigPath
+	}
+
+	for _, signer := range []*openpgp.Entity{oldKey, newKey} {
+		if err := verifyManifest(keyring, manifestPath, sign(signer, manifest)); err != nil {
+			t.Fatalf("verifyManifest error: %v", err)
+		}
+	}
+
+	// Signed by an untrusted key.
+	if err := verifyManifest(keyring, manifestPath, sign(badKey, manifest)); err == nil {
+		t.Fatalf("No error for untrusted signer")
+	}
+
+	// Tampered manifest.
+	tampered := append([]byte("00"), manifest[2:]...)
+	if err := verifyManifest(keyring, manifestPath, sign(oldKey, tampered)); err == nil {
+		t.Fatalf("No error for tampered manifest")
+	}
+
+	// Missing signature.
+	if err := verifyManifest(keyring, manifestPath, filepath.Join(tmpDir, "nope.asc")); err == nil {
+		t.Fatalf("No error for missing signature")
+	}
+}
@@ -0,0 +1,268 @@
+package eco
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ReleaseSource is a source of decred-binaries releases and their assets.
+type ReleaseSource interface {
+	// Releases fetches the available releases, sorted newest first.
+	Releases(ctx context.Context) ([]*githubRelease, error)
+	// Fetch retrieves the asset into dir, and returns the path of the
+	// retrieved file. Byte progress is reported to prog, which can be nil.
+	Fetch(ctx context.Context, asset *githubAsset, dir string, prog *progressReporter) (string, error)
+}
+
+// ReleaseSourceType is the type of ReleaseSource.
+type ReleaseSourceType uint8
+
+const (
+	// ReleaseSourceGitHub is the default, and retrieves releases from the
+	// GitHub API.
+	ReleaseSourceGitHub ReleaseSourceType = iota
+	// ReleaseSourceMirror retrieves releases from a mirror of the GitHub
+	// releases. See newMirrorSource for the expected layout.
+	ReleaseSourceMirror
+	// ReleaseSourceDirectory retrieves releases from a local directory. See
+	// newDirSource for the expected layout.
+	ReleaseSourceDirectory
+)
+
+func (t ReleaseSourceType) String() string {
+	switch t {
+	case ReleaseSourceGitHub:
+		return "github"
+	case ReleaseSourceMirror:
+		return "mirror"
+	case ReleaseSourceDirectory:
+		return "directory"
+	}
+	return "unknown"
+}
+
+// ReleaseSourceConfig is the user's release source configuration.
+type ReleaseSourceConfig struct {
+	Type ReleaseSourceType
+	// Location is the mirror base URL or the directory path. Location is
+	// ignored for ReleaseSourceGitHub.
+	Location string
+	// TrustExtraKeys trusts the signing keys in the extraReleaseKeysPath
+	// file, e.g. a mirror's own key. The file is ignored otherwise, since
+	// anything that can write to the AppDir could add a key.
+	TrustExtraKeys bool
+}
+
+// newReleaseSource creates the ReleaseSource for the configuration.
+func newReleaseSource(cfg *ReleaseSourceConfig) (ReleaseSource, error) {
+	switch cfg.Type {
+	case ReleaseSourceGitHub:
+		return newGitHubSource(releasesURL), nil
+	case ReleaseSourceMirror:
+		return newMirrorSource(cfg.Location)
+	case ReleaseSourceDirectory:
+		return newDirSource(cfg.Location)
+	}
+	return nil, fmt.Errorf("Unknown release source type %d", cfg.Type)
+}
+
+// githubSource is a ReleaseSource for the GitHub releases API.
+type githubSource struct {
+	url string
+}
+
+func newGitHubSource(url string) *githubSource {
+	return &githubSource{url: url}
+}
+
+// Releases fetches the releases from the GitHub API.
+func (src *githubSource) Releases(ctx context.Context) ([]*githubRelease, error) {
+	return fetchReleases(ctx, src.url)
+}
+
+// Fetch downloads the asset from the URL provided by the GitHub API.
+func (src *githubSource) Fetch(ctx context.Context, asset *githubAsset, dir string, prog *progressReporter) (string, error) {
+	return fetchAsset(ctx, dir, asset.URL, asset.Name, int64(asset.Size), prog)
+}
+
+// mirrorSource is a ReleaseSource for a mirror of the GitHub releases.
+type mirrorSource struct {
+	base string
+}
+
+// newMirrorSource creates a mirrorSource. The mirror is expected to serve the
+// release list, in the same JSON format as the GitHub API, at
+// [base]/releases, and the assets at [base]/download/[release]/[asset name].
+// Asset URLs in the release list are ignored.
+func newMirrorSource(base string) (*mirrorSource, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing mirror URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("Mirror URL must be http or https, got %q", base)
+	}
+	return &mirrorSource{base: strings.TrimRight(base, "/")}, nil
+}
+
+// Releases fetches the releases from the mirror, and points the asset URLs at
+// the mirror's download path.
+func (src *mirrorSource) Releases(ctx context.Context) ([]*githubRelease, error) {
+	releases, err := fetchReleases(ctx, src.base+"/releases")
+	if err != nil {
+		return nil, err
+	}
+	for _, release := range releases {
+		for _, asset := range release.Assets {
+			asset.URL = src.base + "/download/" + url.PathEscape(release.Name) + "/" + url.PathEscape(asset.Name)
+			asset.BrowserDownloadURL = asset.URL
+		}
+	}
+	return releases, nil
+}
+
+// Fetch downloads the asset from the mirror.
+func (src *mirrorSource) Fetch(ctx context.Context, asset *githubAsset, dir string, prog *progressReporter) (string, error) {
+	return fetchAsset(ctx, dir, asset.URL, asset.Name, int64(asset.Size), prog)
+}
+
+// dirSource is a ReleaseSource for a local directory of archives.
+type dirSource struct {
+	dir string
+}
+
+// newDirSource creates a dirSource. The directory should have a subdirectory
+// for each release, named for the release version, e.g. v1.6.0. Each release
+// directory holds the archives and *-manifest.txt files for that release. A
+// release with a hyphenated version, e.g. v1.6.0-rc3, is a pre-release.
+func newDirSource(dir string) (*dirSource, error) {
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading release directory: %w", err)
+	}
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+	return &dirSource{dir: dir}, nil
+}
+
+// Releases lists the releases in the directory. The publish times are the
+// release directories' modification times.
+func (src *dirSource) Releases(ctx context.Context) ([]*githubRelease, error) {
+	releaseDirs, err := ioutil.ReadDir(src.dir)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading release directory: %w", err)
+	}
+	releases := make([]*githubRelease, 0, len(releaseDirs))
+	for _, releaseDir := range releaseDirs {
+		if !releaseDir.IsDir() {
+			continue
+		}
+		name := releaseDir.Name()
+		fis, err := ioutil.ReadDir(filepath.Join(src.dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("Error reading release %s: %w", name, err)
+		}
+		release := &githubRelease{
+			Name:       name,
+			Prerelease: strings.Contains(name, "-"),
+			Published:  releaseDir.ModTime(),
+		}
+		for _, fi := range fis {
+			if !fi.Mode().IsRegular() {
+				continue
+			}
+			release.Assets = append(release.Assets, &githubAsset{
+				URL:       filepath.Join(src.dir, name, fi.Name()),
+				Name:      fi.Name(),
+				Size:      uint32(fi.Size()),
+				CreatedAt: fi.ModTime(),
+				UpdatedAt: fi.ModTime(),
+			})
+		}
+		releases = append(releases, release)
+	}
+	sort.Slice(releases, func(i, j int) bool {
+		return releases[i].Published.After(releases[j].Published)
+	})
+	return releases, nil
+}
+
+// Fetch copies the asset into dir.
+func (src *dirSource) Fetch(ctx context.Context, asset *githubAsset, dir string, prog *progressReporter) (string, error) {
+	// Make sure the asset is actually in our directory.
+	rel, err := filepath.Rel(src.dir, asset.URL)
+	if err != nil {
+		// e.g. a different volume on Windows.
+		return "", fmt.Errorf("Asset %s is not in the release directory: %w", asset.URL, err)
+	}
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("Asset %s is not in the release directory", asset.URL)
+	}
+	f, err := os.Open(asset.URL)
+	if err != nil {
+		return "", fmt.Errorf("Error opening asset: %w", err)
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return "", fmt.Errorf("Error reading asset: %w", err)
+	}
+
+	tgt := filepath.Join(dir, asset.Name)
+	payload, err := os.OpenFile(tgt, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+	if err != nil {
+		return "", fmt.Errorf("Error creating file: %w", err)
+	}
+	defer payload.Close()
+
+	log.Infof("Copying %q to %q", asset.URL, tgt)
+	w := &progressWriter{
+		w:     payload,
+		name:  asset.Name,
+		total: fi.Size(),
+		prog:  prog,
+	}
+	_, err = io.Copy(w, f)
+	if err != nil {
+		return "", fmt.Errorf("Error copying asset: %w", err)
+	}
+	w.reportProgress()
+	return tgt, nil
+}
+
+// fetchReleases fetches the releases from a GitHub-style API endpoint.
+func fetchReleases(ctx context.Context, url string) ([]*githubRelease, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Error preparing request: %w", err)
+	}
+	resp, err := webClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Error fetching releases: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Error fetching releases: %s", resp.Status)
+	}
+
+	var releases []*githubRelease
+	err = json.NewDecoder(resp.Body).Decode(&releases)
+	if err != nil {
+		return nil, fmt.Errorf("JSON decode error: %w", err)
+	}
+	//  Should already be sorted newest first, but just make sure.
+	sort.Slice(releases, func(i, j int) bool {
+		return releases[i].Published.After(releases[j].Published)
+	})
+	return releases, nil
+}
@@ -0,0 +1,215 @@
+package eco
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var (
+	tManifestName     = "decred-v1.6.0-manifest.txt"
+	tManifestContents = []byte("c33b26de3c5f2b24a5d423cbdc631405f591776596052e5cf5fd9669f3e5e5cf  decred-linux-amd64-v1.6.0.tar.gz\n")
+)
+
+func testReleaseList(assetURL string) []*githubRelease {
+	return []*githubRelease{
+		{
+			Name:       "v1.6.0-rc3",
+			Prerelease: true,
+			Published:  time.Unix(1600000000, 0),
+		},
+		{
+			Name:      "v1.6.0",
+			Published: time.Unix(1610000000, 0),
+			Assets: []*githubAsset{
+				{
+					Name: tManifestName,
+					URL:  assetURL,
+				},
+			},
+		},
+	}
+}
+
+func checkFetch(t *testing.T, src ReleaseSource, asset *githubAsset) {
+	t.Helper()
+	tmpDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(tmpDir)
+	defer tempDownloadDirs(tmpDir)()
+	path, err := src.Fetch(context.Background(), asset, tmpDir, nil)
+	if err != nil {
+		t.Fatalf("Fetch error: %v", err)
+	}
+	if filepath.Dir(path) != tmpDir {
+		t.Fatalf("Asset fetched to wrong directory %s", path)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Error reading fetched asset: %v", err)
+	}
+	if string(b) != string(tManifestContents) {
+		t.Fatalf("Wrong asset contents %q", string(b))
+	}
+}
+
+func checkReleases(t *testing.T, releases []*githubRelease) *githubRelease {
+	t.Helper()
+	if len(releases) != 2 {
+		t.Fatalf("Expected 2 releases, got %d", len(releases))
+	}
+	if releases[0].Name != "v1.6.0" || releases[1].Name != "v1.6.0-rc3" {
+		t.Fatalf("Releases not sorted newest first: %s, %s", releases[0].Name, releases[1].Name)
+	}
+	if !releases[1].Prerelease {
+		t.Fatalf("Release candidate not marked as a pre-release")
+	}
+	release := releases[0]
+	if len(release.Assets) != 1 || release.Assets[0].Name != tManifestName {
+		t.Fatalf("Wrong assets for release %s", release.Name)
+	}
+	return release
+}
+
+func TestGitHubSource(t *testing.T) {
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/releases":
+			json.NewEncoder(w).Encode(testReleaseList(ts.URL + "/assets/1"))
+		case "/assets/1":
+			if r.Header.Get("Accept") != "application/octet-stream" {
+				w.WriteHeader(http.StatusNotAcceptable)
+				return
+			}
+			w.Write(tManifestContents)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	src := newGitHubSource(ts.URL + "/releases")
+	releases, err := src.Releases(context.Background())
+	if err != nil {
+		t.Fatalf("Releases error: %v", err)
+	}
+	release := checkReleases(t, releases)
+	checkFetch(t, src, release.Assets[0])
+
+	// A missing asset should be an error, not an empty file.
+	tmpDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(tmpDir)
+	defer tempDownloadDirs(tmpDir)()
+	_, err = src.Fetch(context.Background(), &githubAsset{Name: "missing", URL: ts.URL + "/assets/2"}, tmpDir, nil)
+	if err == nil {
+		t.Fatalf("No error fetching missing asset")
+	}
+}
+
+func TestMirrorSource(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/mirror/releases":
+			// The asset URLs from the mirror's release list are ignored.
+			json.NewEncoder(w).Encode(testReleaseList("https://api.github.com/nope"))
+		case "/mirror/download/v1.6.0/" + tManifestName:
+			w.Write(tManifestContents)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	if _, err := newMirrorSource("ftp://example.com"); err == nil {
+		t.Fatalf("No error for non-http mirror URL")
+	}
+
+	src, err := newMirrorSource(ts.URL + "/mirror/")
+	if err != nil {
+		t.Fatalf("newMirrorSource error: %v", err)
+	}
+	releases, err := src.Releases(context.Background())
+	if err != nil {
+		t.Fatalf("Releases error: %v", err)
+	}
+	release := checkReleases(t, releases)
+	checkFetch(t, src, release.Assets[0])
+}
+
+func TestDirSource(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(dir)
+
+	for _, release := range testReleaseList("") {
+		releaseDir := filepath.Join(dir, release.Name)
+		os.Mkdir(releaseDir, 0755)
+		for _, asset := range release.Assets {
+			err := ioutil.WriteFile(filepath.Join(releaseDir, asset.Name), tManifestContents, 0644)
+			if err != nil {
+				t.Fatalf("WriteFile error: %v", err)
+			}
+		}
+		if err := os.Chtimes(releaseDir, release.Published, release.Published); err != nil {
+			t.Fatalf("Chtimes error: %v", err)
+		}
+	}
+	// Stray files in the top directory are ignored.
+	ioutil.WriteFile(filepath.Join(dir, "README"), []byte("hi"), 0644)
+
+	if _, err := newDirSource(filepath.Join(dir, "README")); err == nil {
+		t.Fatalf("No error for non-directory release source")
+	}
+
+	src, err := newDirSource(dir)
+	if err != nil {
+		t.Fatalf("newDirSource error: %v", err)
+	}
+	releases, err := src.Releases(context.Background())
+	if err != nil {
+		t.Fatalf("Releases error: %v", err)
+	}
+	release := checkReleases(t, releases)
+	checkFetch(t, src, release.Assets[0])
+
+	if selectRelease(releases, ReleaseChannelStable) != release {
+		t.Fatalf("Wrong stable release selected")
+	}
+
+	// Assets outside of the directory are rejected.
+	tmpDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(tmpDir)
+	_, err = src.Fetch(context.Background(), &githubAsset{Name: "passwd", URL: "/etc/passwd"}, tmpDir, nil)
+	if err == nil {
+		t.Fatalf("No error fetching asset outside of the release directory")
+	}
+	outside := filepath.Join(dir, "..", filepath.Base(tmpDir), "passwd")
+	if _, err := src.Fetch(context.Background(), &githubAsset{Name: "passwd", URL: outside}, tmpDir, nil); err == nil {
+		t.Fatalf("No error fetching asset through ..")
+	}
+
+	// A file name that only starts with .. is in the directory.
+	dotsPath := filepath.Join(dir, "..dots")
+	if err := ioutil.WriteFile(dotsPath, tManifestContents, 0644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	checkFetch(t, src, &githubAsset{Name: "..dots", URL: dotsPath})
+}
+
+func TestNewReleaseSource(t *testing.T) {
+	src, err := newReleaseSource(&ReleaseSourceConfig{})
+	if err != nil {
+		t.Fatalf("newReleaseSource error for default config: %v", err)
+	}
+	if _, ok := src.(*githubSource); !ok {
+		t.Fatalf("Default release source is not GitHub")
+	}
+	if _, err := newReleaseSource(&ReleaseSourceConfig{Type: 100}); err == nil {
+		t.Fatalf("No error for unknown release source type")
+	}
+}
@@ -0,0 +1,219 @@
+package eco
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync/atomic"
+)
+
+// defaultVersionRetention is the number of version directories kept when
+// pruning if the user hasn't set a retention.
+const defaultVersionRetention = 3
+
+// versionDirPattern matches the names of the version directories in EcoDir.
+var versionDirPattern = regexp.MustCompile(`^v\d+\.\d+\.\d+`)
+
+// StorageUsage is the disk usage of a directory.
+type StorageUsage struct {
+	Name  string
+	Path  string
+	Bytes int64
+	// Active is true for the version currently in use.
+	Active bool
+	// Protected is true for versions that won't be pruned, i.e. the active
+	// version and any known-good versions that Eco could roll back to.
+	Protected bool
+}
+
+// StorageReport is the disk usage of the installed versions and the
+// application data directories.
+type StorageReport struct {
+	// Versions are the installed version directories, newest first.
+	Versions   []*StorageUsage
+	Components []*StorageUsage
+	// Retention is the number of version directories kept when pruning.
+	Retention uint32
+	Total     int64
+}
+
+type storageResponse struct {
+	Report *StorageReport
+	Err    string
+}
+
+type pruneResponse struct {
+	Removed []string
+	Err     string
+}
+
+type versionRetentionRequest struct {
+	Retention uint32
+}
+
+// dirSize is the total size of the regular files in the directory. Symbolic
+// links are not followed. A missing directory has zero size.
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if fi.Mode().IsRegular() {
+			size += fi.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+// versionDirs lists the version directories in EcoDir, most recently
+// installed first.
+func versionDirs() ([]os.FileInfo, error) {
+	fis, err := ioutil.ReadDir(EcoDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("Error reading version directories: %w", err)
+	}
+	dirs := make([]os.FileInfo, 0, len(fis))
+	for _, fi := range fis {
+		if fi.IsDir() && versionDirPattern.MatchString(fi.Name()) {
+			dirs = append(dirs, fi)
+		}
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		return dirs[i].ModTime().After(dirs[j].ModTime())
+	})
+	return dirs, nil
+}
+
+// versionProtection gets the active version, the set of versions that must
+// not be pruned, and the retention.
+func (eco *Eco) versionProtection() (active string, protected map[string]bool, retention uint32) {
+	eco.stateMtx.RLock()
+	defer eco.stateMtx.RUnlock()
+	active = eco.state.Eco.Version
+	protected = map[string]bool{active: true}
+	for _, v := range eco.state.Eco.GoodVersions {
+		protected[v] = true
+	}
+	retention = eco.state.Eco.VersionRetention
+	if retention == 0 {
+		retention = defaultVersionRetention
+	}
+	return
+}
+
+// storageReport measures the disk usage of the version directories and the
+// application data directories.
+func (eco *Eco) storageReport() (*StorageReport, error) {
+	active, protected, retention := eco.versionProtection()
+	dirs, err := versionDirs()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &StorageReport{Retention: retention}
+	measure := func(name, path string) (*StorageUsage, error) {
+		size, err := dirSize(path)
+		if err != nil {
+			return nil, fmt.Errorf("Error measuring %s: %w", path, err)
+		}
+		report.Total += size
+		return &StorageUsage{Name: name, Path: path, Bytes: size}, nil
+	}
+
+	for _, fi := range dirs {
+		u, err := measure(fi.Name(), filepath.Join(EcoDir, fi.Name()))
+		if err != nil {
+			return nil, err
+		}
+		u.Active = fi.Name() == active
+		u.Protected = protected[fi.Name()]
+		report.Versions = append(report.Versions, u)
+	}
+
+	for _, c := range []struct{ name, path string }{
+		{dcrd, dcrdAppDir},
+		{dcrwallet, dcrwalletAppDir},
+		{decrediton, decreditonAppDir},
+		{dexc, dexAppDir},
+		{"chromium", chromiumDir},
+		{"partial downloads", partialDownloadDir()},
+		{"download cache", downloadCacheDir()},
+	} {
+		u, err := measure(c.name, c.path)
+		if err != nil {
+			return nil, err
+		}
+		report.Components = append(report.Components, u)
+	}
+	if dataDir := eco.settings().DCRD.DataDir; dataDir != "" {
+		u, err := measure("dcrd data", dataDir)
+		if err != nil {
+			return nil, err
+		}
+		report.Components = append(report.Components, u)
+	}
+	return report, nil
+}
+
+// pruneVersions deletes the oldest version directories, keeping the
+// retention number of most recently installed versions. The active version
+// and the known-good versions are never deleted, but do count toward the
+// retention.
+func (eco *Eco) pruneVersions() ([]string, error) {
+	// Don't delete anything out from under an upgrade or rollback.
+	if !atomic.CompareAndSwapUint32(&upgrading, 0, 1) {
+		return nil, fmt.Errorf("Upgrade in progress")
+	}
+	defer atomic.StoreUint32(&upgrading, 0)
+
+	_, protected, retention := eco.versionProtection()
+	dirs, err := versionDirs()
+	if err != nil {
+		return nil, err
+	}
+
+	var kept uint32
+	for _, fi := range dirs {
+		if protected[fi.Name()] {
+			kept++
+		}
+	}
+
+	var removed []string
+	for _, fi := range dirs {
+		version := fi.Name()
+		if protected[version] {
+			continue
+		}
+		if kept < retention {
+			kept++
+			continue
+		}
+		log.Infof("Pruning version %s", version)
+		if err := os.RemoveAll(filepath.Join(EcoDir, version)); err != nil {
+			return removed, fmt.Errorf("Error removing version %s: %w", version, err)
+		}
+		removed = append(removed, version)
+	}
+	return removed, nil
+}
+
+// setVersionRetention sets and saves the number of version directories kept
+// when pruning. Zero resets the retention to the default.
+func (eco *Eco) setVersionRetention(retention uint32) error {
+	eco.stateMtx.Lock()
+	defer eco.stateMtx.Unlock()
+	eco.state.Eco.VersionRetention = retention
+	return eco.saveEcoState()
+}
@@ -0,0 +1,102 @@
+package eco
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPruneVersions(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(tmpDir)
+	defer func(dir string) { EcoDir = dir }(EcoDir)
+	EcoDir = tmpDir
+
+	// Newest first.
+	versions := []string{"v1.7.0", "v1.6.2", "v1.6.1", "v1.6.0", "v1.5.1", "v1.5.0"}
+	now := time.Now()
+	for i, v := range versions {
+		dir := filepath.Join(EcoDir, v)
+		os.MkdirAll(filepath.Join(dir, decred), 0755)
+		ioutil.WriteFile(filepath.Join(dir, decred, "dcrd"), make([]byte, 100), 0755)
+		stamp := now.Add(-time.Hour * time.Duration(i))
+		if err := os.Chtimes(dir, stamp, stamp); err != nil {
+			t.Fatalf("Chtimes error: %v", err)
+		}
+	}
+	// Not a version directory.
+	os.Mkdir(filepath.Join(EcoDir, "other"), 0755)
+
+	// Running an old version, with an even older rollback version.
+	eco := &Eco{
+		state: MetaState{Eco: EcoState{
+			Version:          "v1.6.0",
+			GoodVersions:     []string{"v1.5.0"},
+			VersionRetention: 3,
+		}},
+		dcrd: &DCRD{DCRDState: *dcrdNewState()},
+	}
+
+	report, err := eco.storageReport()
+	if err != nil {
+		t.Fatalf("storageReport error: %v", err)
+	}
+	if len(report.Versions) != len(versions) {
+		t.Fatalf("Expected %d versions in report, got %d", len(versions), len(report.Versions))
+	}
+	for i, u := range report.Versions {
+		if u.Name != versions[i] {
+			t.Fatalf("Wrong version at index %d. Expected %s, got %s", i, versions[i], u.Name)
+		}
+		if u.Bytes != 100 {
+			t.Fatalf("Wrong size for %s. Expected 100, got %d", u.Name, u.Bytes)
+		}
+		if u.Active != (u.Name == "v1.6.0") {
+			t.Fatalf("Wrong active flag for %s", u.Name)
+		}
+		if u.Protected != (u.Name == "v1.6.0" || u.Name == "v1.5.0") {
+			t.Fatalf("Wrong protected flag for %s", u.Name)
+		}
+	}
+
+	removed, err := eco.pruneVersions()
+	if err != nil {
+		t.Fatalf("pruneVersions error: %v", err)
+	}
+	// The two protected versions plus the newest version are kept.
+	expRemoved := []string{"v1.6.2", "v1.6.1", "v1.5.1"}
+	if len(removed) != len(expRemoved) {
+		t.Fatalf("Expected %d removed versions, got %v", len(expRemoved), removed)
+	}
+	for i, v := range expRemoved {
+		if removed[i] != v {
+			t.Fatalf("Wrong removed version at index %d. Expected %s, got %s", i, v, removed[i])
+		}
+		if fileExists(filepath.Join(EcoDir, v)) {
+			t.Fatalf("Version directory %s not removed", v)
+		}
+	}
+	for _, v := range []string{"v1.7.0", "v1.6.0", "v1.5.0"} {
+		if !fileExists(filepath.Join(EcoDir, v)) {
+			t.Fatalf("Version directory %s removed", v)
+		}
+	}
+	if !fileExists(filepath.Join(EcoDir, "other")) {
+		t.Fatalf("Non-version directory removed")
+	}
+
+	// Protected versions are kept even if they exceed the retention.
+	eco.state.Eco.VersionRetention = 1
+	removed, err = eco.pruneVersions()
+	if err != nil {
+		t.Fatalf("pruneVersions error: %v", err)
+	}
+	if len(removed) != 1 || removed[0] != "v1.7.0" {
+		t.Fatalf("Expected v1.7.0 to be removed, got %v", removed)
+	}
+	if !fileExists(filepath.Join(EcoDir, "v1.6.0")) || !fileExists(filepath.Join(EcoDir, "v1.5.0")) {
+		t.Fatalf("Protected version removed")
+	}
+}
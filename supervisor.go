@@ -0,0 +1,568 @@
+package eco
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	// minRestartDelay is the delay before the first restart of a crashed
+	// service. The delay doubles with each consecutive crash, up to
+	// maxRestartDelay.
+	minRestartDelay = time.Second * 5
+	maxRestartDelay = time.Minute * 5
+	// stableRunTime is how long a process must run before an exit is no longer
+	// considered consecutive with earlier crashes.
+	stableRunTime = time.Minute
+	// maxServiceCrashes is the number of consecutive crashes after which the
+	// Supervisor gives up on a service.
+	maxServiceCrashes = 5
+	// probeInterval is the time between health probes of a running service.
+	// A starting service is probed more often, every startProbeInterval.
+	probeInterval      = time.Second * 10
+	startProbeInterval = time.Second
+	probeTimeout       = time.Second * 5
+	// maxProbeFailures is the number of consecutive failed health probes after
+	// which a running service is restarted.
+	maxProbeFailures = 3
+	// connectRetryDelay is the time between attempts to connect to a service.
+	connectRetryDelay = time.Second * 5
+	// shutdownTimeout is how long a service has to exit after being asked to
+	// before its process is killed.
+	shutdownTimeout = time.Second * 60
+)
+
+// serviceSpec describes how to run and monitor a supervised service. Only
+// name is required. A service without an exe is external, e.g. a remote dcrd.
+// An external service is connected to and monitored, but has no process to
+// run, restart or stop.
+type serviceSpec struct {
+	name string
+	// deps are the services that must signal readiness before this service's
+	// process is started.
+	deps []string
+	// noRestart is for applications that the user closes when they're done
+	// with them, e.g. Decrediton. Any exit stops the service.
+	noRestart bool
+	// exe prepares the process. exe is called before every start, so the
+	// process picks up e.g. a new version directory.
+	exe func() (*serviceExe, error)
+	// connect is retried once the process has first started, until it
+	// succeeds, e.g. until an RPC client is created.
+	connect func() error
+	// disconnect is called after the service is stopped.
+	disconnect func()
+	// monitor is run after connect succeeds, until the service is stopped.
+	// monitor calls ready to signal the services that depend on it.
+	monitor func(ctx context.Context, ready func())
+	// probe checks the health of the service. A service without a probe is
+	// considered running as soon as its process starts.
+	probe func(ctx context.Context) error
+	// shutdown asks the process to exit, e.g. with a stop RPC. Without a
+	// shutdown, the process is sent an interrupt signal.
+	shutdown func(ctx context.Context) error
+}
+
+// supervised is the state of a service started by the Supervisor.
+type supervised struct {
+	spec *serviceSpec
+	// ctx is canceled to stop the service from restarting.
+	ctx    context.Context
+	cancel context.CancelFunc
+	// monCtx is canceled once the process has exited for good, to stop the
+	// monitor and health probes.
+	monCtx    context.Context
+	monCancel context.CancelFunc
+	wg        sync.WaitGroup
+	done      chan struct{}
+
+	mtx   sync.Mutex
+	exe   *serviceExe
+	state ServiceState
+}
+
+// Supervisor runs services, restarting them with an exponential backoff when
+// they crash. Services are started after their dependencies are ready, and
+// are stopped before the services they depend on. Every change in a
+// service's lifecycle is reported as a ServiceStatus.
+type Supervisor struct {
+	ctx    context.Context
+	status func(*ServiceStatus)
+
+	mtx      sync.Mutex
+	services map[string]*supervised
+	ready    map[string]chan struct{}
+	// exits counts the unexpected process exits for each service. Counts are
+	// kept when a service is stopped and started again.
+	exits map[string]uint32
+}
+
+func newSupervisor(ctx context.Context, status func(*ServiceStatus)) *Supervisor {
+	return &Supervisor{
+		ctx:      ctx,
+		status:   status,
+		services: make(map[string]*supervised),
+		ready:    make(map[string]chan struct{}),
+		exits:    make(map[string]uint32),
+	}
+}
+
+// start starts supervising the service. An error is returned if the service
+// is already running.
+func (s *Supervisor) start(spec *serviceSpec) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if _, found := s.services[spec.name]; found {
+		return fmt.Errorf("%s already running", spec.name)
+	}
+	svc := &supervised{
+		spec: spec,
+		done: make(chan struct{}),
+	}
+	svc.ctx, svc.cancel = context.WithCancel(s.ctx)
+	svc.monCtx, svc.monCancel = context.WithCancel(s.ctx)
+	s.services[spec.name] = svc
+	go s.run(svc)
+	return nil
+}
+
+// run is the run loop for the service.
+func (s *Supervisor) run(svc *supervised) {
+	spec := svc.spec
+	final, finalErr := ServiceStopped, ""
+	defer func() {
+		svc.monCancel()
+		svc.wg.Wait()
+		if spec.disconnect != nil {
+			spec.disconnect()
+		}
+		s.mtx.Lock()
+		if s.services[spec.name] == svc {
+			delete(s.services, spec.name)
+		}
+		// Dependents started later will wait for the service to be ready
+		// again.
+		if ch := s.ready[spec.name]; ch != nil && isClosed(ch) {
+			delete(s.ready, spec.name)
+		}
+		s.mtx.Unlock()
+		s.setState(svc, final, finalErr)
+		close(svc.done)
+	}()
+
+	for _, dep := range spec.deps {
+		s.setState(svc, ServiceWaiting, "")
+		select {
+		case <-s.readyChan(dep):
+		case <-svc.ctx.Done():
+			return
+		}
+	}
+
+	if spec.exe == nil {
+		s.runExternal(svc)
+		return
+	}
+
+	delay := minRestartDelay
+	var crashes int
+	var monitoring bool
+	for {
+		exe, err := spec.exe()
+		if err != nil {
+			log.Errorf("Error preparing %s: %v", spec.name, err)
+			final, finalErr = ServiceFailed, err.Error()
+			return
+		}
+		// Checking the Context under the lock guarantees that stop either
+		// sees this process or we see the cancellation.
+		svc.mtx.Lock()
+		if svc.ctx.Err() != nil {
+			svc.mtx.Unlock()
+			return
+		}
+		svc.exe = exe
+		svc.mtx.Unlock()
+
+		if spec.probe == nil {
+			s.setState(svc, ServiceRunning, "")
+		} else {
+			s.setState(svc, ServiceStarting, "")
+		}
+		if !monitoring {
+			monitoring = true
+			svc.wg.Add(1)
+			go s.monitor(svc)
+			if spec.probe != nil {
+				svc.wg.Add(1)
+				go s.probe(svc)
+			}
+		}
+
+		started := time.Now()
+		err = exe.Run()
+		if svc.ctx.Err() != nil {
+			return
+		}
+		if spec.noRestart {
+			if err != nil {
+				finalErr = err.Error()
+			}
+			return
+		}
+
+		s.mtx.Lock()
+		s.exits[spec.name]++
+		s.mtx.Unlock()
+
+		if time.Since(started) >= stableRunTime {
+			crashes = 0
+			delay = minRestartDelay
+		}
+		crashes++
+		if crashes >= maxServiceCrashes {
+			log.Errorf("%s exited %d times in a row. Giving up.", spec.name, crashes)
+			final, finalErr = ServiceFailed, fmt.Sprintf("exited %d times in a row", crashes)
+			return
+		}
+
+		reason := "process exited"
+		if err != nil {
+			reason = err.Error()
+		}
+		log.Warnf("%s exited (%s). Restarting in %s", spec.name, reason, delay)
+		s.setState(svc, ServiceRestarting, reason)
+		select {
+		case <-time.After(delay):
+		case <-svc.ctx.Done():
+			return
+		}
+		delay *= 2
+		if delay > maxRestartDelay {
+			delay = maxRestartDelay
+		}
+	}
+}
+
+// runExternal monitors an external service until it is stopped.
+func (s *Supervisor) runExternal(svc *supervised) {
+	if svc.spec.probe == nil {
+		s.setState(svc, ServiceRunning, "")
+	} else {
+		s.setState(svc, ServiceStarting, "")
+		svc.wg.Add(1)
+		go s.probe(svc)
+	}
+	svc.wg.Add(1)
+	go s.monitor(svc)
+	<-svc.ctx.Done()
+}
+
+// monitor connects to the service and runs the spec's monitor.
+func (s *Supervisor) monitor(svc *supervised) {
+	defer svc.wg.Done()
+	spec := svc.spec
+	if spec.connect != nil {
+		var attempts int
+		for {
+			err := spec.connect()
+			if err == nil {
+				break
+			}
+			attempts++
+			if attempts%5 == 0 {
+				log.Errorf("Error connecting to %s: %v", spec.name, err)
+			}
+			select {
+			case <-time.After(connectRetryDelay):
+			case <-svc.monCtx.Done():
+				return
+			}
+		}
+	}
+	if spec.monitor != nil {
+		spec.monitor(svc.monCtx, func() { s.setReady(spec.name) })
+	}
+}
+
+// probe runs the health probes. A running service that fails maxProbeFailures
+// probes in a row is restarted. An external service can't be restarted, so it
+// is marked unhealthy until a probe succeeds again.
+func (s *Supervisor) probe(svc *supervised) {
+	defer svc.wg.Done()
+	external := svc.spec.exe == nil
+	var failures int
+	for {
+		delay := probeInterval
+		if svc.currentState() == ServiceStarting {
+			delay = startProbeInterval
+		}
+		select {
+		case <-time.After(delay):
+		case <-svc.monCtx.Done():
+			return
+		}
+
+		state := svc.currentState()
+		if state != ServiceStarting && state != ServiceRunning && !(external && state == ServiceUnhealthy) {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(svc.monCtx, probeTimeout)
+		err := svc.spec.probe(ctx)
+		cancel()
+		if err == nil {
+			failures = 0
+			if state == ServiceStarting || state == ServiceUnhealthy {
+				s.transition(svc, state, ServiceRunning)
+			}
+			continue
+		}
+		// Services can take a while to start answering.
+		if state == ServiceStarting || state == ServiceUnhealthy {
+			continue
+		}
+		failures++
+		if failures < maxProbeFailures {
+			continue
+		}
+		failures = 0
+		if external {
+			log.Errorf("%s failed %d health probes. Last error: %v", svc.spec.name, maxProbeFailures, err)
+			s.transition(svc, ServiceRunning, ServiceUnhealthy)
+			continue
+		}
+		log.Errorf("%s failed %d health probes. Restarting. Last error: %v", svc.spec.name, maxProbeFailures, err)
+		if !s.transition(svc, ServiceRunning, ServiceUnhealthy) {
+			continue
+		}
+		svc.mtx.Lock()
+		exe := svc.exe
+		svc.mtx.Unlock()
+		// The run loop will see the exit and restart the process.
+		if err := exe.stop(shutdownTimeout); err != nil {
+			log.Errorf("Error stopping unhealthy %s: %v", svc.spec.name, err)
+		}
+	}
+}
+
+// stop stops the service and waits for its process to exit. stop is a no-op
+// for a service that isn't running.
+func (s *Supervisor) stop(name string) error {
+	s.mtx.Lock()
+	svc := s.services[name]
+	s.mtx.Unlock()
+	if svc == nil {
+		return nil
+	}
+
+	s.setState(svc, ServiceStopping, "")
+	svc.cancel()
+	svc.mtx.Lock()
+	exe := svc.exe
+	svc.mtx.Unlock()
+
+	var err error
+	if exe != nil && !isClosed(exe.done) {
+		err = s.shutdown(svc.spec, exe)
+	}
+	<-svc.done
+	return err
+}
+
+func (s *Supervisor) shutdown(spec *serviceSpec, exe *serviceExe) error {
+	if spec.shutdown == nil {
+		return exe.stop(shutdownTimeout)
+	}
+	ctx, cancel := context.WithTimeout(s.ctx, probeTimeout)
+	err := spec.shutdown(ctx)
+	cancel()
+	if err != nil {
+		log.Errorf("Error asking %s to shut down: %v", spec.name, err)
+		return exe.stop(shutdownTimeout)
+	}
+	select {
+	case <-exe.Done():
+	case <-time.After(shutdownTimeout):
+		exe.cancel()
+		return fmt.Errorf("Timed out waiting for %s to shutdown. Killing the process", spec.name)
+	}
+	return nil
+}
+
+// stopAll stops all services. Services are stopped before the services they
+// depend on.
+func (s *Supervisor) stopAll() {
+	for {
+		s.mtx.Lock()
+		var leaves, all []string
+		for name := range s.services {
+			all = append(all, name)
+			if !s.hasDependents(name) {
+				leaves = append(leaves, name)
+			}
+		}
+		s.mtx.Unlock()
+		if len(all) == 0 {
+			return
+		}
+		if len(leaves) == 0 {
+			// A dependency cycle. Shouldn't happen.
+			leaves = all
+		}
+		for _, name := range leaves {
+			if err := s.stop(name); err != nil {
+				log.Errorf("Error stopping %s: %v", name, err)
+			}
+		}
+	}
+}
+
+// stopWithDependents stops the service after stopping any running services
+// that depend on it, directly or indirectly. The services that were running
+// are returned in the order they were stopped, so the named service, if it
+// was running, is last.
+func (s *Supervisor) stopWithDependents(name string) ([]string, error) {
+	s.mtx.Lock()
+	order := s.dependents(name)
+	s.mtx.Unlock()
+
+	var stopped []string
+	for _, svcName := range append(order, name) {
+		if !s.running(svcName) {
+			continue
+		}
+		if err := s.stop(svcName); err != nil {
+			return stopped, fmt.Errorf("Error stopping %s: %w", svcName, err)
+		}
+		stopped = append(stopped, svcName)
+	}
+	return stopped, nil
+}
+
+// dependents lists the running services that depend on the named service,
+// directly or indirectly, ordered so that each service comes before the
+// services it depends on. The mtx must be held.
+func (s *Supervisor) dependents(name string) []string {
+	var order []string
+	seen := map[string]bool{name: true}
+	var visit func(string)
+	visit = func(dep string) {
+		for svcName, svc := range s.services {
+			if seen[svcName] {
+				continue
+			}
+			for _, d := range svc.spec.deps {
+				if d == dep {
+					seen[svcName] = true
+					visit(svcName)
+					order = append(order, svcName)
+					break
+				}
+			}
+		}
+	}
+	visit(name)
+	return order
+}
+
+// hasDependents checks whether any running service depends on the named
+// service. The mtx must be held.
+func (s *Supervisor) hasDependents(name string) bool {
+	for _, svc := range s.services {
+		for _, dep := range svc.spec.deps {
+			if dep == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// running checks whether the service has been started and not yet stopped.
+func (s *Supervisor) running(name string) bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	_, found := s.services[name]
+	return found
+}
+
+// exitCount is the number of unexpected process exits for the service.
+func (s *Supervisor) exitCount(name string) uint32 {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.exits[name]
+}
+
+// readyChan is closed when the service signals readiness.
+func (s *Supervisor) readyChan(name string) <-chan struct{} {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.readyChanLocked(name)
+}
+
+func (s *Supervisor) readyChanLocked(name string) chan struct{} {
+	ch, found := s.ready[name]
+	if !found {
+		ch = make(chan struct{})
+		s.ready[name] = ch
+	}
+	return ch
+}
+
+func (s *Supervisor) setReady(name string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if ch := s.readyChanLocked(name); !isClosed(ch) {
+		close(ch)
+	}
+}
+
+func (s *Supervisor) isReady(name string) bool {
+	return isClosed(s.readyChan(name))
+}
+
+func (s *Supervisor) setState(svc *supervised, state ServiceState, errStr string) {
+	svc.mtx.Lock()
+	defer svc.mtx.Unlock()
+	s.setStateLocked(svc, state, errStr)
+}
+
+// transition sets the state only if the service is in the from state.
+func (s *Supervisor) transition(svc *supervised, from, to ServiceState) bool {
+	svc.mtx.Lock()
+	defer svc.mtx.Unlock()
+	if svc.state != from {
+		return false
+	}
+	s.setStateLocked(svc, to, "")
+	return true
+}
+
+func (s *Supervisor) setStateLocked(svc *supervised, state ServiceState, errStr string) {
+	svc.state = state
+	if s.status != nil {
+		s.status(&ServiceStatus{
+			Service: svc.spec.name,
+			On:      state != ServiceStopped && state != ServiceFailed,
+			State:   state,
+			Err:     errStr,
+		})
+	}
+}
+
+func (svc *supervised) currentState() ServiceState {
+	svc.mtx.Lock()
+	defer svc.mtx.Unlock()
+	return svc.state
+}
+
+func isClosed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
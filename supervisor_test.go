@@ -0,0 +1,382 @@
+package eco
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fastSupervisor shortens the Supervisor timing for tests. The returned
+// function restores the defaults.
+func fastSupervisor() func() {
+	restartMin, restartMax, stable, crashes := minRestartDelay, maxRestartDelay, stableRunTime, maxServiceCrashes
+	probeIvl, startIvl, probeFails, connectDelay := probeInterval, startProbeInterval, maxProbeFailures, connectRetryDelay
+	minRestartDelay, maxRestartDelay, stableRunTime, maxServiceCrashes = time.Millisecond*10, time.Millisecond*40, time.Minute, 3
+	probeInterval, startProbeInterval, maxProbeFailures, connectRetryDelay = time.Millisecond*20, time.Millisecond*10, 2, time.Millisecond*10
+	return func() {
+		minRestartDelay, maxRestartDelay, stableRunTime, maxServiceCrashes = restartMin, restartMax, stable, crashes
+		probeInterval, startProbeInterval, maxProbeFailures, connectRetryDelay = probeIvl, startIvl, probeFails, connectDelay
+	}
+}
+
+type tStatusLog struct {
+	mtx      sync.Mutex
+	statuses []*ServiceStatus
+}
+
+func (l *tStatusLog) status(st *ServiceStatus) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.statuses = append(l.statuses, st)
+}
+
+// states are the states reported for the service, in order.
+func (l *tStatusLog) states(svc string) []ServiceState {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	var states []ServiceState
+	for _, st := range l.statuses {
+		if st.Service == svc {
+			states = append(states, st.State)
+		}
+	}
+	return states
+}
+
+// stopped are the services that reported ServiceStopped, in order.
+func (l *tStatusLog) stopped() []string {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	var svcs []string
+	for _, st := range l.statuses {
+		if st.State == ServiceStopped {
+			svcs = append(svcs, st.Service)
+		}
+	}
+	return svcs
+}
+
+func (l *tStatusLog) has(svc string, state ServiceState) bool {
+	for _, s := range l.states(svc) {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+func tCommand(t *testing.T, ctx context.Context, name string, args ...string) func() (*serviceExe, error) {
+	t.Helper()
+	path, err := exec.LookPath(name)
+	if err != nil {
+		t.Skipf("%s not found: %v", name, err)
+	}
+	return func() (*serviceExe, error) {
+		return newExe(ctx, path, args...), nil
+	}
+}
+
+func waitFor(t *testing.T, what string, f func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second * 10)
+	for !f() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond * 5)
+	}
+}
+
+func TestSupervisorGiveUp(t *testing.T) {
+	defer fastSupervisor()()
+	defer tLogDir(t)()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	statuses := new(tStatusLog)
+	sup := newSupervisor(ctx, statuses.status)
+	var starts int
+	crash := tCommand(t, ctx, "sh", "-c", "exit 1")
+	err := sup.start(&serviceSpec{
+		name: "crasher",
+		exe: func() (*serviceExe, error) {
+			starts++
+			return crash()
+		},
+	})
+	if err != nil {
+		t.Fatalf("start error: %v", err)
+	}
+	if err := sup.start(&serviceSpec{name: "crasher"}); err == nil {
+		t.Fatalf("No error for starting a running service")
+	}
+
+	waitFor(t, "give up", func() bool { return statuses.has("crasher", ServiceFailed) })
+	if starts != maxServiceCrashes {
+		t.Fatalf("Expected %d starts, saw %d", maxServiceCrashes, starts)
+	}
+	if n := sup.exitCount("crasher"); n != uint32(maxServiceCrashes) {
+		t.Fatalf("Expected %d exits, saw %d", maxServiceCrashes, n)
+	}
+	if !statuses.has("crasher", ServiceRestarting) {
+		t.Fatalf("No restarting status")
+	}
+	waitFor(t, "removal", func() bool { return !sup.running("crasher") })
+}
+
+func TestSupervisorDependencies(t *testing.T) {
+	defer fastSupervisor()()
+	defer tLogDir(t)()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	statuses := new(tStatusLog)
+	sup := newSupervisor(ctx, statuses.status)
+	sleep := tCommand(t, ctx, "sleep", "30")
+
+	makeReady := make(chan struct{})
+	var disconnected bool
+	err := sup.start(&serviceSpec{
+		name: "base",
+		exe:  sleep,
+		monitor: func(ctx context.Context, ready func()) {
+			select {
+			case <-makeReady:
+				ready()
+			case <-ctx.Done():
+			}
+		},
+		disconnect: func() { disconnected = true },
+	})
+	if err != nil {
+		t.Fatalf("start error for base: %v", err)
+	}
+
+	var depStarted bool
+	var mtx sync.Mutex
+	err = sup.start(&serviceSpec{
+		name: "dependent",
+		deps: []string{"base"},
+		exe: func() (*serviceExe, error) {
+			mtx.Lock()
+			depStarted = true
+			mtx.Unlock()
+			return sleep()
+		},
+		probe: func(context.Context) error { return nil },
+	})
+	if err != nil {
+		t.Fatalf("start error for dependent: %v", err)
+	}
+
+	waitFor(t, "waiting status", func() bool { return statuses.has("dependent", ServiceWaiting) })
+	time.Sleep(time.Millisecond * 50)
+	mtx.Lock()
+	if depStarted {
+		t.Fatalf("Dependent started before its dependency was ready")
+	}
+	mtx.Unlock()
+
+	close(makeReady)
+	waitFor(t, "dependent to run", func() bool { return statuses.has("dependent", ServiceRunning) })
+
+	sup.stopAll()
+	stopped := statuses.stopped()
+	if len(stopped) != 2 || stopped[0] != "dependent" || stopped[1] != "base" {
+		t.Fatalf("Wrong stop order %v", stopped)
+	}
+	if !disconnected {
+		t.Fatalf("Not disconnected")
+	}
+	if sup.running("base") || sup.running("dependent") {
+		t.Fatalf("Services still running after stopAll")
+	}
+	// Stopped services aren't crashes.
+	if sup.exitCount("base") != 0 || sup.exitCount("dependent") != 0 {
+		t.Fatalf("Stopped services counted as exits")
+	}
+	// Readiness is reset when the service stops.
+	if sup.isReady("base") {
+		t.Fatalf("Stopped service still ready")
+	}
+}
+
+func TestSupervisorProbe(t *testing.T) {
+	defer fastSupervisor()()
+	defer tLogDir(t)()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	statuses := new(tStatusLog)
+	sup := newSupervisor(ctx, statuses.status)
+	sleep := tCommand(t, ctx, "sleep", "30")
+
+	var mtx sync.Mutex
+	var starts, probes int
+	err := sup.start(&serviceSpec{
+		name: "flaky",
+		exe: func() (*serviceExe, error) {
+			mtx.Lock()
+			starts++
+			mtx.Unlock()
+			return sleep()
+		},
+		// Healthy once, then unhealthy until restarted.
+		probe: func(context.Context) error {
+			mtx.Lock()
+			defer mtx.Unlock()
+			probes++
+			if starts == 1 && probes > 1 {
+				return fmt.Errorf("unhealthy")
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("start error: %v", err)
+	}
+
+	waitFor(t, "restart", func() bool {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return starts == 2
+	})
+	if !statuses.has("flaky", ServiceUnhealthy) {
+		t.Fatalf("No unhealthy status")
+	}
+	waitFor(t, "running after restart", func() bool {
+		states := statuses.states("flaky")
+		return states[len(states)-1] == ServiceRunning
+	})
+	if err := sup.stop("flaky"); err != nil {
+		t.Fatalf("stop error: %v", err)
+	}
+	if sup.running("flaky") {
+		t.Fatalf("Service running after stop")
+	}
+}
+
+func TestSupervisorStopWithDependents(t *testing.T) {
+	defer fastSupervisor()()
+	defer tLogDir(t)()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	statuses := new(tStatusLog)
+	sup := newSupervisor(ctx, statuses.status)
+	sleep := tCommand(t, ctx, "sleep", "30")
+
+	start := func(name string, deps ...string) {
+		t.Helper()
+		err := sup.start(&serviceSpec{
+			name: name,
+			deps: deps,
+			exe:  sleep,
+			monitor: func(ctx context.Context, ready func()) {
+				ready()
+			},
+		})
+		if err != nil {
+			t.Fatalf("start error for %s: %v", name, err)
+		}
+	}
+	// top -> mid -> base, and other -> base.
+	start("base")
+	start("mid", "base")
+	start("top", "mid")
+	start("other", "base")
+	start("unrelated")
+	for _, name := range []string{"base", "mid", "top", "other", "unrelated"} {
+		name := name
+		waitFor(t, name+" to run", func() bool { return statuses.has(name, ServiceRunning) })
+	}
+
+	stopped, err := sup.stopWithDependents("mid")
+	if err != nil {
+		t.Fatalf("stopWithDependents error: %v", err)
+	}
+	if len(stopped) != 2 || stopped[0] != "top" || stopped[1] != "mid" {
+		t.Fatalf("Wrong services stopped for mid: %v", stopped)
+	}
+
+	stopped, err = sup.stopWithDependents("base")
+	if err != nil {
+		t.Fatalf("stopWithDependents error: %v", err)
+	}
+	if len(stopped) != 2 || stopped[0] != "other" || stopped[1] != "base" {
+		t.Fatalf("Wrong services stopped for base: %v", stopped)
+	}
+	if !sup.running("unrelated") {
+		t.Fatalf("Unrelated service stopped")
+	}
+
+	// A service that isn't running is not an error.
+	stopped, err = sup.stopWithDependents("base")
+	if err != nil || len(stopped) != 0 {
+		t.Fatalf("Unexpected result for stopped service: %v, %v", stopped, err)
+	}
+}
+
+func TestSupervisorExternal(t *testing.T) {
+	defer fastSupervisor()()
+	defer tLogDir(t)()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	statuses := new(tStatusLog)
+	sup := newSupervisor(ctx, statuses.status)
+
+	var mtx sync.Mutex
+	healthy := true
+	err := sup.start(&serviceSpec{
+		name: "remote",
+		monitor: func(ctx context.Context, ready func()) {
+			ready()
+			<-ctx.Done()
+		},
+		probe: func(context.Context) error {
+			mtx.Lock()
+			defer mtx.Unlock()
+			if !healthy {
+				return fmt.Errorf("unreachable")
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("start error: %v", err)
+	}
+	err = sup.start(&serviceSpec{
+		name: "local",
+		deps: []string{"remote"},
+		exe:  tCommand(t, ctx, "sleep", "30"),
+	})
+	if err != nil {
+		t.Fatalf("start error: %v", err)
+	}
+	waitFor(t, "dependent running", func() bool { return statuses.has("local", ServiceRunning) })
+	waitFor(t, "external running", func() bool { return statuses.has("remote", ServiceRunning) })
+
+	// An unhealthy external service is only marked unhealthy.
+	mtx.Lock()
+	healthy = false
+	mtx.Unlock()
+	waitFor(t, "unhealthy", func() bool { return statuses.has("remote", ServiceUnhealthy) })
+	mtx.Lock()
+	healthy = true
+	mtx.Unlock()
+	waitFor(t, "recovery", func() bool {
+		states := statuses.states("remote")
+		return states[len(states)-1] == ServiceRunning
+	})
+
+	if _, err := sup.stopWithDependents("remote"); err != nil {
+		t.Fatalf("stop error: %v", err)
+	}
+	if exp, stopped := []string{"local", "remote"}, statuses.stopped(); len(stopped) != 2 || stopped[0] != exp[0] || stopped[1] != exp[1] {
+		t.Fatalf("Expected stop order %v, got %v", exp, stopped)
+	}
+}
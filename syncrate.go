@@ -0,0 +1,79 @@
+package eco
+
+import (
+	"time"
+)
+
+const (
+	// syncRateSmoothing is the weight of the newest sample in the smoothed
+	// sync rate.
+	syncRateSmoothing = 0.3
+	// maxSyncETA caps the estimated time remaining, so that a stalled sync
+	// doesn't report a nonsense duration.
+	maxSyncETA = time.Hour * 24 * 30
+)
+
+// syncRateEstimator estimates the sync rate and time remaining from the
+// history of heights reported while polling. The rate is an exponentially
+// weighted moving average, so that one slow or fast interval doesn't swing
+// the estimate.
+type syncRateEstimator struct {
+	lastHeight int64
+	lastStamp  time.Time
+	rate       float64 // blocks per second
+	samples    int
+}
+
+// add adds a sample and returns the updated rate, in blocks per second. The
+// rate is zero until there are two samples.
+func (e *syncRateEstimator) add(height int64, stamp time.Time) float64 {
+	defer func() {
+		e.lastHeight, e.lastStamp = height, stamp
+		e.samples++
+	}()
+	if e.samples == 0 {
+		return 0
+	}
+	elapsed := stamp.Sub(e.lastStamp).Seconds()
+	if elapsed <= 0 || height < e.lastHeight {
+		// A reorg or a clock change. Start over.
+		e.rate, e.samples = 0, 0
+		return 0
+	}
+	rate := float64(height-e.lastHeight) / elapsed
+	if e.samples == 1 {
+		e.rate = rate
+	} else {
+		e.rate = syncRateSmoothing*rate + (1-syncRateSmoothing)*e.rate
+	}
+	return e.rate
+}
+
+// eta estimates the time to reach the target height at the current rate.
+// Zero is returned if there's no estimate.
+func (e *syncRateEstimator) eta(target int64) time.Duration {
+	if e.rate <= 0 || e.samples < 2 {
+		return 0
+	}
+	toGo := target - e.lastHeight
+	if toGo <= 0 {
+		return 0
+	}
+	secs := float64(toGo) / e.rate
+	if secs > maxSyncETA.Seconds() {
+		return maxSyncETA
+	}
+	return time.Duration(secs * float64(time.Second))
+}
+
+// reset clears the history, e.g. for a new sync stage.
+func (e *syncRateEstimator) reset() {
+	*e = syncRateEstimator{}
+}
+
+// update adds a sample and fills in the Progress' heights, rate and ETA.
+func (e *syncRateEstimator) update(u *Progress, height, target int64, stamp time.Time) {
+	u.Height, u.TargetHeight = height, target
+	u.BlocksPerSecond = e.add(height, stamp)
+	u.ETA = e.eta(target)
+}
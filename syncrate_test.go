@@ -0,0 +1,66 @@
+package eco
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSyncRateEstimator(t *testing.T) {
+	start := time.Unix(1600000000, 0)
+	at := func(secs int) time.Time {
+		return start.Add(time.Duration(secs) * time.Second)
+	}
+	near := func(a, b float64) bool {
+		return math.Abs(a-b) < 1e-9
+	}
+
+	var e syncRateEstimator
+	if r := e.add(1000, at(0)); r != 0 {
+		t.Fatalf("Rate %f from one sample", r)
+	}
+	if eta := e.eta(2000); eta != 0 {
+		t.Fatalf("ETA %s from one sample", eta)
+	}
+
+	// 100 blocks in 10 seconds.
+	if r := e.add(1100, at(10)); !near(r, 10) {
+		t.Fatalf("Expected rate 10, got %f", r)
+	}
+	if eta := e.eta(2100); eta != 100*time.Second {
+		t.Fatalf("Expected ETA 100s, got %s", eta)
+	}
+
+	// A faster interval only moves the rate part of the way.
+	r := e.add(1300, at(20))
+	exp := syncRateSmoothing*20 + (1-syncRateSmoothing)*10
+	if !near(r, exp) {
+		t.Fatalf("Expected smoothed rate %f, got %f", exp, r)
+	}
+	if eta := e.eta(1300); eta != 0 {
+		t.Fatalf("Expected no ETA at the target, got %s", eta)
+	}
+
+	// A stalled sync is capped.
+	e.reset()
+	e.add(1000, at(0))
+	e.add(1000, at(10))
+	e.add(1001, at(1000000))
+	if eta := e.eta(math.MaxInt32); eta != maxSyncETA {
+		t.Fatalf("Expected capped ETA, got %s", eta)
+	}
+
+	// Going backwards starts over.
+	if r := e.add(900, at(1000010)); r != 0 {
+		t.Fatalf("Expected zero rate after reorg, got %f", r)
+	}
+	if r := e.add(1000, at(1000020)); !near(r, 10) {
+		t.Fatalf("Expected fresh rate 10 after reorg, got %f", r)
+	}
+
+	u := new(Progress)
+	e.update(u, 1100, 2100, at(1000030))
+	if u.Height != 1100 || u.TargetHeight != 2100 || !near(u.BlocksPerSecond, 10) || u.ETA != 100*time.Second {
+		t.Fatalf("Wrong Progress from update: %+v", u)
+	}
+}
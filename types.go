@@ -1,8 +1,7 @@
 package eco
 
 import (
-	"github.com/buck54321/eco/encode"
-	"github.com/buck54321/eco/encrypt"
+	"time"
 )
 
 type Error struct {
@@ -20,27 +19,135 @@ const (
 	SyncModeUninitialized
 	SyncModeSPV
 	SyncModeFull
+	// SyncModeRemote uses a dcrd that Eco doesn't run, e.g. on another
+	// machine, in place of the bundled dcrd.
+	SyncModeRemote
 )
 
+// ReleaseChannel determines which decred-binaries releases Eco will install.
+type ReleaseChannel uint8
+
+const (
+	// ReleaseChannelStable is the default, and skips pre-releases.
+	ReleaseChannelStable ReleaseChannel = iota
+	// ReleaseChannelPrerelease follows the newest release, including
+	// pre-releases.
+	ReleaseChannelPrerelease
+)
+
+func (c ReleaseChannel) String() string {
+	switch c {
+	case ReleaseChannelStable:
+		return "stable"
+	case ReleaseChannelPrerelease:
+		return "pre-release"
+	}
+	return "unknown"
+}
+
+// Network is the Decred network that the Eco stack runs on. The network is
+// chosen at initialization.
+type Network uint8
+
+const (
+	// NetworkMainnet is the zero value, so that the state saved before
+	// networks were selectable is mainnet.
+	NetworkMainnet Network = iota
+	NetworkTestnet
+	NetworkSimnet
+)
+
+func (n Network) String() string {
+	switch n {
+	case NetworkMainnet:
+		return "mainnet"
+	case NetworkTestnet:
+		return "testnet"
+	case NetworkSimnet:
+		return "simnet"
+	}
+	return "unknown"
+}
+
 type MetaState struct {
 	Eco      EcoState
 	Services map[string]*ServiceStatus
+	// Locked is true if pending jobs are waiting for Unlock.
+	Locked bool
 }
 
 type EcoState struct {
 	SyncMode     SyncMode
 	WalletExists bool
 	Version      string
+	// Network is the Decred network the services run on.
+	Network Network
+	// Ports are the ports the services listen on. Nil until allocated, and
+	// then only changed if a port is taken.
+	Ports *ServicePorts
+	// GoodVersions are the most recent versions known to have run
+	// successfully, newest first.
+	GoodVersions []string
+	// ReleaseChannel is the user's preference for release selection.
+	ReleaseChannel ReleaseChannel
+	// ReleaseSource is where releases are retrieved from.
+	ReleaseSource ReleaseSourceConfig
+	// VersionRetention is the number of version directories to keep when
+	// pruning. Zero means the defaultVersionRetention.
+	VersionRetention uint32
+	// DownloadCacheLimit is the size limit of the download cache, in bytes.
+	// Zero means the defaultCacheLimit.
+	DownloadCacheLimit int64
+	// Proxy is the SOCKS5 proxy for the services and Eco's downloads. Nil
+	// for no proxy.
+	Proxy *ProxyConfig
+	// Pending are the setup steps waiting for the user's password.
+	Pending PendingJobs
+	// SeedBackedUp is true once the user has confirmed their seed backup and
+	// the stored seed was deleted.
+	SeedBackedUp bool
 }
 
 type DCRDState struct {
 	UserSettings DCRDUserSettings
 	RPCUser      string
 	RPCPass      string
+	// Remote is the dcrd to use in SyncModeRemote.
+	Remote *RemoteDCRD
+}
+
+// RemoteDCRD is the RPC configuration for a dcrd that Eco doesn't run.
+type RemoteDCRD struct {
+	// Host is the dcrd RPC address, host:port. The network's default RPC port
+	// is used if there is no port.
+	Host    string
+	RPCUser string
+	RPCPass string
+	// Cert is the PEM-encoded TLS certificate of the dcrd RPC server.
+	Cert []byte
 }
 
+// DCRDUserSettings are the user's dcrd settings. The zero values are dcrd's
+// defaults.
 type DCRDUserSettings struct {
+	// DebugLevel is shared with dcrwallet.
 	DebugLevel string
+	TxIndex    bool
+	// AddrIndex requires TxIndex.
+	AddrIndex bool
+	// MaxPeers is the maximum number of peers. Zero is dcrd's default.
+	MaxPeers int
+	// AddPeers are peers to stay connected to, in addition to the peers
+	// dcrd finds.
+	AddPeers []string
+	// ConnectPeers are the only peers to connect to. ConnectPeers can't be
+	// used with AddPeers.
+	ConnectPeers []string
+	// BlocksOnly saves bandwidth by not relaying unconfirmed transactions.
+	BlocksOnly bool
+	// DataDir is a custom location for the blockchain data. An empty DataDir
+	// keeps the data in dcrd's application directory.
+	DataDir string
 }
 
 type DCRWalletState struct {
@@ -48,51 +155,80 @@ type DCRWalletState struct {
 }
 
 type Progress struct {
-	Service  string
+	Service string
+	// Stage is the sync stage, e.g. SyncStageHeaders, for services that
+	// report one.
+	Stage    string
 	Status   string
 	Err      string
 	Progress float32
+	// Height and TargetHeight are the current and target block heights for
+	// services that sync a chain.
+	Height       int64
+	TargetHeight int64
+	// BlocksPerSecond is the smoothed sync rate, and ETA the estimated time
+	// remaining. Both are zero until there's enough history to estimate.
+	BlocksPerSecond float64
+	ETA             time.Duration
 }
 
-type ServiceStatus struct {
-	Service string
-	On      bool
-	Sync    *Progress
+// Notification is a message for the user about something Eco did on its own,
+// e.g. rolling back a failed upgrade.
+type Notification struct {
+	Subject string
+	Details string
 }
 
-// There are a couple of times when a password needs to be saved to disk
-// temporarily. In that case, encrypt the password and save it encrypted
-// password with the encryption key. Is it secure? No. But at least we're not
-// storing plain-text passwords. I'll work with dcrwallet and dcrdex to see if
-// we can get around these needs.
-type pwCache struct {
-	EncPW   []byte
-	Key     []byte
-	Crypter []byte
-}
+// ServiceState is a stage in the lifecycle of a supervised service.
+type ServiceState uint8
 
-func newPWCache(pw []byte) (*pwCache, error) {
-	encKey := encode.RandomBytes(32)
-	tmpCrypter := encrypt.NewCrypter(encKey)
-	encPW, err := tmpCrypter.Encrypt(pw)
-	if err != nil {
-		return nil, err
+const (
+	ServiceStopped ServiceState = iota
+	// ServiceWaiting is a service waiting for its dependencies to be ready.
+	ServiceWaiting
+	// ServiceStarting is a service whose process is running, but has not yet
+	// passed a health probe.
+	ServiceStarting
+	ServiceRunning
+	// ServiceUnhealthy is a service that has failed its health probes, and is
+	// being restarted.
+	ServiceUnhealthy
+	// ServiceRestarting is a service waiting to be restarted after its
+	// process exited.
+	ServiceRestarting
+	ServiceStopping
+	// ServiceFailed is a service that crashed too many times, and will not be
+	// restarted.
+	ServiceFailed
+)
+
+func (s ServiceState) String() string {
+	switch s {
+	case ServiceStopped:
+		return "stopped"
+	case ServiceWaiting:
+		return "waiting"
+	case ServiceStarting:
+		return "starting"
+	case ServiceRunning:
+		return "running"
+	case ServiceUnhealthy:
+		return "unhealthy"
+	case ServiceRestarting:
+		return "restarting"
+	case ServiceStopping:
+		return "stopping"
+	case ServiceFailed:
+		return "failed"
 	}
-	return &pwCache{
-		EncPW:   encPW,
-		Key:     encKey,
-		Crypter: tmpCrypter.Serialize(),
-	}, nil
+	return "unknown"
 }
 
-func (pwc *pwCache) PW() ([]byte, error) {
-	tmpCrypter, err := encrypt.Deserialize(pwc.Key, pwc.Crypter)
-	if err != nil {
-		return nil, err
-	}
-	pw, err := tmpCrypter.Decrypt(pwc.EncPW)
-	if err != nil {
-		return nil, err
-	}
-	return pw, nil
+type ServiceStatus struct {
+	Service string
+	On      bool
+	Sync    *Progress
+	State   ServiceState
+	// Err describes why the service is restarting or has failed.
+	Err string
 }
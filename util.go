@@ -8,6 +8,8 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+
+	"github.com/buck54321/eco/extract"
 )
 
 func ServerAddress() (string, string, error) {
@@ -30,35 +32,45 @@ var (
 		"--user-data-dir=" + chromiumDataDir,
 		"--disable-extensions",
 		"--no-first-run",
-		"--app=http://localhost" + dexWebAddr,
 	}
 )
 
-func downloadChromium(ctx context.Context, tmpDir, versionDir string, prog *progressReporter) error {
+func downloadChromium(ctx context.Context, cache *downloadCache, tmpDir, versionDir string, prog *progressReporter) error {
 	downloadPath, checkHash := chromiumDownloadPath()
 	if downloadPath == "" {
 		return fmt.Errorf("no download path for %s/%s", runtime.GOOS, runtime.GOARCH)
 	}
 
 	prog.report(0, "Fetching Chromium")
-	outPath, err := fetchAsset(ctx, tmpDir, downloadPath, "chromium.zip")
+	fetchProg := prog.subReporter(0, 0.80)
+	outPath, err := cache.fetch(tmpDir, "chromium.zip", checkHash[:], func() (string, error) {
+		return fetchAsset(ctx, tmpDir, downloadPath, "chromium.zip", 0, fetchProg)
+	}, fetchProg)
 	if err != nil {
 		return err
 	}
 
-	prog.report(0.75, "Validating Chromium download")
-	err = checkFileHash(outPath, checkHash[:])
+	prog.report(0.80, "Extracting Chromium")
+	unpacked, err := unpack(outPath)
 	if err != nil {
 		return err
 	}
 
-	prog.report(0.80, "Extracting Chromium")
-	unpacked, err := unzip(outPath)
+	return moveDirectoryContents(unpacked, filepath.Join(versionDir, "chromium"))
+}
+
+// unpack extracts the archive into the directory that contains it. The
+// archive must have a single top-level entry, whose path is returned.
+func unpack(archivePath string) (string, error) {
+	dir := filepath.Dir(archivePath)
+	top, err := extract.File(archivePath, dir, nil)
 	if err != nil {
-		return fmt.Errorf("unzip error: %v", err)
+		return "", fmt.Errorf("Error extracting %s: %w", filepath.Base(archivePath), err)
 	}
-
-	return moveDirectoryContents(unpacked, filepath.Join(versionDir, "chromium"))
+	if len(top) != 1 {
+		return "", fmt.Errorf("Expected a single top-level directory in %s, found %d entries", filepath.Base(archivePath), len(top))
+	}
+	return filepath.Join(dir, top[0]), nil
 }
 
 func parseVersion(ver string) (major, minor, patch int, found bool) {
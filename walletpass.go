@@ -0,0 +1,74 @@
+package eco
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// writeWalletPassFile writes a dcrwallet configuration file that holds only
+// the private passphrase. The passphrase is never passed as an argument, since
+// any local user can read a process's arguments. The file is readable only by
+// the user, and the caller must delete it once dcrwallet has read it.
+func writeWalletPassFile(pw []byte) (string, error) {
+	if err := os.MkdirAll(dcrwalletAppDir, 0700); err != nil {
+		return "", fmt.Errorf("Error creating dcrwallet directory: %w", err)
+	}
+	// TempFile creates the file with mode 0600.
+	f, err := ioutil.TempFile(dcrwalletAppDir, "pass-*.conf")
+	if err != nil {
+		return "", fmt.Errorf("Error creating wallet pass file: %w", err)
+	}
+	// The value is quoted, which go-flags' ini parser unquotes, so that the
+	// passphrase can't add options with a newline, and surrounding spaces and
+	// quotes are kept.
+	_, err = fmt.Fprintf(f, "pass=%s\n", strconv.Quote(string(pw)))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("Error writing wallet pass file: %w", err)
+	}
+	return f.Name(), nil
+}
+
+// walletPassFile tracks the pass file for a running dcrwallet, so that it can
+// be deleted as soon as dcrwallet is up.
+type walletPassFile struct {
+	mtx  sync.Mutex
+	path string
+}
+
+// write writes a new pass file, replacing any previous one, and returns the
+// dcrwallet argument for it.
+func (f *walletPassFile) write(pw []byte) (string, error) {
+	path, err := writeWalletPassFile(pw)
+	if err != nil {
+		return "", err
+	}
+	f.mtx.Lock()
+	old := f.path
+	f.path = path
+	f.mtx.Unlock()
+	if old != "" {
+		os.Remove(old)
+	}
+	return fmt.Sprintf("--configfile=\"%s\"", path), nil
+}
+
+// remove deletes the pass file, if there is one.
+func (f *walletPassFile) remove() {
+	f.mtx.Lock()
+	path := f.path
+	f.path = ""
+	f.mtx.Unlock()
+	if path == "" {
+		return
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Errorf("Error removing wallet pass file: %v", err)
+	}
+}
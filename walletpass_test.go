@@ -0,0 +1,191 @@
+package eco
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/buck54321/eco/db"
+	"github.com/decred/slog"
+)
+
+// tDCRWalletScript is a stand-in dcrwallet that copies its configuration file
+// next to itself, then waits to be stopped.
+const tDCRWalletScript = `#!/bin/sh
+for a in "$@"; do
+	case "$a" in
+	--configfile=*)
+		f="${a#--configfile=}"
+		f="${f#\"}"
+		f="${f%\"}"
+		cp "$f" "$(dirname "$0")/seen.conf"
+		;;
+	esac
+done
+exec sleep 30
+`
+
+func TestDCRWalletPassNotInArgs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("The stand-in dcrwallet is a shell script")
+	}
+	defer fastSupervisor()()
+	defer tLogDir(t)()
+
+	tmpDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(tmpDir)
+	defer func(ecoDir, walletDir string) {
+		EcoDir, dcrwalletAppDir = ecoDir, walletDir
+	}(EcoDir, dcrwalletAppDir)
+	EcoDir = filepath.Join(tmpDir, "eco")
+	dcrwalletAppDir = filepath.Join(tmpDir, dcrwallet)
+
+	const version = "v1.6.0"
+	exeDir := filepath.Join(EcoDir, version, decred)
+	os.MkdirAll(exeDir, 0755)
+	if err := ioutil.WriteFile(filepath.Join(exeDir, dcrWalletExeName), []byte(tDCRWalletScript), 0755); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+
+	dbb, err := db.NewDB(filepath.Join(tmpDir, "eco.db"), slog.Disabled)
+	if err != nil {
+		t.Fatalf("NewDB error: %v", err)
+	}
+	const secret = "correct horse battery staple"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	eco := &Eco{
+		db:       dbb,
+		outerCtx: ctx,
+		innerCtx: ctx,
+		state: MetaState{Eco: EcoState{
+			Version:  version,
+			SyncMode: SyncModeSPV,
+			Network:  NetworkTestnet,
+			Pending:  PendingWalletStart,
+		}},
+		dcrd:      &DCRD{DCRDState: *dcrdNewState()},
+		dcrwallet: new(DCRWallet),
+		sup:       newSupervisor(ctx, nil),
+	}
+
+	if err := eco.session.set([]byte(secret)); err != nil {
+		t.Fatalf("session.set error: %v", err)
+	}
+	if err := eco.runDCRWallet(); err != nil {
+		t.Fatalf("runDCRWallet error: %v", err)
+	}
+	defer eco.sup.stopAll()
+
+	currentExe := func() *serviceExe {
+		eco.sup.mtx.Lock()
+		svc := eco.sup.services[dcrwallet]
+		eco.sup.mtx.Unlock()
+		if svc == nil {
+			return nil
+		}
+		svc.mtx.Lock()
+		defer svc.mtx.Unlock()
+		return svc.exe
+	}
+	var exe *serviceExe
+	waitFor(t, "dcrwallet started", func() bool {
+		exe = currentExe()
+		return exe != nil
+	})
+
+	var passPath string
+	for _, arg := range exe.cmd.Args {
+		if strings.Contains(arg, secret) {
+			t.Fatalf("Wallet password found in dcrwallet argument %q", arg)
+		}
+		if strings.HasPrefix(arg, "--configfile=") {
+			passPath = strings.Trim(strings.TrimPrefix(arg, "--configfile="), `"`)
+		}
+	}
+	if passPath == "" {
+		t.Fatalf("No configuration file passed to dcrwallet")
+	}
+	fi, err := os.Stat(passPath)
+	if err != nil {
+		t.Fatalf("Stat error: %v", err)
+	}
+	if perm := fi.Mode().Perm(); perm != 0600 {
+		t.Fatalf("Wallet pass file has permissions %o", perm)
+	}
+
+	seenPath := filepath.Join(exeDir, "seen.conf")
+	waitFor(t, "configuration read", func() bool {
+		_, err := os.Stat(seenPath)
+		return err == nil
+	})
+	b, _ := ioutil.ReadFile(seenPath)
+	if string(b) != "pass="+strconv.Quote(secret)+"\n" {
+		t.Fatalf("Wrong configuration file contents %q", string(b))
+	}
+
+	// Finishing the job ends the session. The file is deleted once dcrwallet
+	// exits, and a crashed dcrwallet is restarted without a password.
+	eco.finishJob(PendingWalletStart)
+	exe.startMtx.Lock()
+	proc := exe.cmd.Process
+	exe.startMtx.Unlock()
+	if err := proc.Kill(); err != nil {
+		t.Fatalf("Kill error: %v", err)
+	}
+	waitFor(t, "pass file deleted", func() bool {
+		_, err := os.Stat(passPath)
+		return os.IsNotExist(err)
+	})
+	var restarted *serviceExe
+	waitFor(t, "dcrwallet restarted", func() bool {
+		restarted = currentExe()
+		return restarted != nil && restarted != exe
+	})
+	for _, arg := range restarted.cmd.Args {
+		if strings.HasPrefix(arg, "--configfile=") {
+			t.Fatalf("Pass file given to restarted dcrwallet")
+		}
+	}
+}
+
+func TestWriteWalletPassFile(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(tmpDir)
+	defer func(dir string) { dcrwalletAppDir = dir }(dcrwalletAppDir)
+	dcrwalletAppDir = tmpDir
+
+	for _, pw := range []string{
+		"simple",
+		"pass\nnoseed=1",
+		`"quoted"`,
+		" spaces ",
+		`back\slash`,
+	} {
+		path, err := writeWalletPassFile([]byte(pw))
+		if err != nil {
+			t.Fatalf("%q: writeWalletPassFile error: %v", pw, err)
+		}
+		b, _ := ioutil.ReadFile(path)
+		os.Remove(path)
+		// The file must be a single pass option, whose quoted value is
+		// the password.
+		lines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+		if len(lines) != 1 || !strings.HasPrefix(lines[0], "pass=") {
+			t.Fatalf("%q: wrong configuration file contents %q", pw, string(b))
+		}
+		v, err := strconv.Unquote(strings.TrimPrefix(lines[0], "pass="))
+		if err != nil {
+			t.Fatalf("%q: Unquote error: %v", pw, err)
+		}
+		if v != pw {
+			t.Fatalf("Expected password %q, got %q", pw, v)
+		}
+	}
+}
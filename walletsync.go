@@ -0,0 +1,225 @@
+package eco
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg/v3"
+)
+
+// Sync stages, reported in the Progress.Stage. SyncStageBlocks is for a full
+// sync through dcrd. The other stages are dcrwallet's.
+const (
+	SyncStageCFilters  = "cfilters"
+	SyncStageHeaders   = "headers"
+	SyncStageBlocks    = "blocks"
+	SyncStageDiscovery = "discovery"
+	SyncStageRescan    = "rescan"
+	SyncStageSynced    = "synced"
+)
+
+// walletSyncStages are the stages of an initial wallet sync, with the portion
+// of the overall progress that each stage ends at. Not every sync goes
+// through every stage.
+var walletSyncStages = map[string]struct{ start, end float32 }{
+	SyncStageCFilters:  {0, 0.1},
+	SyncStageHeaders:   {0.1, 0.6},
+	SyncStageDiscovery: {0.6, 0.7},
+	SyncStageRescan:    {0.7, 1},
+	SyncStageSynced:    {1, 1},
+}
+
+// Patterns for the dcrwallet log lines that mark sync progress. The patterns
+// don't include the log level or subsystem, since those are set by the user's
+// debug level.
+var (
+	walletHeadersSyncedRe = regexp.MustCompile(`Headers synced through block \S+ height (\d+)`)
+	walletTxSyncedRe      = regexp.MustCompile(`Transactions synced through block \S+ height (-?\d+)`)
+	walletCFiltersRe      = regexp.MustCompile(`Fetched cfilters for blocks (\d+)-(\d+)`)
+	walletConnectedRe     = regexp.MustCompile(`Connected \d+ blocks, new tip \S+, height (\d+), date (.+)$`)
+	walletNewHeadersRe    = regexp.MustCompile(`Fetched \d+ new header\(s\) ending at height (\d+)`)
+	walletNewBlockRe      = regexp.MustCompile(`Connected block \S+, height (\d+), \d+ wallet transaction`)
+	walletDiscoveryRe     = regexp.MustCompile(`Discovering used (accounts|addresses)`)
+	walletDiscoveredRe    = regexp.MustCompile(`Finished address discovery`)
+	walletRescanRe        = regexp.MustCompile(`Rescanning block range \[(\d+), (\d+)\]`)
+	walletRescannedRe     = regexp.MustCompile(`Rescan complete`)
+	walletSyncedRe        = regexp.MustCompile(`Blockchain sync completed`)
+)
+
+// walletTimeLayout is the format of a time.Time printed with %v.
+const walletTimeLayout = "2006-01-02 15:04:05 -0700 MST"
+
+// walletSyncParser turns dcrwallet's log output into sync Progress. dcrwallet
+// offers no sync status over RPC, and in SPV mode there's no dcrd to compare
+// against, so the log is the only source of progress.
+type walletSyncParser struct {
+	params *chaincfg.Params
+	now    func() time.Time
+
+	mtx          sync.Mutex
+	stage        string
+	headerHeight int64
+	// rescanPending is set at startup if the transactions weren't synced
+	// through the headers, so a rescan will follow the header sync.
+	rescanPending bool
+	// rescanFrom is the first height of the current rescan.
+	rescanFrom int64
+	rate       syncRateEstimator
+	rateStage  string
+}
+
+func newWalletSyncParser(params *chaincfg.Params) *walletSyncParser {
+	return &walletSyncParser{
+		params:     params,
+		now:        time.Now,
+		rescanFrom: -1,
+	}
+}
+
+// parseLine parses a line of dcrwallet output. A Progress is returned if the
+// line is a sync update.
+func (p *walletSyncParser) parseLine(line string) *Progress {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	atoi := func(s string) int64 {
+		i, _ := strconv.ParseInt(s, 10, 64)
+		return i
+	}
+
+	if m := walletHeadersSyncedRe.FindStringSubmatch(line); m != nil {
+		p.headerHeight = atoi(m[1])
+		return nil
+	}
+	if m := walletTxSyncedRe.FindStringSubmatch(line); m != nil {
+		// The headers line comes first.
+		p.rescanPending = atoi(m[1]) < p.headerHeight
+		return nil
+	}
+	if m := walletNewHeadersRe.FindStringSubmatch(line); m != nil {
+		p.headerHeight = atoi(m[1])
+		return nil
+	}
+
+	if m := walletCFiltersRe.FindStringSubmatch(line); m != nil {
+		end := atoi(m[2])
+		var progress float32
+		if p.headerHeight > 0 {
+			progress = float32(end) / float32(p.headerHeight)
+		}
+		u := p.progress(SyncStageCFilters, progress, "Fetching compact filters through block %d", end)
+		p.rated(u, end, p.headerHeight)
+		return u
+	}
+
+	if m := walletConnectedRe.FindStringSubmatch(line); m != nil {
+		p.headerHeight = atoi(m[1])
+		stamp, err := time.Parse(walletTimeLayout, m[2])
+		if err != nil {
+			log.Debugf("Error parsing dcrwallet block time %q: %v", m[2], err)
+			return p.progress(SyncStageHeaders, 0, "Syncing headers at block %d", p.headerHeight)
+		}
+		genesis := p.params.GenesisBlock.Header.Timestamp
+		now := p.now()
+		// The tip is current if it's within a few blocks of now.
+		if now.Sub(stamp) < p.params.TargetTimePerBlock*6 {
+			if p.rescanPending {
+				return p.progress(SyncStageHeaders, 1, "Headers synced through block %d", p.headerHeight)
+			}
+			if p.stage == SyncStageSynced {
+				return nil
+			}
+			return p.progress(SyncStageSynced, 1, "Fully synced")
+		}
+		progress := float32(stamp.Sub(genesis)) / float32(now.Sub(genesis))
+		u := p.progress(SyncStageHeaders, progress, "Syncing headers at block %d", p.headerHeight)
+		// The target height is a guess from the time since the tip.
+		target := p.headerHeight + int64(now.Sub(stamp)/p.params.TargetTimePerBlock)
+		p.rated(u, p.headerHeight, target)
+		return u
+	}
+
+	if walletDiscoveryRe.MatchString(line) {
+		return p.progress(SyncStageDiscovery, 0, "Discovering used addresses")
+	}
+	if walletDiscoveredRe.MatchString(line) {
+		return p.progress(SyncStageDiscovery, 1, "Finished address discovery")
+	}
+
+	if m := walletRescanRe.FindStringSubmatch(line); m != nil {
+		from, through := atoi(m[1]), atoi(m[2])
+		if p.rescanFrom < 0 {
+			p.rescanFrom = from
+		}
+		var progress float32
+		if span := p.headerHeight - p.rescanFrom; span > 0 {
+			progress = float32(through-p.rescanFrom) / float32(span)
+		}
+		u := p.progress(SyncStageRescan, progress, "Rescanning blocks %d to %d", from, through)
+		p.rated(u, through, p.headerHeight)
+		return u
+	}
+	if walletRescannedRe.MatchString(line) {
+		p.rescanFrom = -1
+		p.rescanPending = false
+		return p.progress(SyncStageSynced, 1, "Fully synced")
+	}
+
+	if walletSyncedRe.MatchString(line) {
+		return p.progress(SyncStageSynced, 1, "Fully synced")
+	}
+	// A new block is only announced once the initial sync is done.
+	if m := walletNewBlockRe.FindStringSubmatch(line); m != nil {
+		p.headerHeight = atoi(m[1])
+		if p.stage == SyncStageSynced {
+			return nil
+		}
+		return p.progress(SyncStageSynced, 1, "Fully synced")
+	}
+	return nil
+}
+
+// progress creates a Progress for the stage, with the progress within the
+// stage scaled to the overall progress. The mtx must be held.
+func (p *walletSyncParser) progress(stage string, progress float32, status string, args ...interface{}) *Progress {
+	if progress < 0 {
+		progress = 0
+	} else if progress > 1 {
+		progress = 1
+	}
+	p.stage = stage
+	r := walletSyncStages[stage]
+	return &Progress{
+		Service:  dcrwallet,
+		Stage:    stage,
+		Status:   fmt.Sprintf(status, args...),
+		Progress: r.start + (r.end-r.start)*progress,
+	}
+}
+
+// rated adds the heights, rate, and ETA to the Progress. The mtx must be held.
+func (p *walletSyncParser) rated(u *Progress, height, target int64) {
+	if u.Stage != p.rateStage {
+		p.rate.reset()
+		p.rateStage = u.Stage
+	}
+	p.rate.update(u, height, target, p.now())
+}
+
+// syncing is true if the parser has seen a sync stage, but not the end of the
+// sync.
+func (p *walletSyncParser) syncing() bool {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return p.stage != "" && p.stage != SyncStageSynced
+}
+
+// synced is true if the parser has seen the end of the sync.
+func (p *walletSyncParser) synced() bool {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return p.stage == SyncStageSynced
+}
@@ -0,0 +1,114 @@
+package eco
+
+import (
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg/v3"
+)
+
+func TestWalletSyncParser(t *testing.T) {
+	params := chaincfg.MainNetParams()
+	now := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	genesis := params.GenesisBlock.Header.Timestamp
+	halfway := genesis.Add(now.Sub(genesis) / 2)
+	recent := now.Add(-time.Minute)
+	date := func(t time.Time) string {
+		return t.Format(walletTimeLayout)
+	}
+
+	type tLine struct {
+		line string
+		// stage is empty if no update is expected.
+		stage    string
+		progress float32
+	}
+
+	tests := []struct {
+		name  string
+		lines []tLine
+	}{
+		{
+			name: "restore",
+			lines: []tLine{
+				{line: "2021-01-01 11:00:00.000 [INF] SYNC: Headers synced through block 298e5cc3d985bfe7f81dc135f360abe089edd4396b86d2de66b0cef42b21d980 height 0"},
+				{line: "2021-01-01 11:00:00.000 [INF] SYNC: Transactions synced through block 298e5cc3d985bfe7f81dc135f360abe089edd4396b86d2de66b0cef42b21d980 height 0"},
+				{line: "2021-01-01 11:00:01.000 [INF] SYNC: New peer 1.2.3.4:9108 /dcrwire:0.4.0/dcrd:1.6.0/ SFNodeNetwork|SFNodeCF"},
+				{
+					line:     "2021-01-01 11:00:02.000 [INF] SYNC: Connected 2000 blocks, new tip 00000000000000001cd2d32ebeb51ad4e5e0d3dd46a39ee1a3a48ba3de0b8d27, height 2000, date " + date(halfway),
+					stage:    SyncStageHeaders,
+					progress: 0.35,
+				},
+				{
+					line:     "2021-01-01 11:00:03.000 [INF] SYNC: Connected 2000 blocks, new tip 00000000000000001cd2d32ebeb51ad4e5e0d3dd46a39ee1a3a48ba3de0b8d27, height 4000, date " + date(recent),
+					stage:    SyncStageSynced,
+					progress: 1,
+				},
+				{line: "2021-01-01 11:00:04.000 [INF] WLLT: Discovering used addresses for 1 account(s)", stage: SyncStageDiscovery, progress: 0.6},
+				{line: "2021-01-01 11:00:05.000 [INF] WLLT: Finished address discovery", stage: SyncStageDiscovery, progress: 0.7},
+				{line: "2021-01-01 11:00:06.000 [INF] WLLT: Rescanning block range [1, 2000]...", stage: SyncStageRescan, progress: 0.7 + 0.3*1999/3999},
+				{line: "2021-01-01 11:00:07.000 [INF] WLLT: Rescanning block range [2001, 4000]...", stage: SyncStageRescan, progress: 1},
+				{line: "2021-01-01 11:00:08.000 [INF] WLLT: Rescan complete", stage: SyncStageSynced, progress: 1},
+				{line: "2021-01-01 11:05:00.000 [INF] SYNC: Connected block 00000000000000001cd2d32ebeb51ad4e5e0d3dd46a39ee1a3a48ba3de0b8d27, height 4001, 0 wallet transaction(s)"},
+			},
+		},
+		{
+			name: "rescan pending",
+			lines: []tLine{
+				{line: "[INF] SYNC: Headers synced through block 1cd2 height 4000"},
+				{line: "[INF] SYNC: Transactions synced through block 1cd2 height 3000"},
+				{line: "[INF] WLLT: Fetched cfilters for blocks 2000-2999", stage: SyncStageCFilters, progress: 0.1 * 2999 / 4000},
+				{
+					// Headers are current, but a rescan will follow.
+					line:     "[INF] SYNC: Connected 10 blocks, new tip 1cd2, height 4010, date " + date(recent),
+					stage:    SyncStageHeaders,
+					progress: 0.6,
+				},
+				{line: "[INF] WLLT: Rescanning block range [3001, 4010]...", stage: SyncStageRescan, progress: 1},
+				{line: "[INF] WLLT: Rescan complete", stage: SyncStageSynced, progress: 1},
+			},
+		},
+		{
+			name: "new block after startup",
+			lines: []tLine{
+				{line: "[INF] SYNC: Headers synced through block 1cd2 height 4000"},
+				{line: "[INF] SYNC: Transactions synced through block 1cd2 height 4000"},
+				{line: "[INF] SYNC: Connected block 1cd2, height 4001, 1 wallet transaction(s)", stage: SyncStageSynced, progress: 1},
+				{line: "[INF] SYNC: Connected block 1cd2, height 4002, 0 wallet transaction(s)"},
+			},
+		},
+		{
+			name: "rpc mode",
+			lines: []tLine{
+				{line: "[INF] CHNS: Fetched 100 new header(s) ending at height 4100 from 127.0.0.1:19703"},
+				{line: "[INF] CHNS: Blockchain sync completed, wallet ready for general usage.", stage: SyncStageSynced, progress: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		p := newWalletSyncParser(params)
+		p.now = func() time.Time { return now }
+		for i, l := range tt.lines {
+			u := p.parseLine(l.line)
+			if l.stage == "" {
+				if u != nil {
+					t.Fatalf("%s: line %d: unexpected update %+v", tt.name, i, u)
+				}
+				continue
+			}
+			if u == nil {
+				t.Fatalf("%s: line %d: no update", tt.name, i)
+			}
+			if u.Service != dcrwallet || u.Stage != l.stage {
+				t.Fatalf("%s: line %d: expected stage %s, got %s", tt.name, i, l.stage, u.Stage)
+			}
+			if diff := u.Progress - l.progress; diff > 0.001 || diff < -0.001 {
+				t.Fatalf("%s: line %d: expected progress %.4f, got %.4f", tt.name, i, l.progress, u.Progress)
+			}
+		}
+		if p.syncing() {
+			t.Fatalf("%s: still syncing at the end", tt.name)
+		}
+	}
+}